@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
+	_ "time/tzdata"
 
 	_ "github.com/FoodVerse/FoodVerse-backend/docs"
 	"github.com/FoodVerse/FoodVerse-backend/internal/config"
 	"github.com/FoodVerse/FoodVerse-backend/internal/controller"
 	"github.com/FoodVerse/FoodVerse-backend/internal/middleware"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payout"
 	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/scheduler"
 	"github.com/FoodVerse/FoodVerse-backend/internal/service"
 	"github.com/FoodVerse/FoodVerse-backend/migrations"
 	"github.com/FoodVerse/FoodVerse-backend/pkg/database"
@@ -47,7 +53,9 @@ func main() {
 	}
 
 	// Run migrations
-	migrations.Migrate(db)
+	if err := migrations.Migrate(db); err != nil {
+		panic("failed to migrate database: " + err.Error())
+	}
 	fmt.Println("🔍 DEBUG: Migrations completed, about to seed...")
 
 	fmt.Println("🔍 DEBUG: About to call seedDatabase...")
@@ -57,22 +65,151 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
-	storeRepo := repository.NewStoreRepository(db)
-	foodBagRepo := repository.NewFoodBagRepository(db)
+	geoSearch := repository.NewGeoSearch(database.HasExtension(db, "postgis"))
+	textSearch := repository.NewTextSearch(database.HasExtension(db, "pg_trgm"))
+	storeRepo := repository.NewStoreRepository(db, geoSearch, textSearch)
+	foodBagRepo := repository.NewFoodBagRepository(db, geoSearch, textSearch)
 	orderRepo := repository.NewOrderRepository(db)
+	orderEventRepo := repository.NewOrderEventRepository(db)
+	cartRepo := repository.NewCartRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	promotionRepo := repository.NewPromotionRepository(db)
+	payoutRepo := repository.NewPayoutRepository(db)
+	foodBagTemplateRepo := repository.NewFoodBagTemplateRepository(db)
+	foodBagScheduleRepo := repository.NewFoodBagScheduleRepository(db)
 	sellerRequestRepo := repository.NewSellerRequestRepository(db)
+	searchSuggestionRepo := repository.NewSearchSuggestionRepository(db)
+	savedSearchRepo := repository.NewSavedSearchRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
 
 	// Initialize services
 	jwtService := service.NewJWTService(cfg.JWTSecret, cfg.JWTExpirationHours)
 	authService := service.NewAuthService(userRepo, jwtService)
+	walletService := service.NewWalletService(db, ledgerRepo, cfg.PaymentCurrency)
+	promotionService := service.NewPromotionService(promotionRepo, orderRepo, storeRepo, userRepo)
+	orderService := service.NewOrderService(db, orderRepo, orderEventRepo, foodBagRepo, cartRepo, storeRepo, userRepo, walletService, promotionService, service.OrderTiming{
+		CancelCutoff: time.Duration(cfg.OrderCancelCutoffMinutes) * time.Minute,
+		PickupGrace:  time.Duration(cfg.PickupGraceMinutes) * time.Minute,
+	})
+
+	// Only development setups may fall back to a key derived from the JWT
+	// secret.
+	pickupTokenDevSecret := ""
+	if cfg.Environment == "development" {
+		pickupTokenDevSecret = cfg.JWTSecret
+	}
+	pickupTokenKey, err := service.PickupTokenKey(cfg.PickupTokenSeed, pickupTokenDevSecret)
+	if err != nil {
+		panic("failed to load pickup token key: " + err.Error())
+	}
+	pickupTokenService := service.NewPickupTokenService(pickupTokenKey, orderService)
+	expiryService := service.NewExpiryService(orderService, orderRepo, foodBagRepo)
+	recommendationService := service.NewRecommendationService(foodBagRepo, orderRepo, service.RecommendationWeights{
+		Category:   cfg.RecommendationWeightCategory,
+		Store:      cfg.RecommendationWeightStore,
+		Price:      cfg.RecommendationWeightPrice,
+		TimeOfDay:  cfg.RecommendationWeightTimeOfDay,
+		Popularity: cfg.RecommendationWeightPopularity,
+		Distance:   cfg.RecommendationWeightDistance,
+	})
+	scheduleService := service.NewScheduleService(db, foodBagTemplateRepo, foodBagScheduleRepo, foodBagRepo, storeRepo, cfg.ScheduleLookaheadDays)
+	alertService := service.NewAlertService(db, savedSearchRepo, notificationRepo, foodBagRepo, service.AlertConfig{
+		Throttle:   time.Duration(cfg.AlertThrottleMinutes) * time.Minute,
+		MaxPerHour: cfg.AlertMaxPerHour,
+	})
+	scheduleService.AddListingListener(alertService)
+	orderService.AddListingListener(alertService)
+
+	var paymentProvider payment.Provider
+	switch cfg.PaymentProvider {
+	case "mock":
+		paymentProvider = payment.NewMockProvider(payment.MockConfig{
+			WebhookURL:  cfg.PaymentWebhookURL,
+			Secret:      cfg.PaymentWebhookSecret,
+			Delay:       time.Duration(cfg.MockPaymentDelayMs) * time.Millisecond,
+			FailureRate: cfg.MockPaymentFailureRate,
+		})
+	default:
+		panic("unknown payment provider: " + cfg.PaymentProvider)
+	}
+	paymentService := service.NewPaymentService(db, paymentProvider, paymentRepo, refundRepo, orderRepo, orderService, walletService, cfg.PaymentCurrency)
+
+	var payoutProvider payout.Provider
+	switch cfg.PayoutProvider {
+	case "stub":
+		payoutProvider = payout.NewStubProvider(payout.StubConfig{
+			FailureRate: cfg.StubPayoutFailureRate,
+		})
+	default:
+		panic("unknown payout provider: " + cfg.PayoutProvider)
+	}
+	settlementService := service.NewSettlementService(db, payoutRepo, storeRepo, userRepo, payoutProvider, service.SettlementConfig{
+		CommissionRate: cfg.PlatformCommissionRate,
+		Period:         time.Duration(cfg.PayoutPeriodDays) * 24 * time.Hour,
+		Currency:       cfg.PaymentCurrency,
+	})
+
+	// Background jobs
+	jobs := scheduler.New(db)
+	jobs.Register(scheduler.Job{
+		Name:     "expire-pickups",
+		Interval: time.Duration(cfg.ExpiryJobIntervalSeconds) * time.Second,
+		Run:      expiryService.Run,
+	})
+	idempotencyTTL := time.Duration(cfg.IdempotencyKeyTTLHours) * time.Hour
+	jobs.Register(scheduler.Job{
+		Name:     "purge-idempotency-keys",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			_, err := idempotencyRepo.DeleteCreatedBefore(time.Now().Add(-idempotencyTTL))
+			return err
+		},
+	})
+	jobs.Register(scheduler.Job{
+		Name:     "generate-scheduled-food-bags",
+		Interval: time.Duration(cfg.ScheduleJobIntervalMinutes) * time.Minute,
+		Run:      scheduleService.Generate,
+	})
+	jobs.Register(scheduler.Job{
+		Name:     "retry-refunds",
+		Interval: time.Minute,
+		Run:      paymentService.RetryRefunds,
+	})
+	jobs.Register(scheduler.Job{
+		Name:     "settle-payouts",
+		Interval: time.Duration(cfg.PayoutJobIntervalMinutes) * time.Minute,
+		Run:      settlementService.Settle,
+	})
+	jobs.Register(scheduler.Job{
+		Name:     "refresh-search-suggestions",
+		Interval: time.Duration(cfg.SuggestionJobIntervalMinutes) * time.Minute,
+		Run: func(ctx context.Context) error {
+			return searchSuggestionRepo.Refresh()
+		},
+	})
+	jobs.Start(context.Background())
 
 	// Initialize controllers
 	authController := controller.NewAuthController(authService)
 	storeController := controller.NewStoreController(storeRepo)
-	foodBagController := controller.NewFoodBagController(foodBagRepo, storeRepo)
-	orderController := controller.NewOrderController(orderRepo, foodBagRepo)
+	foodBagController := controller.NewFoodBagController(foodBagRepo, storeRepo, orderService, alertService)
+	orderController := controller.NewOrderController(orderRepo, foodBagRepo, orderService)
 	sellerRequestController := controller.NewSellerRequestController(sellerRequestRepo, userRepo)
 	seedController := controller.NewSeedController(db)
+	pickupTokenController := controller.NewPickupTokenController(pickupTokenService)
+	cartController := controller.NewCartController(cartRepo, foodBagRepo, orderService)
+	paymentController := controller.NewPaymentController(paymentService, userRepo)
+	walletController := controller.NewWalletController(walletService, userRepo)
+	promotionController := controller.NewPromotionController(promotionService, orderService)
+	payoutController := controller.NewPayoutController(settlementService, userRepo)
+	scheduleController := controller.NewScheduleController(scheduleService)
+	searchController := controller.NewSearchController(searchSuggestionRepo)
+	recommendationController := controller.NewRecommendationController(recommendationService)
+	savedSearchController := controller.NewSavedSearchController(alertService)
+	notificationController := controller.NewNotificationController(notificationRepo)
 
 	// App Router
 	router := gin.Default()
@@ -81,7 +218,8 @@ func main() {
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, Idempotency-Key")
+		c.Header("Access-Control-Expose-Headers", "Idempotent-Replayed")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -112,8 +250,17 @@ func main() {
 		// Search routes (public for browsing)
 		public.POST("/stores/search", storeController.SearchStores)
 		public.POST("/food-bags/search", foodBagController.SearchFoodBags)
+		public.GET("/stores/map", storeController.GetMapStores)
 		public.GET("/stores/:id", storeController.GetStore)
+		public.GET("/stores/:id/hours-exceptions", storeController.GetHoursExceptions)
 		public.GET("/food-bags/:id", foodBagController.GetFoodBag)
+		public.GET("/search/suggest", searchController.Suggest)
+
+		// Public key for verifying pickup tokens offline
+		public.GET("/pickup-tokens/public-key", pickupTokenController.GetPublicKey)
+
+		// Payment provider callbacks, authenticated by their signature
+		public.POST("/payments/webhook", paymentController.Webhook)
 
 		// Store specific food bags (using different path structure)
 		public.GET("/store-food-bags/:store_id", foodBagController.GetFoodBagsByStore)
@@ -121,7 +268,7 @@ func main() {
 
 	// Protected routes
 	protected := router.Group("/api/v1")
-	protected.Use(middleware.JWTAuthMiddleware(jwtService))
+	protected.Use(middleware.JWTAuthMiddleware(jwtService), middleware.IdempotencyMiddleware(idempotencyRepo))
 	{
 		// User routes
 		protected.GET("/user", authController.Profile)
@@ -138,23 +285,90 @@ func main() {
 		protected.PUT("/stores/:id", storeController.UpdateStore)
 		protected.PATCH("/stores/:id/toggle-status", storeController.ToggleStoreStatus)
 		protected.DELETE("/stores/:id", storeController.DeleteStore)
+		protected.POST("/stores/:id/hours-exceptions", storeController.SetHoursException)
+		protected.DELETE("/stores/:id/hours-exceptions/:exception_id", storeController.DeleteHoursException)
 
 		// Food bag management routes
 		protected.POST("/food-bags", foodBagController.CreateFoodBag)
 		protected.PUT("/food-bags/:id", foodBagController.UpdateFoodBag)
 		protected.DELETE("/food-bags/:id", foodBagController.DeleteFoodBag)
+		protected.GET("/food-bags/recommended", recommendationController.GetRecommended)
+
+		// Food bag template and schedule routes
+		protected.GET("/food-bag-templates", scheduleController.ListTemplates)
+		protected.POST("/food-bag-templates", scheduleController.CreateTemplate)
+		protected.PUT("/food-bag-templates/:id", scheduleController.UpdateTemplate)
+		protected.DELETE("/food-bag-templates/:id", scheduleController.DeleteTemplate)
+		protected.GET("/food-bag-schedules", scheduleController.ListSchedules)
+		protected.POST("/food-bag-schedules", scheduleController.CreateSchedule)
+		protected.PUT("/food-bag-schedules/:id", scheduleController.UpdateSchedule)
+		protected.DELETE("/food-bag-schedules/:id", scheduleController.DeleteSchedule)
+		protected.POST("/food-bag-schedules/:id/pause", scheduleController.PauseSchedule)
+		protected.POST("/food-bag-schedules/:id/resume", scheduleController.ResumeSchedule)
+		protected.POST("/food-bag-schedules/:id/skips", scheduleController.SkipDate)
+		protected.DELETE("/food-bag-schedules/:id/skips/:date", scheduleController.UnskipDate)
+
+		// Saved search and notification routes
+		protected.GET("/saved-searches", savedSearchController.ListSavedSearches)
+		protected.POST("/saved-searches", savedSearchController.CreateSavedSearch)
+		protected.PUT("/saved-searches/:id", savedSearchController.UpdateSavedSearch)
+		protected.DELETE("/saved-searches/:id", savedSearchController.DeleteSavedSearch)
+		protected.GET("/notifications", notificationController.GetNotifications)
+		protected.POST("/notifications/read-all", notificationController.MarkAllRead)
+		protected.POST("/notifications/:id/read", notificationController.MarkRead)
 
 		// Order routes
 		protected.POST("/orders", orderController.CreateOrder)
 		protected.GET("/orders/:id", orderController.GetOrder)
 		protected.GET("/orders/my", orderController.GetMyOrders)
 		protected.PUT("/orders/:id/status", orderController.UpdateOrderStatus)
+		protected.POST("/orders/:id/cancel", orderController.CancelOrder)
+		protected.GET("/orders/:id/history", orderController.GetOrderHistory)
 		protected.POST("/orders/verify-pickup", orderController.VerifyPickupCode)
+		protected.GET("/orders/:id/pickup-token", pickupTokenController.GetPickupToken)
+		protected.GET("/orders/:id/pickup-qr", pickupTokenController.GetPickupQRCode)
+		protected.POST("/orders/verify-pickup-token", pickupTokenController.VerifyPickupToken)
+		protected.POST("/orders/sync-pickup-redemptions", pickupTokenController.SyncRedemptions)
+		protected.POST("/orders/:id/pay", paymentController.PayOrder)
+		protected.GET("/orders/:id/payments", paymentController.GetOrderPayments)
+
+		// Admin refund routes
+		protected.GET("/admin/refunds", paymentController.ListRefunds)
+		protected.POST("/admin/orders/:id/refunds", paymentController.ForceRefund)
+
+		// Wallet routes
+		protected.GET("/wallet", walletController.GetWallet)
+		protected.GET("/wallet/transactions", walletController.GetTransactions)
+		protected.POST("/admin/wallets/:user_id/adjustments", walletController.AdjustWallet)
+		protected.GET("/admin/wallets/reconciliation", walletController.Reconcile)
+
+		// Payout routes
+		protected.GET("/stores/:id/earnings", payoutController.GetStoreEarnings)
+		protected.GET("/stores/:id/payouts", payoutController.GetStorePayouts)
+		protected.GET("/stores/:id/payouts/:payout_id", payoutController.GetStatement)
+		protected.GET("/admin/payouts", payoutController.ListPayouts)
+		protected.GET("/admin/payouts/export", payoutController.ExportPayouts)
+		protected.POST("/admin/payouts/settle", payoutController.Settle)
+		protected.POST("/admin/payouts/:id/retry", payoutController.RetryPayout)
+
+		// Promotion routes
+		protected.POST("/promotions/validate", promotionController.ValidateCode)
+		protected.GET("/promotions", promotionController.ListPromotions)
+		protected.POST("/promotions", promotionController.CreatePromotion)
+		protected.PUT("/promotions/:id", promotionController.UpdatePromotion)
+
+		// Cart routes
+		protected.GET("/cart", cartController.GetCart)
+		protected.DELETE("/cart", cartController.ClearCart)
+		protected.POST("/cart/items", cartController.AddItem)
+		protected.PUT("/cart/items/:food_bag_id", cartController.UpdateItem)
+		protected.DELETE("/cart/items/:food_bag_id", cartController.RemoveItem)
+		protected.POST("/cart/checkout", cartController.Checkout)
 	}
 
 	// Protected store specific routes
 	protectedStoreRoutes := router.Group("/api/v1")
-	protectedStoreRoutes.Use(middleware.JWTAuthMiddleware(jwtService))
+	protectedStoreRoutes.Use(middleware.JWTAuthMiddleware(jwtService), middleware.IdempotencyMiddleware(idempotencyRepo))
 	{
 		protectedStoreRoutes.GET("/store/:store_id/orders", orderController.GetStoreOrders)
 	}
@@ -53,6 +53,7 @@ func seedDatabase(db *gorm.DB) {
 			Category:    "Bakery",
 			ImageURL:    "https://images.unsplash.com/photo-1509440159596-0249088772ff?auto=format&fit=crop&w=400&q=80", // Bakery storefront
 			Rating:      4.8,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     owners[0].ID,
 		},
@@ -67,6 +68,7 @@ func seedDatabase(db *gorm.DB) {
 			Category:    "Cafe",
 			ImageURL:    "https://images.unsplash.com/photo-1501339847302-ac426a4a7cbb?auto=format&fit=crop&w=400&q=80", // Coffee shop interior
 			Rating:      4.6,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     owners[1].ID,
 		},
@@ -81,6 +83,7 @@ func seedDatabase(db *gorm.DB) {
 			Category:    "Deli",
 			ImageURL:    "https://images.unsplash.com/photo-1555396273-367ea4eb4db5?auto=format&fit=crop&w=400&q=80", // Deli counter
 			Rating:      4.7,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     owners[2].ID,
 		},
@@ -95,6 +98,7 @@ func seedDatabase(db *gorm.DB) {
 			Category:    "Grill",
 			ImageURL:    "https://images.unsplash.com/photo-1555939594-58d7cb561ad1?auto=format&fit=crop&w=400&q=80", // Grill restaurant
 			Rating:      4.5,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     owners[3].ID,
 		},
@@ -1052,6 +1052,10 @@ const docTemplate = `{
         "model.ErrorResponse": {
             "type": "object",
             "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "invalid_transition"
+                },
                 "error": {
                     "type": "string",
                     "example": "Something went wrong"
@@ -1407,7 +1411,7 @@ const docTemplate = `{
                     "type": "string",
                     "enum": [
                         "pending",
-                        "preparing",
+                        "confirmed",
                         "ready",
                         "completed",
                         "cancelled"
@@ -8,6 +8,10 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// devPaymentWebhookSecret signs payment webhooks in development when
+// PAYMENT_WEBHOOK_SECRET is unset.
+const devPaymentWebhookSecret = "mock_webhook_secret"
+
 type Config struct {
 	DBHost             string
 	DBPort             string
@@ -17,6 +21,77 @@ type Config struct {
 	ServerPort         string
 	JWTSecret          string
 	JWTExpirationHours int
+
+	// Environment is "development" for local setups; anything else is
+	// treated as a deployed environment.
+	Environment string
+
+	// PickupTokenSeed is the base64url Ed25519 seed used to sign pickup
+	// tokens. It is required outside development; in development a key is
+	// derived from JWTSecret when it is empty.
+	PickupTokenSeed string
+
+	// OrderCancelCutoffMinutes is how long before PickupTimeStart a buyer
+	// can still cancel their own order.
+	OrderCancelCutoffMinutes int
+
+	// PickupGraceMinutes widens the pickup window when verifying a pickup.
+	PickupGraceMinutes int
+
+	// ExpiryJobIntervalSeconds is how often expired food bags and orders
+	// are closed out.
+	ExpiryJobIntervalSeconds int
+
+	// IdempotencyKeyTTLHours is how long stored Idempotency-Key responses
+	// are kept for replay.
+	IdempotencyKeyTTLHours int
+
+	// PaymentProvider selects the payment gateway. Only "mock" exists.
+	PaymentProvider   string
+	PaymentCurrency   string
+	PaymentWebhookURL string
+	// PaymentWebhookSecret signs payment webhooks. It is required outside
+	// development, where it defaults to a well-known value.
+	PaymentWebhookSecret string
+
+	// MockPaymentFailureRate is the share of mock authorizations declined at
+	// random, and MockPaymentDelayMs how long the mock waits before calling
+	// the webhook.
+	MockPaymentFailureRate float64
+	MockPaymentDelayMs     int
+
+	// PlatformCommissionRate is the platform's share, between 0 and 1, of
+	// what stores sell. Stores are paid the rest every PayoutPeriodDays.
+	PlatformCommissionRate float64
+	PayoutPeriodDays       int
+	// PayoutProvider selects how stores are paid. Only "stub" exists.
+	PayoutProvider           string
+	StubPayoutFailureRate    float64
+	PayoutJobIntervalMinutes int
+
+	// ScheduleLookaheadDays is how many days ahead, today included,
+	// recurring schedules create their food bags.
+	ScheduleLookaheadDays      int
+	ScheduleJobIntervalMinutes int
+
+	// SuggestionJobIntervalMinutes is how often the search suggestion index
+	// is rebuilt with fresh popularity and availability.
+	SuggestionJobIntervalMinutes int
+
+	// RecommendationWeight* set how much each signal counts towards a food
+	// bag recommendation. Only their ratios matter.
+	RecommendationWeightCategory   float64
+	RecommendationWeightStore      float64
+	RecommendationWeightPrice      float64
+	RecommendationWeightTimeOfDay  float64
+	RecommendationWeightPopularity float64
+	RecommendationWeightDistance   float64
+
+	// AlertThrottleMinutes is the least time between two alerts of one
+	// saved search, and AlertMaxPerHour caps the alerts a user gets in an
+	// hour (0 for no cap).
+	AlertThrottleMinutes int
+	AlertMaxPerHour      int
 }
 
 func getEnv(key, defaultValue string) string {
@@ -26,6 +101,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvWeight reads a non-negative weight, falling back to defaultValue
+// when it is unset or invalid.
+func getEnvWeight(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 	jwtHours, err := strconv.Atoi(getEnv("JWT_EXPIRATION_HOURS", "24"))
@@ -33,15 +118,136 @@ func LoadConfig() (*Config, error) {
 		jwtHours = 24
 	}
 
+	cancelCutoff, err := strconv.Atoi(getEnv("ORDER_CANCEL_CUTOFF_MINUTES", "60"))
+	if err != nil {
+		cancelCutoff = 60
+	}
+
+	pickupGrace, err := strconv.Atoi(getEnv("PICKUP_GRACE_MINUTES", "15"))
+	if err != nil {
+		pickupGrace = 15
+	}
+
+	expiryInterval, err := strconv.Atoi(getEnv("EXPIRY_JOB_INTERVAL_SECONDS", "60"))
+	if err != nil {
+		expiryInterval = 60
+	}
+
+	idempotencyTTL, err := strconv.Atoi(getEnv("IDEMPOTENCY_KEY_TTL_HOURS", "24"))
+	if err != nil {
+		idempotencyTTL = 24
+	}
+
+	mockFailureRate, err := strconv.ParseFloat(getEnv("MOCK_PAYMENT_FAILURE_RATE", "0"), 64)
+	if err != nil {
+		mockFailureRate = 0
+	}
+
+	mockDelay, err := strconv.Atoi(getEnv("MOCK_PAYMENT_DELAY_MS", "500"))
+	if err != nil {
+		mockDelay = 500
+	}
+
+	commissionRate, err := strconv.ParseFloat(getEnv("PLATFORM_COMMISSION_RATE", "0.15"), 64)
+	if err != nil {
+		commissionRate = 0.15
+	}
+
+	payoutPeriod, err := strconv.Atoi(getEnv("PAYOUT_PERIOD_DAYS", "7"))
+	if err != nil || payoutPeriod < 1 {
+		payoutPeriod = 7
+	}
+
+	stubPayoutFailureRate, err := strconv.ParseFloat(getEnv("STUB_PAYOUT_FAILURE_RATE", "0"), 64)
+	if err != nil {
+		stubPayoutFailureRate = 0
+	}
+
+	payoutJobInterval, err := strconv.Atoi(getEnv("PAYOUT_JOB_INTERVAL_MINUTES", "60"))
+	if err != nil || payoutJobInterval < 1 {
+		payoutJobInterval = 60
+	}
+
+	scheduleLookahead, err := strconv.Atoi(getEnv("SCHEDULE_LOOKAHEAD_DAYS", "2"))
+	if err != nil || scheduleLookahead < 1 {
+		scheduleLookahead = 2
+	}
+
+	scheduleJobInterval, err := strconv.Atoi(getEnv("SCHEDULE_JOB_INTERVAL_MINUTES", "15"))
+	if err != nil || scheduleJobInterval < 1 {
+		scheduleJobInterval = 15
+	}
+
+	suggestionJobInterval, err := strconv.Atoi(getEnv("SUGGESTION_JOB_INTERVAL_MINUTES", "10"))
+	if err != nil || suggestionJobInterval < 1 {
+		suggestionJobInterval = 10
+	}
+
+	alertThrottle, err := strconv.Atoi(getEnv("ALERT_THROTTLE_MINUTES", "30"))
+	if err != nil || alertThrottle < 0 {
+		alertThrottle = 30
+	}
+
+	alertMaxPerHour, err := strconv.Atoi(getEnv("ALERT_MAX_PER_HOUR", "5"))
+	if err != nil || alertMaxPerHour < 0 {
+		alertMaxPerHour = 5
+	}
+
+	serverPort := getEnv("SERVER_PORT", "7000")
+
 	config := &Config{
 		DBHost:             getEnv("DB_HOST", "localhost"),
 		DBPort:             getEnv("DB_PORT", "5432"),
 		DBUser:             getEnv("DB_USER", "foodverse"),
 		DBPassword:         getEnv("DB_PASSWORD", "foodverse"),
 		DBName:             getEnv("DB_NAME", "foodverse"),
-		ServerPort:         getEnv("SERVER_PORT", "7000"),
+		ServerPort:         serverPort,
 		JWTSecret:          getEnv("JWT_SECRET", "your_jwt_secret"),
 		JWTExpirationHours: jwtHours,
+		Environment:        getEnv("APP_ENV", "development"),
+		PickupTokenSeed:    getEnv("PICKUP_TOKEN_SEED", ""),
+
+		OrderCancelCutoffMinutes: cancelCutoff,
+		PickupGraceMinutes:       pickupGrace,
+		ExpiryJobIntervalSeconds: expiryInterval,
+		IdempotencyKeyTTLHours:   idempotencyTTL,
+
+		PaymentProvider:        getEnv("PAYMENT_PROVIDER", "mock"),
+		PaymentCurrency:        getEnv("PAYMENT_CURRENCY", "USD"),
+		PaymentWebhookURL:      getEnv("PAYMENT_WEBHOOK_URL", "http://localhost:"+serverPort+"/api/v1/payments/webhook"),
+		PaymentWebhookSecret:   getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+		MockPaymentFailureRate: mockFailureRate,
+		MockPaymentDelayMs:     mockDelay,
+
+		PlatformCommissionRate:   commissionRate,
+		PayoutPeriodDays:         payoutPeriod,
+		PayoutProvider:           getEnv("PAYOUT_PROVIDER", "stub"),
+		StubPayoutFailureRate:    stubPayoutFailureRate,
+		PayoutJobIntervalMinutes: payoutJobInterval,
+
+		ScheduleLookaheadDays:      scheduleLookahead,
+		ScheduleJobIntervalMinutes: scheduleJobInterval,
+
+		SuggestionJobIntervalMinutes: suggestionJobInterval,
+
+		RecommendationWeightCategory:   getEnvWeight("RECOMMENDATION_WEIGHT_CATEGORY", 0.3),
+		RecommendationWeightStore:      getEnvWeight("RECOMMENDATION_WEIGHT_STORE", 0.2),
+		RecommendationWeightPrice:      getEnvWeight("RECOMMENDATION_WEIGHT_PRICE", 0.15),
+		RecommendationWeightTimeOfDay:  getEnvWeight("RECOMMENDATION_WEIGHT_TIME_OF_DAY", 0.15),
+		RecommendationWeightPopularity: getEnvWeight("RECOMMENDATION_WEIGHT_POPULARITY", 0.1),
+		RecommendationWeightDistance:   getEnvWeight("RECOMMENDATION_WEIGHT_DISTANCE", 0.1),
+
+		AlertThrottleMinutes: alertThrottle,
+		AlertMaxPerHour:      alertMaxPerHour,
+	}
+
+	// Anyone knowing the webhook secret can confirm orders as paid, so
+	// only development setups may use the well-known one.
+	if config.PaymentWebhookSecret == "" {
+		if config.Environment != "development" {
+			return nil, fmt.Errorf("PAYMENT_WEBHOOK_SECRET must be set outside development")
+		}
+		config.PaymentWebhookSecret = devPaymentWebhookSecret
 	}
 
 	fmt.Println(config.ServerPort)
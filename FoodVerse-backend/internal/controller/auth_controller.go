@@ -134,7 +134,15 @@ func (c *AuthController) UserStats(ctx *gin.Context) {
 		if order.Status == "completed" {
 			completedOrders++
 		}
-		if order.FoodBag.OriginalPrice > 0 && order.FoodBag.DiscountedPrice > 0 {
+		// Savings are measured against the price actually paid; orders
+		// from before order items existed only know the listed price.
+		if len(order.Items) > 0 {
+			for _, item := range order.Items {
+				if savings := (item.FoodBag.OriginalPrice - item.UnitPrice) * float64(item.Quantity); savings > 0 {
+					totalSavings += savings
+				}
+			}
+		} else if order.FoodBag.OriginalPrice > 0 && order.FoodBag.DiscountedPrice > 0 {
 			savings := (order.FoodBag.OriginalPrice - order.FoodBag.DiscountedPrice) * float64(order.Quantity)
 			if savings > 0 {
 				totalSavings += savings
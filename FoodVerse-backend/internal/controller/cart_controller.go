@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CartController struct {
+	cartRepo     *repository.CartRepository
+	foodBagRepo  *repository.FoodBagRepository
+	orderService *service.OrderService
+}
+
+func NewCartController(cartRepo *repository.CartRepository, foodBagRepo *repository.FoodBagRepository, orderService *service.OrderService) *CartController {
+	return &CartController{
+		cartRepo:     cartRepo,
+		foodBagRepo:  foodBagRepo,
+		orderService: orderService,
+	}
+}
+
+// @Summary Get cart
+// @Description Get the current user's cart priced at current food bag prices
+// @Tags cart
+// @Produce json
+// @Success 200 {object} model.CartResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /cart [get]
+func (c *CartController) GetCart(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	c.writeCart(ctx, userID.(uint))
+}
+
+// @Summary Add cart item
+// @Description Add a food bag to the cart, or add to its quantity if already there
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param item body model.CartItemInput true "Cart item"
+// @Success 200 {object} model.CartResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /cart/items [post]
+func (c *CartController) AddItem(ctx *gin.Context) {
+	var input model.CartItemInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	foodBag, err := c.foodBagRepo.GetByID(input.FoodBagID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Food bag not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch food bag"})
+		return
+	}
+	if !foodBag.IsActive {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Food bag is no longer available"})
+		return
+	}
+
+	if err := c.cartRepo.Add(userID.(uint), input.FoodBagID, input.Quantity); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to cart"})
+		return
+	}
+
+	c.writeCart(ctx, userID.(uint))
+}
+
+// @Summary Update cart item
+// @Description Set the quantity of a food bag in the cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param food_bag_id path int true "Food bag ID"
+// @Param item body model.CartItemUpdateInput true "New quantity"
+// @Success 200 {object} model.CartResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /cart/items/{food_bag_id} [put]
+func (c *CartController) UpdateItem(ctx *gin.Context) {
+	foodBagID, err := strconv.ParseUint(ctx.Param("food_bag_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid food bag ID"})
+		return
+	}
+
+	var input model.CartItemUpdateInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	updated, err := c.cartRepo.SetQuantity(userID.(uint), uint(foodBagID), input.Quantity)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart item"})
+		return
+	}
+	if !updated {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Food bag is not in the cart"})
+		return
+	}
+
+	c.writeCart(ctx, userID.(uint))
+}
+
+// @Summary Remove cart item
+// @Description Remove a food bag from the cart
+// @Tags cart
+// @Produce json
+// @Param food_bag_id path int true "Food bag ID"
+// @Success 200 {object} model.CartResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /cart/items/{food_bag_id} [delete]
+func (c *CartController) RemoveItem(ctx *gin.Context) {
+	foodBagID, err := strconv.ParseUint(ctx.Param("food_bag_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid food bag ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	removed, err := c.cartRepo.Remove(userID.(uint), uint(foodBagID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove cart item"})
+		return
+	}
+	if !removed {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Food bag is not in the cart"})
+		return
+	}
+
+	c.writeCart(ctx, userID.(uint))
+}
+
+// @Summary Clear cart
+// @Description Remove every item from the cart
+// @Tags cart
+// @Produce json
+// @Success 200 {object} model.CartResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /cart [delete]
+func (c *CartController) ClearCart(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.cartRepo.Clear(userID.(uint)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		return
+	}
+
+	c.writeCart(ctx, userID.(uint))
+}
+
+// @Summary Check out cart
+// @Description Reserve every cart line at once and create one order per store. The cart is emptied on success.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param checkout body model.CheckoutInput false "Checkout options"
+// @Param Idempotency-Key header string false "Client-generated key that makes retries safe"
+// @Success 201 {array} model.SwaggerOrder
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /cart/checkout [post]
+func (c *CartController) Checkout(ctx *gin.Context) {
+	var input model.CheckoutInput
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	orders, err := c.orderService.Checkout(userID.(uint), &input)
+	if err != nil {
+		writeOrderError(ctx, err, "Failed to check out")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, model.OrderResponses(orders))
+}
+
+func (c *CartController) writeCart(ctx *gin.Context, userID uint) {
+	items, err := c.cartRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.ToCartResponse(items))
+}
@@ -3,21 +3,33 @@ package controller
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/FoodVerse/FoodVerse-backend/internal/model"
 	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
+const errPickupOutsideHours = "Pickup window falls outside the store's opening hours"
+
+// pickupStartGrace lets a new pickup window start slightly in the past, for
+// clients whose clocks run behind.
+const pickupStartGrace = 5 * time.Minute
+
 type FoodBagController struct {
-	foodBagRepo *repository.FoodBagRepository
-	storeRepo   *repository.StoreRepository
+	foodBagRepo     *repository.FoodBagRepository
+	storeRepo       *repository.StoreRepository
+	orderService    *service.OrderService
+	listingListener service.ListingListener
 }
 
-func NewFoodBagController(foodBagRepo *repository.FoodBagRepository, storeRepo *repository.StoreRepository) *FoodBagController {
+func NewFoodBagController(foodBagRepo *repository.FoodBagRepository, storeRepo *repository.StoreRepository, orderService *service.OrderService, listingListener service.ListingListener) *FoodBagController {
 	return &FoodBagController{
-		foodBagRepo: foodBagRepo,
-		storeRepo:   storeRepo,
+		foodBagRepo:     foodBagRepo,
+		storeRepo:       storeRepo,
+		orderService:    orderService,
+		listingListener: listingListener,
 	}
 }
 
@@ -45,6 +57,19 @@ func (c *FoodBagController) CreateFoodBag(ctx *gin.Context) {
 		return
 	}
 
+	if msg := validatePricing(&input); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	now := time.Now()
+	start, end, err := model.NormalizePickupWindow(input.PickupTimeStart, input.PickupTimeEnd, now.Add(-pickupStartGrace), now)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.PickupTimeStart, input.PickupTimeEnd = start, end
+
 	// Verify that the user owns the store
 	store, err := c.storeRepo.GetByID(input.StoreID)
 	if err != nil {
@@ -57,6 +82,11 @@ func (c *FoodBagController) CreateFoodBag(ctx *gin.Context) {
 		return
 	}
 
+	if !store.CoversWindow(input.PickupTimeStart, input.PickupTimeEnd) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errPickupOutsideHours})
+		return
+	}
+
 	foodBag := &model.FoodBag{
 		StoreID:         input.StoreID,
 		Title:           input.Title,
@@ -69,12 +99,16 @@ func (c *FoodBagController) CreateFoodBag(ctx *gin.Context) {
 		ImageURL:        input.ImageURL,
 		Category:        input.Category,
 		IsActive:        true,
+		PricingStrategy: input.PricingStrategy,
+		PriceFloor:      input.PriceFloor,
+		PriceSteps:      input.PriceSteps,
 	}
 
 	if err := c.foodBagRepo.Create(foodBag); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create food bag"})
 		return
 	}
+	c.listingListener.FoodBagListed(foodBag)
 
 	ctx.JSON(http.StatusCreated, foodBag)
 }
@@ -84,7 +118,7 @@ func (c *FoodBagController) CreateFoodBag(ctx *gin.Context) {
 // @Tags food-bags
 // @Produce json
 // @Param id path int true "Food bag ID"
-// @Success 200 {object} model.SwaggerFoodBag
+// @Success 200 {object} model.SwaggerFoodBagResponse
 // @Failure 404 {object} model.ErrorResponse
 // @Router /food-bags/{id} [get]
 func (c *FoodBagController) GetFoodBag(ctx *gin.Context) {
@@ -100,7 +134,7 @@ func (c *FoodBagController) GetFoodBag(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, foodBag)
+	ctx.JSON(http.StatusOK, foodBag.ToResponse(time.Now()))
 }
 
 // @Summary Search nearby food bags
@@ -109,7 +143,7 @@ func (c *FoodBagController) GetFoodBag(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param search body model.SwaggerFoodBagSearchRequest true "Search criteria"
-// @Success 200 {array} model.SwaggerFoodBagResponse
+// @Success 200 {object} model.Page[model.SwaggerFoodBagResponse]
 // @Failure 400 {object} model.ErrorResponse
 // @Router /food-bags/search [post]
 func (c *FoodBagController) SearchFoodBags(ctx *gin.Context) {
@@ -121,7 +155,7 @@ func (c *FoodBagController) SearchFoodBags(ctx *gin.Context) {
 
 	foodBags, err := c.foodBagRepo.SearchNearby(req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search food bags"})
+		writeListError(ctx, err, "Failed to search food bags")
 		return
 	}
 
@@ -129,11 +163,14 @@ func (c *FoodBagController) SearchFoodBags(ctx *gin.Context) {
 }
 
 // @Summary Get food bags by store
-// @Description Get all food bags for a specific store
+// @Description Get a page of the active food bags of a store
 // @Tags food-bags
 // @Produce json
 // @Param store_id path int true "Store ID"
-// @Success 200 {array} model.SwaggerFoodBag
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Param sort query string false "pickup_time (default), price, discount_percent or created_at; prefix with - to reverse"
+// @Success 200 {object} model.Page[model.SwaggerFoodBag]
 // @Failure 400 {object} model.ErrorResponse
 // @Router /stores/{store_id}/food-bags [get]
 func (c *FoodBagController) GetFoodBagsByStore(ctx *gin.Context) {
@@ -143,9 +180,14 @@ func (c *FoodBagController) GetFoodBagsByStore(ctx *gin.Context) {
 		return
 	}
 
-	foodBags, err := c.foodBagRepo.GetByStoreID(uint(storeID))
+	page, ok := bindPage(ctx)
+	if !ok {
+		return
+	}
+
+	foodBags, err := c.foodBagRepo.GetByStoreID(uint(storeID), page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get food bags"})
+		writeListError(ctx, err, "Failed to get food bags")
 		return
 	}
 
@@ -195,6 +237,37 @@ func (c *FoodBagController) UpdateFoodBag(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := validatePricing(&input); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// Only a moved pickup window is checked, so bags listed before the
+	// store changed its hours can still be edited. A window that is already
+	// running may keep its start.
+	if !input.PickupTimeStart.Equal(foodBag.PickupTimeStart) || !input.PickupTimeEnd.Equal(foodBag.PickupTimeEnd) {
+		now := time.Now()
+		earliestStart := now.Add(-pickupStartGrace)
+		if foodBag.PickupTimeStart.Before(earliestStart) {
+			earliestStart = foodBag.PickupTimeStart
+		}
+		start, end, err := model.NormalizePickupWindow(input.PickupTimeStart, input.PickupTimeEnd, earliestStart, now)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		input.PickupTimeStart, input.PickupTimeEnd = start, end
+
+		store, err := c.storeRepo.GetByID(foodBag.StoreID)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+			return
+		}
+		if !store.CoversWindow(input.PickupTimeStart, input.PickupTimeEnd) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errPickupOutsideHours})
+			return
+		}
+	}
 
 	foodBag.Title = input.Title
 	foodBag.Description = input.Description
@@ -205,17 +278,25 @@ func (c *FoodBagController) UpdateFoodBag(ctx *gin.Context) {
 	foodBag.PickupTimeEnd = input.PickupTimeEnd
 	foodBag.ImageURL = input.ImageURL
 	foodBag.Category = input.Category
+	foodBag.PricingStrategy = input.PricingStrategy
+	foodBag.PriceFloor = input.PriceFloor
+	foodBag.PriceSteps = input.PriceSteps
 
-	if err := c.foodBagRepo.Update(foodBag); err != nil {
+	// Raising QuantityTotal adds the difference to the bags left.
+	restocked, err := c.foodBagRepo.Update(foodBag)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update food bag"})
 		return
 	}
+	if restocked && foodBag.IsActive {
+		c.listingListener.FoodBagListed(foodBag)
+	}
 
 	ctx.JSON(http.StatusOK, foodBag)
 }
 
 // @Summary Delete food bag
-// @Description Delete a food bag
+// @Description Delete a food bag. Open orders that include it are cancelled and their buyers refunded.
 // @Tags food-bags
 // @Param id path int true "Food bag ID"
 // @Success 204
@@ -223,6 +304,8 @@ func (c *FoodBagController) UpdateFoodBag(ctx *gin.Context) {
 // @Failure 401 {object} model.ErrorResponse
 // @Failure 403 {object} model.ErrorResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Security Bearer
 // @Router /food-bags/{id} [delete]
 func (c *FoodBagController) DeleteFoodBag(ctx *gin.Context) {
@@ -250,10 +333,30 @@ func (c *FoodBagController) DeleteFoodBag(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.foodBagRepo.Delete(uint(id)); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete food bag"})
+	// Open orders for the bag are cancelled and refunded along with it.
+	if err := c.orderService.WithdrawFoodBag(foodBag.ID, userID.(uint)); err != nil {
+		writeOrderError(ctx, err, "Failed to delete food bag")
 		return
 	}
 
 	ctx.Status(http.StatusNoContent)
 }
+
+// validatePricing fills in the default pricing strategy and checks that a
+// decaying price has somewhere to decay to. It returns an error message, or
+// "" when the input is fine.
+func validatePricing(input *model.FoodBagInput) string {
+	if input.PricingStrategy == "" {
+		input.PricingStrategy = model.PricingFixed
+	}
+	if input.PricingStrategy == model.PricingFixed {
+		return ""
+	}
+	if input.PriceFloor <= 0 || input.PriceFloor >= input.DiscountedPrice {
+		return "price_floor must be above zero and below discounted_price"
+	}
+	if !input.PickupTimeEnd.After(input.PickupTimeStart) {
+		return "pickup_time_end must be after pickup_time_start"
+	}
+	return ""
+}
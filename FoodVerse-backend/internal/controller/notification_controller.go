@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationRepo *repository.NotificationRepository
+}
+
+func NewNotificationController(notificationRepo *repository.NotificationRepository) *NotificationController {
+	return &NotificationController{notificationRepo: notificationRepo}
+}
+
+// @Summary Get notifications
+// @Description Get a page of the user's notifications, newest first
+// @Tags notifications
+// @Produce json
+// @Param unread query bool false "Only notifications not yet read"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} model.Page[model.Notification]
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /notifications [get]
+func (c *NotificationController) GetNotifications(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	page, ok := bindPage(ctx)
+	if !ok {
+		return
+	}
+	unreadOnly, _ := strconv.ParseBool(ctx.Query("unread"))
+
+	notifications, err := c.notificationRepo.GetByUserID(userID.(uint), unreadOnly, page)
+	if err != nil {
+		writeListError(ctx, err, "Failed to get notifications")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, notifications)
+}
+
+// @Summary Mark a notification read
+// @Tags notifications
+// @Param id path int true "Notification ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /notifications/{id}/read [post]
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	found, err := c.notificationRepo.MarkRead(uint(id), userID.(uint), time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification read"})
+		return
+	}
+	if !found {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Mark all notifications read
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} map[string]int64
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /notifications/read-all [post]
+func (c *NotificationController) MarkAllRead(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	count, err := c.notificationRepo.MarkAllRead(userID.(uint), time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications read"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"marked_read": count})
+}
@@ -1,24 +1,27 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/FoodVerse/FoodVerse-backend/internal/model"
 	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
 type OrderController struct {
-	orderRepo   *repository.OrderRepository
-	foodBagRepo *repository.FoodBagRepository
+	orderRepo    *repository.OrderRepository
+	foodBagRepo  *repository.FoodBagRepository
+	orderService *service.OrderService
 }
 
-func NewOrderController(orderRepo *repository.OrderRepository, foodBagRepo *repository.FoodBagRepository) *OrderController {
+func NewOrderController(orderRepo *repository.OrderRepository, foodBagRepo *repository.FoodBagRepository, orderService *service.OrderService) *OrderController {
 	return &OrderController{
-		orderRepo:   orderRepo,
-		foodBagRepo: foodBagRepo,
+		orderRepo:    orderRepo,
+		foodBagRepo:  foodBagRepo,
+		orderService: orderService,
 	}
 }
 
@@ -28,9 +31,12 @@ func NewOrderController(orderRepo *repository.OrderRepository, foodBagRepo *repo
 // @Accept json
 // @Produce json
 // @Param order body model.OrderInput true "Order data"
+// @Param Idempotency-Key header string false "Client-generated key that makes retries safe"
 // @Success 201 {object} model.SwaggerOrder
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Security Bearer
 // @Router /orders [post]
 func (c *OrderController) CreateOrder(ctx *gin.Context) {
@@ -46,57 +52,13 @@ func (c *OrderController) CreateOrder(ctx *gin.Context) {
 		return
 	}
 
-	// Get food bag details
-	foodBag, err := c.foodBagRepo.GetByID(input.FoodBagID)
+	order, err := c.orderService.PlaceOrder(userID.(uint), &input)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Food bag not found"})
-		return
-	}
-
-	// Check if enough quantity is available
-	if foodBag.QuantityLeft < input.Quantity {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Not enough quantity available"})
-		return
-	}
-
-	// Check if food bag is still active
-	if !foodBag.IsActive {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Food bag is no longer available"})
+		writeOrderError(ctx, err, "Failed to create order")
 		return
 	}
 
-	totalPrice := foodBag.DiscountedPrice * float64(input.Quantity)
-
-	order := &model.Order{
-		UserID:     userID.(uint),
-		FoodBagID:  input.FoodBagID,
-		StoreID:    foodBag.StoreID,
-		Quantity:   input.Quantity,
-		TotalPrice: totalPrice,
-		Status:     model.OrderStatusPending,
-		Notes:      input.Notes,
-	}
-
-	if err := c.orderRepo.Create(order); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
-		return
-	}
-
-	// Update food bag quantity
-	newQuantity := foodBag.QuantityLeft - input.Quantity
-	if err := c.foodBagRepo.UpdateQuantity(foodBag.ID, newQuantity); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update food bag quantity"})
-		return
-	}
-
-	// Get the created order with all relations
-	createdOrder, err := c.orderRepo.GetByID(order.ID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get created order"})
-		return
-	}
-
-	ctx.JSON(http.StatusCreated, createdOrder)
+	ctx.JSON(http.StatusCreated, order.ToResponse())
 }
 
 // @Summary Get order by ID
@@ -136,14 +98,18 @@ func (c *OrderController) GetOrder(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, order)
+	ctx.JSON(http.StatusOK, order.ToResponse())
 }
 
 // @Summary Get user's orders
-// @Description Get all orders for the authenticated user
+// @Description Get a page of the authenticated user's orders
 // @Tags orders
 // @Produce json
-// @Success 200 {array} model.SwaggerOrder
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Param sort query string false "created_at (default, newest first) or total_price; prefix with - to reverse"
+// @Success 200 {object} model.Page[model.SwaggerOrder]
+// @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Security Bearer
@@ -155,23 +121,33 @@ func (c *OrderController) GetMyOrders(ctx *gin.Context) {
 		return
 	}
 
-	orders, err := c.orderRepo.GetByUserID(userID.(uint))
+	page, ok := bindPage(ctx)
+	if !ok {
+		return
+	}
+
+	orders, err := c.orderRepo.GetByUserID(userID.(uint), page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
+		writeListError(ctx, err, "Failed to get orders")
 		return
 	}
 
-	ctx.JSON(http.StatusOK, orders)
+	ctx.JSON(http.StatusOK, orderPage(orders))
 }
 
 // @Summary Get store orders
-// @Description Get all orders for a store
+// @Description Get a page of a store's orders (for the store owner or an admin)
 // @Tags orders
 // @Produce json
 // @Param store_id path int true "Store ID"
-// @Success 200 {array} model.SwaggerOrder
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Param sort query string false "created_at (default, newest first) or total_price; prefix with - to reverse"
+// @Success 200 {object} model.Page[model.SwaggerOrder]
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Security Bearer
 // @Router /stores/{store_id}/orders [get]
@@ -187,20 +163,29 @@ func (c *OrderController) GetStoreOrders(ctx *gin.Context) {
 		return
 	}
 
-	// Verify that the user owns the store (for now, we'll skip this check)
-	_ = userID // TODO: Implement store ownership verification
+	page, ok := bindPage(ctx)
+	if !ok {
+		return
+	}
 
-	orders, err := c.orderRepo.GetByStoreID(uint(storeID))
+	orders, err := c.orderService.GetStoreOrders(uint(storeID), userID.(uint), page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
+		switch {
+		case errors.Is(err, service.ErrStoreNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+		case errors.Is(err, service.ErrOrderForbidden):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this store's orders"})
+		default:
+			writeListError(ctx, err, "Failed to get orders")
+		}
 		return
 	}
 
-	ctx.JSON(http.StatusOK, orders)
+	ctx.JSON(http.StatusOK, orderPage(orders))
 }
 
 // @Summary Update order status
-// @Description Update order status (for store owners)
+// @Description Update order status (for store owners). Orders are completed by verifying their pickup code or token, not through this endpoint.
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -211,6 +196,7 @@ func (c *OrderController) GetStoreOrders(ctx *gin.Context) {
 // @Failure 401 {object} model.ErrorResponse
 // @Failure 403 {object} model.ErrorResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Security Bearer
 // @Router /orders/{id}/status [put]
@@ -235,57 +221,118 @@ func (c *OrderController) UpdateOrderStatus(ctx *gin.Context) {
 		return
 	}
 
-	order, err := c.orderRepo.GetByID(uint(id))
+	updatedOrder, err := c.orderService.UpdateStatus(uint(id), userID.(uint), model.OrderStatus(input.Status))
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		writeOrderError(ctx, err, "Failed to update order status")
 		return
 	}
 
-	// Check if user owns the store
-	if order.Store.OwnerID != userID.(uint) {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this order"})
+	ctx.JSON(http.StatusOK, updatedOrder.ToResponse())
+}
+
+// @Summary Cancel order
+// @Description Cancel an order and return its quantity to the food bag. Buyers can cancel until the configured cutoff before pickup starts; store owners must give a reason.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param cancellation body model.OrderCancelInput false "Cancellation reason"
+// @Success 200 {object} model.SwaggerOrder
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/{id}/cancel [post]
+func (c *OrderController) CancelOrder(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
 		return
 	}
 
-	status := model.OrderStatus(input.Status)
-	if err := c.orderRepo.UpdateStatus(uint(id), status); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	// If order is completed, update picked up time
-	if status == model.OrderStatusCompleted {
-		now := time.Now()
-		order.PickedUpAt = &now
-		if err := c.orderRepo.Update(order); err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pickup time"})
+	var input model.OrderCancelInput
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 	}
 
-	// Get updated order
-	updatedOrder, err := c.orderRepo.GetByID(uint(id))
+	order, err := c.orderService.CancelOrder(uint(id), userID.(uint), input.Reason)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get updated order"})
+		writeOrderError(ctx, err, "Failed to cancel order")
 		return
 	}
 
-	ctx.JSON(http.StatusOK, updatedOrder)
+	ctx.JSON(http.StatusOK, order.ToResponse())
+}
+
+// @Summary Get order history
+// @Description Get the status history of an order (buyer or store owner)
+// @Tags orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} model.OrderEvent
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/{id}/history [get]
+func (c *OrderController) GetOrderHistory(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	events, err := c.orderService.GetHistory(uint(id), userID.(uint))
+	if err != nil {
+		if errors.Is(err, service.ErrOrderForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this order"})
+			return
+		}
+		writeOrderError(ctx, err, "Failed to get order history")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
 }
 
 // @Summary Verify pickup code
-// @Description Verify pickup code and complete order
+// @Description Verify a pickup code at one of the caller's stores and complete the order. The order must be confirmed or ready and the pickup window (plus grace period) must be open.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Param code body model.SwaggerPickupCodeRequest true "Pickup code"
 // @Success 200 {object} model.SwaggerOrder
 // @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
+// @Security Bearer
 // @Router /orders/verify-pickup [post]
 func (c *OrderController) VerifyPickupCode(ctx *gin.Context) {
 	var input struct {
+		StoreID    uint   `json:"store_id" binding:"required"`
 		PickupCode string `json:"pickup_code" binding:"required"`
 	}
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -293,21 +340,82 @@ func (c *OrderController) VerifyPickupCode(ctx *gin.Context) {
 		return
 	}
 
-	order, err := c.orderRepo.GetByPickupCode(input.PickupCode)
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Invalid pickup code"})
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	// Update order status to completed
-	now := time.Now()
-	order.Status = model.OrderStatusCompleted
-	order.PickedUpAt = &now
-
-	if err := c.orderRepo.Update(order); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete order"})
+	order, err := c.orderService.VerifyPickup(input.StoreID, input.PickupCode, userID.(uint))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOrderNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Invalid pickup code"})
+			return
+		case errors.Is(err, service.ErrStoreNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+			return
+		case errors.Is(err, service.ErrOrderForbidden):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to verify pickups for this store"})
+			return
+		}
+		writeOrderError(ctx, err, "Failed to complete order")
 		return
 	}
 
-	ctx.JSON(http.StatusOK, order)
+	ctx.JSON(http.StatusOK, order.ToResponse())
+}
+
+// orderPage builds the public view of a page of orders.
+func orderPage(page *model.Page[model.Order]) model.Page[model.OrderResponse] {
+	return model.Page[model.OrderResponse]{Data: model.OrderResponses(page.Data), NextCursor: page.NextCursor, Total: page.Total}
+}
+
+// writeOrderError maps order service errors to HTTP responses.
+func writeOrderError(ctx *gin.Context, err error, fallback string) {
+	var soldOut *service.SoldOutError
+	var transitionErr *service.OrderTransitionError
+	var promoErr *service.PromotionError
+	switch {
+	case errors.As(err, &soldOut):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":         "Not enough quantity available",
+			"code":          "sold_out",
+			"food_bag_id":   soldOut.FoodBagID,
+			"quantity_left": soldOut.Available,
+		})
+	case errors.As(err, &transitionErr):
+		status := http.StatusConflict
+		if transitionErr.Code == service.OrderErrorUnknownStatus {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{"error": transitionErr.Error(), "code": transitionErr.Code})
+	case errors.As(err, &promoErr):
+		status := http.StatusUnprocessableEntity
+		switch promoErr.Code {
+		case service.PromotionErrorExhausted, service.PromotionErrorUserLimitReached:
+			status = http.StatusConflict
+		}
+		ctx.JSON(status, gin.H{"error": promoErr.Error(), "code": promoErr.Code})
+	case errors.Is(err, service.ErrOrderNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case errors.Is(err, service.ErrOrderForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this order"})
+	case errors.Is(err, service.ErrFoodBagNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Food bag not found"})
+	case errors.Is(err, service.ErrFoodBagUnavailable):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Food bag is no longer available"})
+	case errors.Is(err, service.ErrInvalidQuantity), errors.Is(err, service.ErrCancellationReasonRequired):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInsufficientWalletBalance):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "insufficient_wallet_balance"})
+	case errors.Is(err, service.ErrCartEmpty):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "cart_empty"})
+	case errors.Is(err, service.ErrOutsidePickupWindow):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "outside_pickup_window"})
+	case errors.Is(err, service.ErrCancellationCutoffPassed):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "cancellation_cutoff_passed"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
 }
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// bindPage reads the cursor, limit and sort query parameters of a list and
+// writes a 400 response when they are malformed.
+func bindPage(ctx *gin.Context) (model.PageRequest, bool) {
+	var page model.PageRequest
+	if err := ctx.ShouldBindQuery(&page); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return page, false
+	}
+	return page, true
+}
+
+// writeListError maps errors from paginated repository lists to HTTP
+// responses.
+func writeListError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrInvalidCursor):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor", "code": "invalid_cursor"})
+	case errors.Is(err, repository.ErrInvalidSort):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported sort", "code": "invalid_sort"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentController struct {
+	paymentService *service.PaymentService
+	userRepo       *repository.UserRepository
+}
+
+func NewPaymentController(paymentService *service.PaymentService, userRepo *repository.UserRepository) *PaymentController {
+	return &PaymentController{
+		paymentService: paymentService,
+		userRepo:       userRepo,
+	}
+}
+
+// @Summary Pay for an order
+// @Description Start a payment for one of the user's pending orders. The order is confirmed once the provider authorizes the payment.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param payment body model.PaymentInput true "Payment method"
+// @Param Idempotency-Key header string false "Client-generated key that makes retries safe"
+// @Success 201 {object} model.Payment
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 402 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/{id}/pay [post]
+func (c *PaymentController) PayOrder(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var input model.PaymentInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	record, err := c.paymentService.Pay(ctx.Request.Context(), uint(id), userID.(uint), input.PaymentMethod)
+	if err != nil {
+		writePaymentError(ctx, err, "Failed to pay for order")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, record)
+}
+
+// @Summary Get order payments
+// @Description Get every payment attempt for an order
+// @Tags payments
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} model.Payment
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/{id}/payments [get]
+func (c *PaymentController) GetOrderPayments(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	payments, err := c.paymentService.GetForOrder(uint(id), userID.(uint))
+	if err != nil {
+		writePaymentError(ctx, err, "Failed to fetch payments")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, payments)
+}
+
+// @Summary Payment provider webhook
+// @Description Receive signed payment events from the payment provider
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /payments/webhook [post]
+func (c *PaymentController) Webhook(ctx *gin.Context) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	err = c.paymentService.HandleWebhook(ctx.Request.Context(), body, ctx.Request.Header)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, gin.H{"received": true})
+	case errors.Is(err, payment.ErrInvalidSignature):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook signature"})
+	case errors.Is(err, service.ErrPaymentNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+	}
+}
+
+// @Summary List refunds
+// @Description List refunds newest first (admin only)
+// @Tags payments
+// @Produce json
+// @Param status query string false "Refund status" Enums(pending, succeeded, failed)
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/refunds [get]
+func (c *PaymentController) ListRefunds(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	status := model.RefundStatus(ctx.Query("status"))
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	refunds, total, err := c.paymentService.ListRefunds(status, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refunds"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"refunds": refunds,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// @Summary Refund an order
+// @Description Refund all or part of an order's payment (admin only). Without an amount everything left on the payment is refunded.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param refund body model.RefundInput true "Refund details"
+// @Success 201 {object} model.Refund
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/orders/{id}/refunds [post]
+func (c *PaymentController) ForceRefund(ctx *gin.Context) {
+	adminID, ok := c.requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var input model.RefundInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := c.paymentService.Refund(ctx.Request.Context(), service.RefundRequest{
+		OrderID:  uint(id),
+		Amount:   input.Amount,
+		Actor:    model.OrderActorAdmin,
+		ActorID:  &adminID,
+		Reason:   input.Reason,
+		ToWallet: input.ToWallet,
+	})
+	if err != nil {
+		writePaymentError(ctx, err, "Failed to refund order")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, refund)
+}
+
+// requireAdmin writes an error response unless the caller is an admin.
+func (c *PaymentController) requireAdmin(ctx *gin.Context) (uint, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, false
+	}
+
+	user, err := c.userRepo.FindUserById(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify user"})
+		return 0, false
+	}
+	if user.UserType != model.UserTypeAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// writePaymentError maps payment service errors to HTTP responses.
+func writePaymentError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrOrderNotPayable):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "order_not_payable"})
+	case errors.Is(err, service.ErrPaymentInProgress):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "payment_in_progress"})
+	case errors.Is(err, service.ErrPaymentDeclined):
+		ctx.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error(), "code": "payment_declined"})
+	case errors.Is(err, service.ErrPaymentUnavailable):
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "code": "payment_unavailable"})
+	case errors.Is(err, service.ErrNothingToRefund):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "nothing_to_refund"})
+	case errors.Is(err, service.ErrRefundExceedsPayment):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "refund_exceeds_payment"})
+	case errors.Is(err, service.ErrRefundFailed):
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "code": "refund_failed"})
+	case errors.Is(err, service.ErrOrderForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to access this order"})
+	default:
+		writeOrderError(ctx, err, fallback)
+	}
+}
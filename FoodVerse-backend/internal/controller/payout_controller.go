@@ -0,0 +1,356 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PayoutController struct {
+	settlementService *service.SettlementService
+	userRepo          *repository.UserRepository
+}
+
+func NewPayoutController(settlementService *service.SettlementService, userRepo *repository.UserRepository) *PayoutController {
+	return &PayoutController{
+		settlementService: settlementService,
+		userRepo:          userRepo,
+	}
+}
+
+// @Summary Get store earnings
+// @Description Preview what the store has earned since its last payout (store owner or admin)
+// @Tags payouts
+// @Produce json
+// @Param id path int true "Store ID"
+// @Success 200 {object} model.StoreEarnings
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /stores/{id}/earnings [get]
+func (c *PayoutController) GetStoreEarnings(ctx *gin.Context) {
+	storeID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	earnings, err := c.settlementService.Earnings(uint(storeID), userID.(uint))
+	if err != nil {
+		writePayoutError(ctx, err, "Failed to fetch earnings")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, earnings)
+}
+
+// @Summary List store payouts
+// @Description List a store's payouts, newest period first (store owner or admin)
+// @Tags payouts
+// @Produce json
+// @Param id path int true "Store ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /stores/{id}/payouts [get]
+func (c *PayoutController) GetStorePayouts(ctx *gin.Context) {
+	storeID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	page, limit, offset := payoutPage(ctx)
+	payouts, total, err := c.settlementService.StorePayouts(uint(storeID), userID.(uint), limit, offset)
+	if err != nil {
+		writePayoutError(ctx, err, "Failed to fetch payouts")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"payouts": payouts,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// @Summary Get a payout statement
+// @Description Get one of a store's payouts with every order and refund on it (store owner or admin)
+// @Tags payouts
+// @Produce json
+// @Param id path int true "Store ID"
+// @Param payout_id path int true "Payout ID"
+// @Success 200 {object} model.Payout
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /stores/{id}/payouts/{payout_id} [get]
+func (c *PayoutController) GetStatement(ctx *gin.Context) {
+	storeID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+	payoutID, err := strconv.ParseUint(ctx.Param("payout_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payout ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	statement, err := c.settlementService.Statement(uint(storeID), uint(payoutID), userID.(uint))
+	if err != nil {
+		writePayoutError(ctx, err, "Failed to fetch payout")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, statement)
+}
+
+// @Summary List payouts
+// @Description List payouts across stores, newest period first (admin only)
+// @Tags payouts
+// @Produce json
+// @Param store_id query int false "Store ID"
+// @Param status query string false "Payout status" Enums(pending, paid, failed, carried_forward)
+// @Param from query string false "Periods starting on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Periods starting before this date (YYYY-MM-DD or RFC 3339)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/payouts [get]
+func (c *PayoutController) ListPayouts(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	filter, ok := payoutFilter(ctx)
+	if !ok {
+		return
+	}
+
+	page, limit, offset := payoutPage(ctx)
+	payouts, total, err := c.settlementService.List(filter, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payouts"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"payouts": payouts,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// @Summary Export payouts
+// @Description Download payouts as CSV, one row per payout (admin only)
+// @Tags payouts
+// @Produce text/csv
+// @Param store_id query int false "Store ID"
+// @Param status query string false "Payout status" Enums(pending, paid, failed, carried_forward)
+// @Param from query string false "Periods starting on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Periods starting before this date (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {file} file
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/payouts/export [get]
+func (c *PayoutController) ExportPayouts(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	filter, ok := payoutFilter(ctx)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := c.settlementService.ExportCSV(&buf, filter); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export payouts"})
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="payouts.csv"`)
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// @Summary Run settlement
+// @Description Settle the last closed payout period now instead of waiting for the scheduled job (admin only)
+// @Tags payouts
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/payouts/settle [post]
+func (c *PayoutController) Settle(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	if err := c.settlementService.Settle(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Settlement finished with errors: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Settlement completed"})
+}
+
+// @Summary Retry a payout
+// @Description Send a failed payout to the payout provider again (admin only)
+// @Tags payouts
+// @Produce json
+// @Param id path int true "Payout ID"
+// @Success 200 {object} model.Payout
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/payouts/{id}/retry [post]
+func (c *PayoutController) RetryPayout(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	payoutID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payout ID"})
+		return
+	}
+
+	record, err := c.settlementService.Retry(ctx.Request.Context(), uint(payoutID))
+	if err != nil {
+		writePayoutError(ctx, err, "Failed to retry payout")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, record)
+}
+
+// requireAdmin writes an error response unless the caller is an admin.
+func (c *PayoutController) requireAdmin(ctx *gin.Context) (uint, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, false
+	}
+
+	user, err := c.userRepo.FindUserById(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify user"})
+		return 0, false
+	}
+	if user.UserType != model.UserTypeAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return 0, false
+	}
+	return user.ID, true
+}
+
+func payoutPage(ctx *gin.Context) (page, limit, offset int) {
+	page, _ = strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ = strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	return page, limit, (page - 1) * limit
+}
+
+// payoutFilter reads the admin payout filters from the query string and
+// writes a 400 response when one is malformed.
+func payoutFilter(ctx *gin.Context) (repository.PayoutFilter, bool) {
+	filter := repository.PayoutFilter{Status: model.PayoutStatus(ctx.Query("status"))}
+
+	if raw := ctx.Query("store_id"); raw != "" {
+		storeID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+			return filter, false
+		}
+		filter.StoreID = uint(storeID)
+	}
+
+	for _, bound := range []struct {
+		param string
+		dest  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := ctx.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			parsed, err = time.Parse(time.DateOnly, raw)
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + bound.param + " date"})
+			return filter, false
+		}
+		*bound.dest = parsed
+	}
+
+	return filter, true
+}
+
+// writePayoutError maps settlement service errors to HTTP responses.
+func writePayoutError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrStoreNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+	case errors.Is(err, service.ErrPayoutNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payout not found"})
+	case errors.Is(err, service.ErrStoreForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPayoutNotRetryable):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "payout_not_retryable"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/FoodVerse/FoodVerse-backend/pkg/pickuptoken"
+	"github.com/gin-gonic/gin"
+)
+
+type PickupTokenController struct {
+	pickupTokenService *service.PickupTokenService
+}
+
+func NewPickupTokenController(pickupTokenService *service.PickupTokenService) *PickupTokenController {
+	return &PickupTokenController{pickupTokenService: pickupTokenService}
+}
+
+// @Summary Get pickup token public key
+// @Description Get the Ed25519 public key store devices use to verify pickup tokens offline
+// @Tags pickup-tokens
+// @Produce json
+// @Success 200 {object} model.PickupTokenPublicKeyResponse
+// @Router /pickup-tokens/public-key [get]
+func (c *PickupTokenController) GetPublicKey(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, model.PickupTokenPublicKeyResponse{
+		Algorithm: "Ed25519",
+		PublicKey: pickuptoken.EncodePublicKey(c.pickupTokenService.PublicKey()),
+	})
+}
+
+// @Summary Get pickup token
+// @Description Get a signed pickup token for one of the user's paid orders
+// @Tags pickup-tokens
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} model.PickupTokenResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/{id}/pickup-token [get]
+func (c *PickupTokenController) GetPickupToken(ctx *gin.Context) {
+	token, ok := c.issueToken(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, token)
+}
+
+// @Summary Get pickup QR code
+// @Description Get a QR code encoding a signed pickup token for one of the user's paid orders
+// @Tags pickup-tokens
+// @Produce png
+// @Produce image/svg+xml
+// @Param id path int true "Order ID"
+// @Param format query string false "Image format" Enums(png, svg)
+// @Success 200 {file} binary
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/{id}/pickup-qr [get]
+func (c *PickupTokenController) GetPickupQRCode(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", "png")
+	if format != "png" && format != "svg" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Format must be png or svg"})
+		return
+	}
+
+	token, ok := c.issueToken(ctx)
+	if !ok {
+		return
+	}
+
+	image, contentType, err := c.pickupTokenService.RenderQRCode(token.Token, format)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, contentType, image)
+}
+
+// @Summary Verify pickup token
+// @Description Verify a scanned pickup token and complete the order
+// @Tags pickup-tokens
+// @Accept json
+// @Produce json
+// @Param token body model.PickupTokenVerifyInput true "Pickup token"
+// @Success 200 {object} model.SwaggerOrder
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/verify-pickup-token [post]
+func (c *PickupTokenController) VerifyPickupToken(ctx *gin.Context) {
+	var input model.PickupTokenVerifyInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	order, err := c.pickupTokenService.Redeem(input.Token, userID.(uint))
+	if err != nil {
+		switch {
+		case errors.Is(err, pickuptoken.ErrMalformed), errors.Is(err, pickuptoken.ErrInvalidSignature):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_token"})
+		case errors.Is(err, pickuptoken.ErrExpired):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "token_expired"})
+		case errors.Is(err, service.ErrStoreNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+		case errors.Is(err, service.ErrOrderForbidden):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to verify pickups for this store"})
+		default:
+			writeOrderError(ctx, err, "Failed to complete order")
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order.ToResponse())
+}
+
+// @Summary Sync offline pickups
+// @Description Report pickups a store device accepted offline. Each redemption is checked against the time it happened and gets its own result.
+// @Tags pickup-tokens
+// @Accept json
+// @Produce json
+// @Param redemptions body model.PickupRedemptionSyncInput true "Offline redemptions"
+// @Success 200 {array} model.PickupRedemptionResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /orders/sync-pickup-redemptions [post]
+func (c *PickupTokenController) SyncRedemptions(ctx *gin.Context) {
+	var input model.PickupRedemptionSyncInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	results := c.pickupTokenService.Sync(input.Redemptions, userID.(uint))
+	ctx.JSON(http.StatusOK, results)
+}
+
+// issueToken signs a pickup token for the order in the path, writing an
+// error response when that is not possible.
+func (c *PickupTokenController) issueToken(ctx *gin.Context) (*model.PickupTokenResponse, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return nil, false
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	token, err := c.pickupTokenService.IssueToken(uint(id), userID.(uint))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOrderForbidden):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this order"})
+		case errors.Is(err, service.ErrOrderNotCollectable):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "order_not_collectable"})
+		case errors.Is(err, service.ErrOrderNotPaid):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "order_not_paid"})
+		default:
+			writeOrderError(ctx, err, "Failed to issue pickup token")
+		}
+		return nil, false
+	}
+	return token, true
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionController struct {
+	promotionService *service.PromotionService
+	orderService     *service.OrderService
+}
+
+func NewPromotionController(promotionService *service.PromotionService, orderService *service.OrderService) *PromotionController {
+	return &PromotionController{
+		promotionService: promotionService,
+		orderService:     orderService,
+	}
+}
+
+// @Summary Validate a promo code
+// @Description Show the price the given items, or the cart when no items are sent, would have with a promo code. An unusable code is reported with valid=false and a reason code.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param request body model.PromotionValidateInput true "Code and items"
+// @Success 200 {object} model.PromotionQuote
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /promotions/validate [post]
+func (c *PromotionController) ValidateCode(ctx *gin.Context) {
+	var input model.PromotionValidateInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	quote, err := c.orderService.QuotePromotion(userID.(uint), &input)
+	if err != nil {
+		writeOrderError(ctx, err, "Failed to validate promo code")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, quote)
+}
+
+// @Summary Create a promotion
+// @Description Create a promo code. Admins may create any promotion; sellers only ones scoped to a store they own.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param promotion body model.PromotionInput true "Promotion"
+// @Success 201 {object} model.Promotion
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /promotions [post]
+func (c *PromotionController) CreatePromotion(ctx *gin.Context) {
+	var input model.PromotionInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	promotion, err := c.promotionService.Create(userID.(uint), &input)
+	if err != nil {
+		writePromotionError(ctx, err, "Failed to create promotion")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, promotion)
+}
+
+// @Summary List promotions
+// @Description List every promotion (admin) or the promotions of the caller's stores (seller)
+// @Tags promotions
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /promotions [get]
+func (c *PromotionController) ListPromotions(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	promotions, total, err := c.promotionService.List(userID.(uint), limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promotions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"promotions": promotions,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+// @Summary Update a promotion
+// @Description Replace a promotion's settings. Set is_active to false to switch a code off.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Param promotion body model.PromotionInput true "Promotion"
+// @Success 200 {object} model.Promotion
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /promotions/{id} [put]
+func (c *PromotionController) UpdatePromotion(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	var input model.PromotionInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	promotion, err := c.promotionService.Update(uint(id), userID.(uint), &input)
+	if err != nil {
+		writePromotionError(ctx, err, "Failed to update promotion")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, promotion)
+}
+
+// writePromotionError maps promotion management errors to HTTP responses.
+func writePromotionError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrPromotionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+	case errors.Is(err, service.ErrPromotionForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPromotionCodeTaken):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "promo_code_taken"})
+	case errors.Is(err, service.ErrInvalidPromotion):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Percentage discounts may not exceed 100 and ends_at must be after starts_at"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type RecommendationController struct {
+	recommendationService *service.RecommendationService
+}
+
+func NewRecommendationController(recommendationService *service.RecommendationService) *RecommendationController {
+	return &RecommendationController{recommendationService: recommendationService}
+}
+
+// @Summary Recommended food bags
+// @Description Rank the food bags on sale near a location for the signed-in user, from the categories, stores, prices and pickup times of their past orders. Users without orders get the most popular bags nearby.
+// @Tags food-bags
+// @Produce json
+// @Param latitude query number true "Latitude"
+// @Param longitude query number true "Longitude"
+// @Param radius query number false "Radius in kilometers (default 5)"
+// @Param limit query int false "Number of bags (default 10, max 50)"
+// @Success 200 {array} model.RecommendedFoodBag
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bags/recommended [get]
+func (c *RecommendationController) GetRecommended(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.RecommendationRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recommendations, err := c.recommendationService.Recommend(userID.(uint), req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recommendations"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, recommendations)
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SavedSearchController struct {
+	alertService *service.AlertService
+}
+
+func NewSavedSearchController(alertService *service.AlertService) *SavedSearchController {
+	return &SavedSearchController{alertService: alertService}
+}
+
+// @Summary Save a search
+// @Description Save a food bag search as a named alert. Bags that go on sale matching it produce notifications, at most one per saved search every throttle period.
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param search body model.SavedSearchInput true "Saved search"
+// @Success 201 {object} model.SavedSearch
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /saved-searches [post]
+func (c *SavedSearchController) CreateSavedSearch(ctx *gin.Context) {
+	var input model.SavedSearchInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	savedSearch, err := c.alertService.CreateSavedSearch(userID.(uint), &input)
+	if err != nil {
+		writeSavedSearchError(ctx, err, "Failed to save search")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, savedSearch)
+}
+
+// @Summary List saved searches
+// @Description List the user's saved searches, newest first
+// @Tags saved-searches
+// @Produce json
+// @Success 200 {array} model.SavedSearch
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /saved-searches [get]
+func (c *SavedSearchController) ListSavedSearches(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	savedSearches, err := c.alertService.ListSavedSearches(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved searches"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, savedSearches)
+}
+
+// @Summary Update a saved search
+// @Description Change a saved search or turn its alerts on or off. Only bags listed from now on are matched against the change.
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Param search body model.SavedSearchInput true "Saved search"
+// @Success 200 {object} model.SavedSearch
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /saved-searches/{id} [put]
+func (c *SavedSearchController) UpdateSavedSearch(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	var input model.SavedSearchInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	savedSearch, err := c.alertService.UpdateSavedSearch(uint(id), userID.(uint), &input)
+	if err != nil {
+		writeSavedSearchError(ctx, err, "Failed to update saved search")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, savedSearch)
+}
+
+// @Summary Delete a saved search
+// @Description Delete a saved search. Notifications it already sent are kept.
+// @Tags saved-searches
+// @Param id path int true "Saved search ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /saved-searches/{id} [delete]
+func (c *SavedSearchController) DeleteSavedSearch(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.alertService.DeleteSavedSearch(uint(id), userID.(uint)); err != nil {
+		writeSavedSearchError(ctx, err, "Failed to delete saved search")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// writeSavedSearchError maps saved search service errors to HTTP
+// responses.
+func writeSavedSearchError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSavedSearch):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSavedSearchNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSavedSearchLimit):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "saved_search_limit"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
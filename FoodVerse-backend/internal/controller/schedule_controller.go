@@ -0,0 +1,435 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleController struct {
+	scheduleService *service.ScheduleService
+}
+
+func NewScheduleController(scheduleService *service.ScheduleService) *ScheduleController {
+	return &ScheduleController{scheduleService: scheduleService}
+}
+
+// @Summary Create a food bag template
+// @Description Create a reusable food bag for one of the seller's stores
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param template body model.FoodBagTemplateInput true "Template"
+// @Success 201 {object} model.FoodBagTemplate
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-templates [post]
+func (c *ScheduleController) CreateTemplate(ctx *gin.Context) {
+	var input model.FoodBagTemplateInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	template, err := c.scheduleService.CreateTemplate(userID.(uint), &input)
+	if err != nil {
+		writeScheduleError(ctx, err, "Failed to create template")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, template)
+}
+
+// @Summary List food bag templates
+// @Description List the templates of the seller's stores
+// @Tags schedules
+// @Produce json
+// @Success 200 {array} model.FoodBagTemplate
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-templates [get]
+func (c *ScheduleController) ListTemplates(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	templates, err := c.scheduleService.ListTemplates(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, templates)
+}
+
+// @Summary Update a food bag template
+// @Description Change a template. Only bags created from now on pick up the change.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param template body model.FoodBagTemplateInput true "Template"
+// @Success 200 {object} model.FoodBagTemplate
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-templates/{id} [put]
+func (c *ScheduleController) UpdateTemplate(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var input model.FoodBagTemplateInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	template, err := c.scheduleService.UpdateTemplate(uint(id), userID.(uint), &input)
+	if err != nil {
+		writeScheduleError(ctx, err, "Failed to update template")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, template)
+}
+
+// @Summary Delete a food bag template
+// @Description Delete a template and its schedules. Bags already created stay listed.
+// @Tags schedules
+// @Param id path int true "Template ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-templates/{id} [delete]
+func (c *ScheduleController) DeleteTemplate(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.scheduleService.DeleteTemplate(uint(id), userID.(uint)); err != nil {
+		writeScheduleError(ctx, err, "Failed to delete template")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Create a food bag schedule
+// @Description List a template on a recurring basis, e.g. weekdays 18:00-20:00 in the store's timezone. Bags are created a few days ahead by a background job.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param schedule body model.FoodBagScheduleInput true "Schedule"
+// @Success 201 {object} model.FoodBagSchedule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules [post]
+func (c *ScheduleController) CreateSchedule(ctx *gin.Context) {
+	var input model.FoodBagScheduleInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	schedule, err := c.scheduleService.CreateSchedule(userID.(uint), &input)
+	if err != nil {
+		writeScheduleError(ctx, err, "Failed to create schedule")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, schedule)
+}
+
+// @Summary List food bag schedules
+// @Description List the schedules of the seller's stores
+// @Tags schedules
+// @Produce json
+// @Success 200 {array} model.FoodBagSchedule
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules [get]
+func (c *ScheduleController) ListSchedules(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	schedules, err := c.scheduleService.ListSchedules(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedules)
+}
+
+// @Summary Update a food bag schedule
+// @Description Change a schedule's recurrence. Bags already created are left as they are.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param schedule body model.FoodBagScheduleInput true "Schedule"
+// @Success 200 {object} model.FoodBagSchedule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules/{id} [put]
+func (c *ScheduleController) UpdateSchedule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	var input model.FoodBagScheduleInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	schedule, err := c.scheduleService.UpdateSchedule(uint(id), userID.(uint), &input)
+	if err != nil {
+		writeScheduleError(ctx, err, "Failed to update schedule")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Delete a food bag schedule
+// @Description Stop a schedule for good. Bags already created stay listed.
+// @Tags schedules
+// @Param id path int true "Schedule ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules/{id} [delete]
+func (c *ScheduleController) DeleteSchedule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.scheduleService.DeleteSchedule(uint(id), userID.(uint)); err != nil {
+		writeScheduleError(ctx, err, "Failed to delete schedule")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Pause a food bag schedule
+// @Description Stop creating bags until the schedule is resumed. Bags already created stay listed.
+// @Tags schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} model.FoodBagSchedule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules/{id}/pause [post]
+func (c *ScheduleController) PauseSchedule(ctx *gin.Context) {
+	c.setPaused(ctx, true)
+}
+
+// @Summary Resume a food bag schedule
+// @Description Start creating bags for a paused schedule again
+// @Tags schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} model.FoodBagSchedule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules/{id}/resume [post]
+func (c *ScheduleController) ResumeSchedule(ctx *gin.Context) {
+	c.setPaused(ctx, false)
+}
+
+func (c *ScheduleController) setPaused(ctx *gin.Context, paused bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	schedule, err := c.scheduleService.SetPaused(uint(id), userID.(uint), paused)
+	if err != nil {
+		writeScheduleError(ctx, err, "Failed to update schedule")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Skip a date
+// @Description Skip one local date of a schedule. A bag already created for that date is taken off sale unless it has orders.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param skip body model.FoodBagScheduleSkipInput true "Date to skip"
+// @Success 200 {object} model.FoodBagSchedule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules/{id}/skips [post]
+func (c *ScheduleController) SkipDate(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	var input model.FoodBagScheduleSkipInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	schedule, err := c.scheduleService.SkipDate(uint(id), userID.(uint), input.Date)
+	if err != nil {
+		writeScheduleError(ctx, err, "Failed to skip date")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Unskip a date
+// @Description List a skipped date again
+// @Tags schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param date path string true "Skipped date (YYYY-MM-DD)"
+// @Success 200 {object} model.FoodBagSchedule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /food-bag-schedules/{id}/skips/{date} [delete]
+func (c *ScheduleController) UnskipDate(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	schedule, err := c.scheduleService.UnskipDate(uint(id), userID.(uint), ctx.Param("date"))
+	if err != nil {
+		writeScheduleError(ctx, err, "Failed to unskip date")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+// writeScheduleError maps schedule service errors to HTTP responses.
+func writeScheduleError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSchedule):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTemplateNotFound), errors.Is(err, service.ErrScheduleNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrStoreNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+	case errors.Is(err, service.ErrScheduleForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrScheduledBagHasOrders):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "scheduled_bag_has_orders"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct {
+	suggestionRepo *repository.SearchSuggestionRepository
+}
+
+func NewSearchController(suggestionRepo *repository.SearchSuggestionRepository) *SearchController {
+	return &SearchController{suggestionRepo: suggestionRepo}
+}
+
+// @Summary Search suggestions
+// @Description Suggest stores, food bags and categories near a location whose name, title or category starts with what has been typed, most ordered first
+// @Tags search
+// @Produce json
+// @Param q query string true "What has been typed so far"
+// @Param latitude query number true "Latitude"
+// @Param longitude query number true "Longitude"
+// @Param radius query number false "Radius in kilometers (default 5)"
+// @Param limit query int false "Number of suggestions (default 8, max 20)"
+// @Success 200 {array} model.SuggestionResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Router /search/suggest [get]
+func (c *SearchController) Suggest(ctx *gin.Context) {
+	var req model.SuggestRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestions, err := c.suggestionRepo.Suggest(req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suggestions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, suggestions)
+}
@@ -90,6 +90,7 @@ func (sc *SeedController) seedDatabaseFixed() error {
 			Category:    "Bakery",
 			ImageURL:    "https://images.unsplash.com/photo-1509440159596-0249088772ff?auto=format&fit=crop&w=400&q=80",
 			Rating:      4.8,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     sellers[0].ID, // Alice
 		},
@@ -104,6 +105,7 @@ func (sc *SeedController) seedDatabaseFixed() error {
 			Category:    "Cafe",
 			ImageURL:    "https://images.unsplash.com/photo-1501339847302-ac426a4a7cbb?auto=format&fit=crop&w=400&q=80",
 			Rating:      4.6,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     sellers[1].ID, // Bob
 		},
@@ -118,6 +120,7 @@ func (sc *SeedController) seedDatabaseFixed() error {
 			Category:    "Deli",
 			ImageURL:    "https://images.unsplash.com/photo-1555396273-367ea4eb4db5?auto=format&fit=crop&w=400&q=80",
 			Rating:      4.7,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     sellers[2].ID, // Carol
 		},
@@ -132,6 +135,7 @@ func (sc *SeedController) seedDatabaseFixed() error {
 			Category:    "Grill",
 			ImageURL:    "https://images.unsplash.com/photo-1555939594-58d7cb561ad1?auto=format&fit=crop&w=400&q=80",
 			Rating:      4.5,
+			Timezone:    "Asia/Jakarta",
 			IsActive:    true,
 			OwnerID:     sellers[3].ID, // Dave
 		},
@@ -3,6 +3,7 @@ package controller
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/FoodVerse/FoodVerse-backend/internal/model"
 	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
@@ -53,6 +54,17 @@ func (c *StoreController) CreateStore(ctx *gin.Context) {
 		return
 	}
 
+	timezone, ok := storeTimezone(ctx, input.Timezone)
+	if !ok {
+		return
+	}
+
+	openingHours, msg := openingHoursFromInput(input.OpeningHours)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	store := &model.Store{
 		Name:        input.Name,
 		Description: input.Description,
@@ -65,6 +77,9 @@ func (c *StoreController) CreateStore(ctx *gin.Context) {
 		ImageURL:    input.ImageURL,
 		OwnerID:     userID.(uint),
 		IsActive:    true,
+		Timezone:    timezone,
+
+		OpeningHours: openingHours,
 	}
 	if err := c.storeRepo.Create(store); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create store"})
@@ -104,7 +119,7 @@ func (c *StoreController) GetStore(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param search body model.SwaggerStoreSearchRequest true "Search criteria"
-// @Success 200 {array} model.SwaggerStoreResponse
+// @Success 200 {object} model.Page[model.SwaggerStoreResponse]
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /stores/search [post]
@@ -117,7 +132,7 @@ func (c *StoreController) SearchStores(ctx *gin.Context) {
 
 	stores, err := c.storeRepo.SearchNearby(req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search stores"})
+		writeListError(ctx, err, "Failed to search stores")
 		return
 	}
 
@@ -125,6 +140,41 @@ func (c *StoreController) SearchStores(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, stores)
 }
 
+// @Summary Stores on the map
+// @Description Get the active stores inside a map viewport as a GeoJSON FeatureCollection, with their bags on sale. Below zoom 15 nearby stores are grouped into clusters with their store count, bag count and price range.
+// @Tags stores
+// @Produce json
+// @Param min_lat query number true "South edge"
+// @Param min_lon query number true "West edge"
+// @Param max_lat query number true "North edge"
+// @Param max_lon query number true "East edge; west of min_lon when the viewport crosses the antimeridian"
+// @Param zoom query int true "Map zoom level, 0 to 22"
+// @Param category query string false "Store category"
+// @Success 200 {object} model.FeatureCollection
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stores/map [get]
+func (c *StoreController) GetMapStores(ctx *gin.Context) {
+	var req model.MapRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.MinLatitude > req.MaxLatitude {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "min_lat must not be north of max_lat"})
+		return
+	}
+
+	features, err := c.storeRepo.GetMapFeatures(req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get map stores"})
+		return
+	}
+
+	ctx.Header("Content-Type", "application/geo+json")
+	ctx.JSON(http.StatusOK, features)
+}
+
 // @Summary Get user's stores
 // @Description Get all stores owned by the authenticated user
 // @Tags stores
@@ -220,6 +270,17 @@ func (c *StoreController) UpdateStore(ctx *gin.Context) {
 		return
 	}
 
+	timezone, ok := storeTimezone(ctx, input.Timezone)
+	if !ok {
+		return
+	}
+
+	openingHours, msg := openingHoursFromInput(input.OpeningHours)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	// Update store fields
 	store.Name = input.Name
 	store.Description = input.Description
@@ -230,12 +291,22 @@ func (c *StoreController) UpdateStore(ctx *gin.Context) {
 	store.Email = input.Email
 	store.Category = input.Category
 	store.ImageURL = input.ImageURL
+	store.Timezone = timezone
 
 	if err := c.storeRepo.Update(store); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update store"})
 		return
 	}
 
+	// Leave the weekly hours alone unless the request carries them.
+	if input.OpeningHours != nil {
+		if err := c.storeRepo.ReplaceOpeningHours(store.ID, openingHours); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update opening hours"})
+			return
+		}
+		store.OpeningHours = openingHours
+	}
+
 	ctx.JSON(http.StatusOK, store.ToResponse())
 }
 
@@ -326,3 +397,197 @@ func (c *StoreController) DeleteStore(ctx *gin.Context) {
 
 	ctx.Status(http.StatusNoContent)
 }
+
+// @Summary List store hours exceptions
+// @Description List a store's holidays and special hours from today on
+// @Tags stores
+// @Produce json
+// @Param id path int true "Store ID"
+// @Success 200 {array} model.StoreHoursException
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /stores/{id}/hours-exceptions [get]
+func (c *StoreController) GetHoursExceptions(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	store, err := c.storeRepo.GetByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+		return
+	}
+
+	today := time.Now().In(store.Location()).Format("2006-01-02")
+	exceptions, err := c.storeRepo.GetHoursExceptions(store.ID, today)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hours exceptions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, exceptions)
+}
+
+// @Summary Set a store hours exception
+// @Description Close the store or set special hours on one local date, replacing any exception already set for that date
+// @Tags stores
+// @Accept json
+// @Produce json
+// @Param id path int true "Store ID"
+// @Param exception body model.StoreHoursExceptionInput true "Exception"
+// @Success 200 {object} model.StoreHoursException
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /stores/{id}/hours-exceptions [post]
+func (c *StoreController) SetHoursException(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input model.StoreHoursExceptionInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := c.storeRepo.GetByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+		return
+	}
+
+	// Check if user owns the store
+	if store.OwnerID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to modify this store"})
+		return
+	}
+
+	if _, err := time.Parse("2006-01-02", input.Date); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+		return
+	}
+
+	exception := &model.StoreHoursException{
+		StoreID:  store.ID,
+		Date:     input.Date,
+		IsClosed: input.IsClosed,
+		Reason:   input.Reason,
+	}
+	if !input.IsClosed {
+		if !model.ValidClock(input.Opens) || !model.ValidClock(input.Closes) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "opens and closes must be HH:MM unless the store is closed"})
+			return
+		}
+		exception.Opens = input.Opens
+		exception.Closes = input.Closes
+	}
+
+	if err := c.storeRepo.SaveHoursException(exception); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save hours exception"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, exception)
+}
+
+// @Summary Delete a store hours exception
+// @Description Go back to the weekly hours on an exception's date
+// @Tags stores
+// @Param id path int true "Store ID"
+// @Param exception_id path int true "Exception ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /stores/{id}/hours-exceptions/{exception_id} [delete]
+func (c *StoreController) DeleteHoursException(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	exceptionID, err := strconv.ParseUint(ctx.Param("exception_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exception ID"})
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	store, err := c.storeRepo.GetByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+		return
+	}
+
+	// Check if user owns the store
+	if store.OwnerID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to modify this store"})
+		return
+	}
+
+	deleted, err := c.storeRepo.DeleteHoursException(store.ID, uint(exceptionID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete hours exception"})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Hours exception not found"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// openingHoursFromInput checks weekly opening hours from store input and
+// returns a message describing the first problem found.
+func openingHoursFromInput(input []model.StoreOpeningHoursInput) ([]model.StoreOpeningHours, string) {
+	hours := make([]model.StoreOpeningHours, 0, len(input))
+	for _, interval := range input {
+		if interval.Weekday < 0 || interval.Weekday > 6 {
+			return nil, "opening_hours weekday must be 0 (Sunday) through 6 (Saturday)"
+		}
+		if !model.ValidClock(interval.Opens) || !model.ValidClock(interval.Closes) {
+			return nil, "opening_hours opens and closes must be HH:MM"
+		}
+		hours = append(hours, model.StoreOpeningHours{
+			Weekday: interval.Weekday,
+			Opens:   interval.Opens,
+			Closes:  interval.Closes,
+		})
+	}
+	return hours, ""
+}
+
+// storeTimezone checks a timezone from store input, defaulting to UTC, and
+// writes a 400 response when it is not a known IANA zone.
+func storeTimezone(ctx *gin.Context, timezone string) (string, bool) {
+	if timezone == "" {
+		return "UTC", true
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone " + strconv.Quote(timezone)})
+		return "", false
+	}
+	return timezone, true
+}
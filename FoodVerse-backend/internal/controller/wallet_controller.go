@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WalletController struct {
+	walletService *service.WalletService
+	userRepo      *repository.UserRepository
+}
+
+func NewWalletController(walletService *service.WalletService, userRepo *repository.UserRepository) *WalletController {
+	return &WalletController{
+		walletService: walletService,
+		userRepo:      userRepo,
+	}
+}
+
+// @Summary Get wallet
+// @Description Get the current user's wallet balance
+// @Tags wallet
+// @Produce json
+// @Success 200 {object} model.WalletResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /wallet [get]
+func (c *WalletController) GetWallet(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	wallet, err := c.walletService.Balance(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wallet)
+}
+
+// @Summary Get wallet transactions
+// @Description Get the entries on the current user's wallet, newest first. Amounts are in cents.
+// @Tags wallet
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /wallet/transactions [get]
+func (c *WalletController) GetTransactions(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	entries, total, err := c.walletService.Transactions(userID.(uint), limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet transactions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// @Summary Adjust a wallet
+// @Description Credit (positive amount) or debit (negative amount) a user's wallet (admin only)
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Param adjustment body model.WalletAdjustmentInput true "Adjustment"
+// @Success 201 {object} model.LedgerTransaction
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/wallets/{user_id}/adjustments [post]
+func (c *WalletController) AdjustWallet(ctx *gin.Context) {
+	adminID, ok := c.requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(ctx.Param("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input model.WalletAdjustmentInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := c.userRepo.FindUserById(uint(userID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	transaction, err := c.walletService.Adjust(adminID, uint(userID), payment.ToMinorUnits(input.Amount), input.Reason)
+	if err != nil {
+		writeWalletError(ctx, err, "Failed to adjust wallet")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, transaction)
+}
+
+// @Summary Reconcile the ledger
+// @Description Check that ledger transactions balance and cached wallet balances match their entries (admin only)
+// @Tags wallet
+// @Produce json
+// @Success 200 {object} model.ReconciliationReport
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Security Bearer
+// @Router /admin/wallets/reconciliation [get]
+func (c *WalletController) Reconcile(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	report, err := c.walletService.Reconcile()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// requireAdmin writes an error response unless the caller is an admin.
+func (c *WalletController) requireAdmin(ctx *gin.Context) (uint, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, false
+	}
+
+	user, err := c.userRepo.FindUserById(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify user"})
+		return 0, false
+	}
+	if user.UserType != model.UserTypeAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// writeWalletError maps wallet service errors to HTTP responses.
+func writeWalletError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInsufficientWalletBalance):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "insufficient_wallet_balance"})
+	case errors.Is(err, service.ErrInvalidWalletAmount):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyAbandonedAfter = time.Minute
+)
+
+// IdempotencyMiddleware makes POST, PUT and PATCH requests that carry an
+// Idempotency-Key header safe to retry. The first response for a user and key
+// is stored and replayed for later requests with the same method, path and
+// body. Reusing a key for a different request is rejected with 422, and a
+// retry that arrives while the first request is still running gets 409.
+// Server errors are not stored so the client can try again.
+//
+// It must run after JWTAuthMiddleware because keys are scoped per user.
+func IdempotencyMiddleware(repo *repository.IdempotencyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isIdempotentMethod(ctx.Request.Method) {
+			ctx.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		userID, exists := ctx.Get("user_id")
+		if !exists {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &model.IdempotencyKey{
+			UserID:      userID.(uint),
+			Key:         key,
+			Method:      ctx.Request.Method,
+			Path:        ctx.Request.URL.Path,
+			Fingerprint: requestFingerprint(ctx.Request.Method, ctx.Request.URL.Path, body),
+		}
+
+		reserved, err := reserveIdempotencyKey(repo, record)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to store idempotency key"})
+			return
+		}
+		if !reserved {
+			replayIdempotentResponse(ctx, repo, record)
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+
+		if writer.Status() >= http.StatusInternalServerError {
+			_ = repo.Delete(record.ID)
+			return
+		}
+		_ = repo.Complete(record.ID, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes())
+	}
+}
+
+// reserveIdempotencyKey stores record, taking over a key whose first request
+// was abandoned mid-flight.
+func reserveIdempotencyKey(repo *repository.IdempotencyRepository, record *model.IdempotencyKey) (bool, error) {
+	reserved, err := repo.Reserve(record)
+	if err != nil || reserved {
+		return reserved, err
+	}
+
+	existing, err := repo.GetByUserAndKey(record.UserID, record.Key)
+	if err != nil {
+		return false, err
+	}
+	if existing.Fingerprint != record.Fingerprint {
+		return false, nil
+	}
+
+	abandoned, err := repo.DeleteUnfinished(existing.ID, time.Now().Add(-idempotencyAbandonedAfter))
+	if err != nil || !abandoned {
+		return false, err
+	}
+	return repo.Reserve(record)
+}
+
+func replayIdempotentResponse(ctx *gin.Context, repo *repository.IdempotencyRepository, record *model.IdempotencyKey) {
+	existing, err := repo.GetByUserAndKey(record.UserID, record.Key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to load idempotency key"})
+		return
+	}
+
+	if existing.Fingerprint != record.Fingerprint {
+		ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency-Key was already used for a different request",
+			"code":  "idempotency_key_reused",
+		})
+		return
+	}
+
+	if existing.CompletedAt == nil {
+		ctx.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "A request with this Idempotency-Key is still being processed",
+			"code":  "idempotency_request_in_progress",
+		})
+		return
+	}
+
+	ctx.Header(IdempotentReplayedHeader, "true")
+	ctx.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+	ctx.Abort()
+}
+
+func isIdempotentMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// capturingWriter keeps a copy of the response body so it can be stored.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyTest is a router behind IdempotencyMiddleware whose handlers
+// count how often they actually run. The X-Test-User header stands in for
+// JWTAuthMiddleware.
+type idempotencyTest struct {
+	router *gin.Engine
+	calls  atomic.Int32
+	// entered and release hold /slow requests in the handler.
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newIdempotencyTest(t *testing.T) *idempotencyTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewIdempotencyRepository(testdb.Open(t))
+
+	it := &idempotencyTest{router: gin.New(), entered: make(chan struct{}), release: make(chan struct{})}
+	it.router.Use(func(ctx *gin.Context) {
+		userID, _ := strconv.Atoi(ctx.GetHeader("X-Test-User"))
+		ctx.Set("user_id", uint(userID))
+		ctx.Next()
+	}, IdempotencyMiddleware(repo))
+
+	it.router.POST("/orders", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusCreated, gin.H{"call": it.calls.Add(1)})
+	})
+	it.router.POST("/flaky", func(ctx *gin.Context) {
+		if it.calls.Add(1) == 1 {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "try again"})
+			return
+		}
+		ctx.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	it.router.POST("/slow", func(ctx *gin.Context) {
+		it.calls.Add(1)
+		it.entered <- struct{}{}
+		<-it.release
+		ctx.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	return it
+}
+
+func (it *idempotencyTest) post(userID uint, key, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", strconv.Itoa(int(userID)))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	it.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysFirstResponse(t *testing.T) {
+	it := newIdempotencyTest(t)
+
+	first := it.post(1, "key-1", "/orders", `{"food_bag_id":1}`)
+	retry := it.post(1, "key-1", "/orders", `{"food_bag_id":1}`)
+
+	if first.Code != http.StatusCreated || retry.Code != first.Code {
+		t.Fatalf("status = %d then %d, want 201 twice", first.Code, retry.Code)
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("replayed body = %s, want %s", retry.Body, first.Body)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("replayed response is missing the Idempotent-Replayed header")
+	}
+	if calls := it.calls.Load(); calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyKeyReusedForDifferentBody(t *testing.T) {
+	it := newIdempotencyTest(t)
+
+	it.post(1, "key-1", "/orders", `{"food_bag_id":1}`)
+	rec := it.post(1, "key-1", "/orders", `{"food_bag_id":2}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+	if calls := it.calls.Load(); calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyRetryWhileInFlight(t *testing.T) {
+	it := newIdempotencyTest(t)
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = it.post(1, "key-1", "/slow", `{}`)
+	}()
+	<-it.entered
+
+	retry := it.post(1, "key-1", "/slow", `{}`)
+	close(it.release)
+	wg.Wait()
+
+	if retry.Code != http.StatusConflict {
+		t.Errorf("retry status = %d, want 409", retry.Code)
+	}
+	if first.Code != http.StatusCreated {
+		t.Errorf("first status = %d, want 201", first.Code)
+	}
+	if calls := it.calls.Load(); calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyServerErrorIsNotStored(t *testing.T) {
+	it := newIdempotencyTest(t)
+
+	first := it.post(1, "key-1", "/flaky", `{}`)
+	retry := it.post(1, "key-1", "/flaky", `{}`)
+
+	if first.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first status = %d, want 503", first.Code)
+	}
+	if retry.Code != http.StatusCreated {
+		t.Errorf("retry status = %d, want 201", retry.Code)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("retry after a server error was replayed instead of run")
+	}
+}
+
+func TestIdempotencyKeysAreScopedPerUser(t *testing.T) {
+	it := newIdempotencyTest(t)
+
+	it.post(1, "key-1", "/orders", `{"food_bag_id":1}`)
+	other := it.post(2, "key-1", "/orders", `{"food_bag_id":1}`)
+
+	if other.Code != http.StatusCreated || other.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("other user's request: status %d, replayed %q; want it to run", other.Code, other.Header().Get(IdempotentReplayedHeader))
+	}
+	if calls := it.calls.Load(); calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
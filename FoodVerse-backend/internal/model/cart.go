@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+)
+
+// CartItem is one food bag in a user's cart. Items are removed for good when
+// they leave the cart, so the unique index only ever sees live rows.
+type CartItem struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_cart_items_user_food_bag"`
+	FoodBagID uint      `json:"food_bag_id" gorm:"uniqueIndex:idx_cart_items_user_food_bag"`
+	FoodBag   FoodBag   `json:"food_bag" gorm:"foreignKey:FoodBagID"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CartItemInput struct {
+	FoodBagID uint `json:"food_bag_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
+type CartItemUpdateInput struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+type CheckoutInput struct {
+	Notes string `json:"notes"`
+	// WalletAmount is how much of the wallet balance to spend across the
+	// resulting orders, at most their total.
+	WalletAmount float64 `json:"wallet_amount" binding:"omitempty,gte=0"`
+	PromoCode    string  `json:"promo_code"`
+}
+
+// CartItemResponse prices a cart line at the food bag's current price.
+// Available is false when the bag is inactive or can no longer cover the
+// quantity; checkout would reject the cart until the line is fixed.
+type CartItemResponse struct {
+	FoodBagID uint    `json:"food_bag_id"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Subtotal  float64 `json:"subtotal"`
+	Available bool    `json:"available"`
+	FoodBag   FoodBag `json:"food_bag"`
+}
+
+type CartResponse struct {
+	Items []CartItemResponse `json:"items"`
+	Total float64            `json:"total"`
+}
+
+// ToCartResponse prices the cart items. Unavailable lines are listed but left out
+// of the total.
+func ToCartResponse(items []CartItem) CartResponse {
+	response := CartResponse{Items: make([]CartItemResponse, 0, len(items))}
+	for _, item := range items {
+		subtotal := item.FoodBag.CurrentPrice * float64(item.Quantity)
+		available := item.FoodBag.IsActive && item.FoodBag.QuantityLeft >= item.Quantity
+		response.Items = append(response.Items, CartItemResponse{
+			FoodBagID: item.FoodBagID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.FoodBag.CurrentPrice,
+			Subtotal:  subtotal,
+			Available: available,
+			FoodBag:   item.FoodBag,
+		})
+		if available {
+			response.Total += subtotal
+		}
+	}
+	return response
+}
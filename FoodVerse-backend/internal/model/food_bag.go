@@ -1,11 +1,33 @@
 package model
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// PricingStrategy decides how a food bag's price moves over its pickup
+// window.
+type PricingStrategy string
+
+const (
+	// PricingFixed keeps the bag at DiscountedPrice.
+	PricingFixed PricingStrategy = "fixed"
+	// PricingLinear lowers the price steadily from DiscountedPrice at
+	// PickupTimeStart to PriceFloor at PickupTimeEnd.
+	PricingLinear PricingStrategy = "linear"
+	// PricingStep splits the pickup window into PriceSteps equal parts and
+	// drops the price at the start of each, from DiscountedPrice in the first
+	// to PriceFloor in the last.
+	PricingStep PricingStrategy = "step"
+)
+
+// defaultPriceSteps is used for step pricing when PriceSteps is not set.
+const defaultPriceSteps = 4
+
 type FoodBag struct {
 	gorm.Model
 	StoreID         uint      `json:"store_id" binding:"required"`
@@ -22,6 +44,181 @@ type FoodBag struct {
 	Category        string    `json:"category"` // same as store category or specific food type
 	IsActive        bool      `json:"is_active" gorm:"default:true"`
 	Orders          []Order   `json:"orders,omitempty"`
+
+	PricingStrategy PricingStrategy `json:"pricing_strategy" gorm:"default:'fixed'"`
+	PriceFloor      float64         `json:"price_floor"`
+	PriceSteps      int             `json:"price_steps"`
+	// ScheduleID and ScheduledDate are set on bags a schedule created, one
+	// per schedule and local date.
+	ScheduleID    *uint  `json:"schedule_id,omitempty" gorm:"uniqueIndex:idx_food_bags_schedule_date"`
+	ScheduledDate string `json:"scheduled_date,omitempty" gorm:"uniqueIndex:idx_food_bags_schedule_date"`
+
+	// CurrentPrice is what the bag costs right now. It is worked out when
+	// the bag is loaded and never stored.
+	CurrentPrice float64 `json:"current_price" gorm:"-"`
+}
+
+func (f *FoodBag) AfterFind(tx *gorm.DB) error {
+	// The driver returns times in the server's zone; serve them in UTC.
+	f.PickupTimeStart = f.PickupTimeStart.UTC()
+	f.PickupTimeEnd = f.PickupTimeEnd.UTC()
+	f.CurrentPrice = f.PriceAt(time.Now())
+	return nil
+}
+
+func (f *FoodBag) AfterSave(tx *gorm.DB) error {
+	f.CurrentPrice = f.PriceAt(time.Now())
+	return nil
+}
+
+// PriceAt returns the bag's price at the given time, rounded to cents. It
+// never goes below PriceFloor or above DiscountedPrice.
+func (f *FoodBag) PriceAt(at time.Time) float64 {
+	start, floor := f.DiscountedPrice, f.PriceFloor
+	if !f.decays() {
+		return start
+	}
+
+	window := f.PickupTimeEnd.Sub(f.PickupTimeStart)
+	elapsed := at.Sub(f.PickupTimeStart)
+	if elapsed <= 0 {
+		return start
+	}
+	if elapsed >= window {
+		return floor
+	}
+
+	progress := float64(elapsed) / float64(window)
+	if f.PricingStrategy == PricingStep {
+		steps := f.priceSteps()
+		progress = math.Floor(progress*float64(steps)) / float64(steps-1)
+	}
+
+	price := math.Round((start-(start-floor)*progress)*100) / 100
+	return math.Max(price, floor)
+}
+
+// NextPriceDropAt returns when a step-priced bag next gets cheaper, or nil
+// if its price is not going to drop in steps any more.
+func (f *FoodBag) NextPriceDropAt(at time.Time) *time.Time {
+	if f.PricingStrategy != PricingStep || !f.decays() {
+		return nil
+	}
+
+	steps := f.priceSteps()
+	interval := f.PickupTimeEnd.Sub(f.PickupTimeStart) / time.Duration(steps)
+	for step := 1; step < steps; step++ {
+		drop := f.PickupTimeStart.Add(time.Duration(step) * interval)
+		if drop.After(at) {
+			return &drop
+		}
+	}
+	return nil
+}
+
+// decays reports whether the bag has a usable decaying pricing rule.
+func (f *FoodBag) decays() bool {
+	switch f.PricingStrategy {
+	case PricingLinear, PricingStep:
+		return f.PriceFloor < f.DiscountedPrice && f.PickupTimeEnd.After(f.PickupTimeStart)
+	}
+	return false
+}
+
+func (f *FoodBag) priceSteps() int {
+	if f.PriceSteps < 2 {
+		return defaultPriceSteps
+	}
+	return f.PriceSteps
+}
+
+// MaxPickupWindow is the longest a pickup window may run.
+const MaxPickupWindow = 12 * time.Hour
+
+var (
+	ErrPickupWindowOrder  = errors.New("pickup_time_end must be after pickup_time_start")
+	ErrPickupWindowPast   = errors.New("pickup window must not start in the past")
+	ErrPickupWindowEnded  = errors.New("pickup window has already ended")
+	ErrPickupWindowLength = fmt.Errorf("pickup window must not be longer than %d hours", int(MaxPickupWindow.Hours()))
+)
+
+// NormalizePickupWindow checks a pickup window sent by a client and returns
+// it in UTC. The window may not start before earliestStart, may not have
+// ended by now and may not run longer than MaxPickupWindow.
+func NormalizePickupWindow(start, end, earliestStart, now time.Time) (time.Time, time.Time, error) {
+	start = start.UTC().Truncate(time.Second)
+	end = end.UTC().Truncate(time.Second)
+
+	switch {
+	case !end.After(start):
+		return start, end, ErrPickupWindowOrder
+	case start.Before(earliestStart):
+		return start, end, ErrPickupWindowPast
+	case !end.After(now):
+		return start, end, ErrPickupWindowEnded
+	case end.Sub(start) > MaxPickupWindow:
+		return start, end, ErrPickupWindowLength
+	}
+	return start, end, nil
+}
+
+// PickupWindow gives a pickup window both in UTC and in the store's local
+// time, so clients don't have to know the store's timezone rules.
+type PickupWindow struct {
+	Timezone   string    `json:"timezone" example:"Asia/Jakarta"`
+	StartUTC   time.Time `json:"start_utc" example:"2025-01-10T11:00:00Z"`
+	EndUTC     time.Time `json:"end_utc" example:"2025-01-10T13:00:00Z"`
+	StartLocal time.Time `json:"start_local" example:"2025-01-10T18:00:00+07:00"`
+	EndLocal   time.Time `json:"end_local" example:"2025-01-10T20:00:00+07:00"`
+}
+
+// NewPickupWindow gives the window from start to end in UTC and in loc.
+func NewPickupWindow(start, end time.Time, loc *time.Location) PickupWindow {
+	return PickupWindow{
+		Timezone:   loc.String(),
+		StartUTC:   start.UTC(),
+		EndUTC:     end.UTC(),
+		StartLocal: start.In(loc),
+		EndLocal:   end.In(loc),
+	}
+}
+
+// LocalPickupWindow returns the bag's pickup window in UTC and in the
+// timezone of store.
+func (f *FoodBag) LocalPickupWindow(store *Store) PickupWindow {
+	return NewPickupWindow(f.PickupTimeStart, f.PickupTimeEnd, store.Location())
+}
+
+// ToResponse builds the public view of the bag priced at now. Store is the
+// store's full response without a distance.
+func (f *FoodBag) ToResponse(now time.Time) FoodBagResponse {
+	currentPrice := f.PriceAt(now)
+	discountPercent := 0
+	if f.OriginalPrice > 0 {
+		discountPercent = int(((f.OriginalPrice - currentPrice) / f.OriginalPrice) * 100)
+	}
+
+	return FoodBagResponse{
+		ID:              f.ID,
+		Title:           f.Title,
+		Description:     f.Description,
+		OriginalPrice:   f.OriginalPrice,
+		DiscountedPrice: f.DiscountedPrice,
+		CurrentPrice:    currentPrice,
+		DiscountPercent: discountPercent,
+		QuantityLeft:    f.QuantityLeft,
+		PickupTimeStart: f.PickupTimeStart.UTC(),
+		PickupTimeEnd:   f.PickupTimeEnd.UTC(),
+		PickupWindow:    f.LocalPickupWindow(&f.Store),
+		ImageURL:        f.ImageURL,
+		Category:        f.Category,
+		Store:           f.Store.ToResponse(),
+		CreatedAt:       f.CreatedAt,
+		UpdatedAt:       f.UpdatedAt,
+		PricingStrategy: f.PricingStrategy,
+		PriceFloor:      f.PriceFloor,
+		NextPriceDropAt: f.NextPriceDropAt(now),
+	}
 }
 
 type FoodBagInput struct {
@@ -35,6 +232,11 @@ type FoodBagInput struct {
 	PickupTimeEnd   time.Time `json:"pickup_time_end" binding:"required"`
 	ImageURL        string    `json:"image_url"`
 	Category        string    `json:"category"`
+	// PricingStrategy defaults to fixed. Linear and step pricing need a
+	// PriceFloor below DiscountedPrice.
+	PricingStrategy PricingStrategy `json:"pricing_strategy" binding:"omitempty,oneof=fixed linear step"`
+	PriceFloor      float64         `json:"price_floor" binding:"gte=0"`
+	PriceSteps      int             `json:"price_steps" binding:"omitempty,min=2,max=24"`
 }
 
 type FoodBagSearchRequest struct {
@@ -44,6 +246,13 @@ type FoodBagSearchRequest struct {
 	Category  string  `json:"category,omitempty"`
 	MaxPrice  float64 `json:"max_price,omitempty"`
 	MinPrice  float64 `json:"min_price,omitempty"`
+	// Query matches bag titles, categories and descriptions, and the names
+	// of the stores selling them. Small typos in titles are tolerated.
+	Query string `json:"query,omitempty"`
+	// Sort is relevance, distance, price, discount_percent, pickup_time,
+	// rating or created_at. Relevance, which also weighs distance, is the
+	// default when there is a query.
+	PageRequest
 }
 
 type FoodBagResponse struct {
@@ -52,13 +261,24 @@ type FoodBagResponse struct {
 	Description     string        `json:"description"`
 	OriginalPrice   float64       `json:"original_price"`
 	DiscountedPrice float64       `json:"discounted_price"`
+	CurrentPrice    float64       `json:"current_price"`
 	DiscountPercent int           `json:"discount_percent"`
 	QuantityLeft    int           `json:"quantity_left"`
 	PickupTimeStart time.Time     `json:"pickup_time_start"`
 	PickupTimeEnd   time.Time     `json:"pickup_time_end"`
+	PickupWindow    PickupWindow  `json:"pickup_window"`
 	ImageURL        string        `json:"image_url"`
 	Category        string        `json:"category"`
 	Store           StoreResponse `json:"store"`
 	CreatedAt       time.Time     `json:"created_at"`
 	UpdatedAt       time.Time     `json:"updated_at"`
+
+	PricingStrategy PricingStrategy `json:"pricing_strategy"`
+	PriceFloor      float64         `json:"price_floor,omitempty"`
+	NextPriceDropAt *time.Time      `json:"next_price_drop_at,omitempty"`
+
+	// Highlights holds the title, category and description of a search
+	// result with the words matching the query marked up as <mark>. Only
+	// fields with a match are present.
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FoodBagTemplate holds everything about a food bag except when it can be
+// picked up, so the same bag can be listed again and again.
+type FoodBagTemplate struct {
+	gorm.Model
+	StoreID         uint            `json:"store_id" gorm:"index"`
+	Title           string          `json:"title"`
+	Description     string          `json:"description"`
+	OriginalPrice   float64         `json:"original_price"`
+	DiscountedPrice float64         `json:"discounted_price"`
+	QuantityTotal   int             `json:"quantity_total"`
+	ImageURL        string          `json:"image_url"`
+	Category        string          `json:"category"`
+	PricingStrategy PricingStrategy `json:"pricing_strategy" gorm:"default:'fixed'"`
+	PriceFloor      float64         `json:"price_floor"`
+	PriceSteps      int             `json:"price_steps"`
+}
+
+type FoodBagTemplateInput struct {
+	StoreID         uint            `json:"store_id" binding:"required"`
+	Title           string          `json:"title" binding:"required"`
+	Description     string          `json:"description"`
+	OriginalPrice   float64         `json:"original_price" binding:"required,gt=0"`
+	DiscountedPrice float64         `json:"discounted_price" binding:"required,gt=0"`
+	QuantityTotal   int             `json:"quantity_total" binding:"required,min=1"`
+	ImageURL        string          `json:"image_url"`
+	Category        string          `json:"category"`
+	PricingStrategy PricingStrategy `json:"pricing_strategy" binding:"omitempty,oneof=fixed linear step"`
+	PriceFloor      float64         `json:"price_floor" binding:"gte=0"`
+	PriceSteps      int             `json:"price_steps" binding:"omitempty,min=2,max=24"`
+}
+
+// FoodBagSchedule lists a template as a food bag on every matching day.
+// Weekdays is a comma-separated list such as "mon,tue,wed,thu,fri". Pickup
+// times are "HH:MM" in the store's timezone; an end at or before the start
+// runs past midnight. StartsOn and EndsOn are optional "YYYY-MM-DD" local
+// dates, both inclusive.
+type FoodBagSchedule struct {
+	gorm.Model
+	TemplateID  uint            `json:"template_id" gorm:"index"`
+	Template    FoodBagTemplate `json:"template" gorm:"foreignKey:TemplateID"`
+	StoreID     uint            `json:"store_id" gorm:"index"`
+	Store       Store           `json:"-" gorm:"foreignKey:StoreID"`
+	Weekdays    string          `json:"weekdays"`
+	PickupStart string          `json:"pickup_start"`
+	PickupEnd   string          `json:"pickup_end"`
+	StartsOn    string          `json:"starts_on,omitempty"`
+	EndsOn      string          `json:"ends_on,omitempty"`
+	IsPaused    bool            `json:"is_paused"`
+	// GeneratedThrough is the last local date food bags were created for.
+	GeneratedThrough string                `json:"generated_through,omitempty"`
+	Skips            []FoodBagScheduleSkip `json:"skips,omitempty" gorm:"foreignKey:ScheduleID"`
+}
+
+type FoodBagScheduleInput struct {
+	TemplateID  uint   `json:"template_id" binding:"required"`
+	Weekdays    string `json:"weekdays" binding:"required" example:"mon,tue,wed,thu,fri"`
+	PickupStart string `json:"pickup_start" binding:"required" example:"18:00"`
+	PickupEnd   string `json:"pickup_end" binding:"required" example:"20:00"`
+	StartsOn    string `json:"starts_on" example:"2025-01-06"`
+	EndsOn      string `json:"ends_on"`
+}
+
+// FoodBagScheduleSkip keeps a schedule from listing a bag on one local date.
+type FoodBagScheduleSkip struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at"`
+	ScheduleID uint      `json:"schedule_id" gorm:"uniqueIndex:idx_food_bag_schedule_skips_date"`
+	Date       string    `json:"date" gorm:"uniqueIndex:idx_food_bag_schedule_skips_date"`
+}
+
+type FoodBagScheduleSkipInput struct {
+	Date string `json:"date" binding:"required" example:"2025-01-08"`
+}
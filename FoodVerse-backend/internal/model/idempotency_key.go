@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// IdempotencyKey remembers the first response to a mutating request sent with
+// an Idempotency-Key header so that retries get the same response instead of
+// repeating the work. CompletedAt is nil while the first request is still
+// being handled.
+type IdempotencyKey struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	UserID       uint       `json:"user_id" gorm:"uniqueIndex:idx_idempotency_keys_user_key"`
+	Key          string     `json:"key" gorm:"size:255;uniqueIndex:idx_idempotency_keys_user_key"`
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	Fingerprint  string     `json:"fingerprint"`
+	StatusCode   int        `json:"status_code"`
+	ContentType  string     `json:"content_type"`
+	ResponseBody []byte     `json:"-"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"index"`
+}
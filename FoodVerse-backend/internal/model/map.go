@@ -0,0 +1,54 @@
+package model
+
+// MapRequest asks for the stores inside a map viewport. A viewport whose
+// west edge is east of its east edge crosses the antimeridian. Below the
+// clustering zoom level nearby stores are grouped into clusters.
+type MapRequest struct {
+	MinLatitude  float64 `form:"min_lat" binding:"min=-90,max=90"`
+	MinLongitude float64 `form:"min_lon" binding:"min=-180,max=180"`
+	MaxLatitude  float64 `form:"max_lat" binding:"min=-90,max=90"`
+	MaxLongitude float64 `form:"max_lon" binding:"min=-180,max=180"`
+	Zoom         *int    `form:"zoom" binding:"required,min=0,max=22"`
+	Category     string  `form:"category"`
+}
+
+// FeatureCollection is a GeoJSON feature collection of map features.
+// Truncated is set when the viewport held more stores than are returned
+// one by one; zooming in or clustering shows the rest.
+type FeatureCollection struct {
+	Type      string       `json:"type" example:"FeatureCollection"`
+	Features  []MapFeature `json:"features"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// MapFeature is a GeoJSON point feature for a store or a cluster of stores.
+// A cluster's BBox is [west, south, east, north] around its stores.
+type MapFeature struct {
+	Type       string        `json:"type" example:"Feature"`
+	ID         string        `json:"id" example:"store-1"`
+	BBox       []float64     `json:"bbox,omitempty"`
+	Geometry   GeoJSONPoint  `json:"geometry"`
+	Properties MapProperties `json:"properties"`
+}
+
+// GeoJSONPoint is a GeoJSON point. Coordinates are [longitude, latitude].
+type GeoJSONPoint struct {
+	Type        string     `json:"type" example:"Point"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// MapProperties describes a store, or a cluster when Cluster is set.
+// BagCount counts the bags on sale and MinPrice and MaxPrice range over
+// their current prices; both prices are absent when nothing is on sale.
+type MapProperties struct {
+	Cluster    bool     `json:"cluster"`
+	StoreCount int      `json:"store_count,omitempty" example:"12"`
+	StoreID    uint     `json:"store_id,omitempty" example:"1"`
+	Name       string   `json:"name,omitempty" example:"Green Grocery"`
+	Category   string   `json:"category,omitempty" example:"Grocery"`
+	ImageURL   string   `json:"image_url,omitempty"`
+	Rating     float32  `json:"rating,omitempty" example:"4.5"`
+	BagCount   int      `json:"bag_count" example:"3"`
+	MinPrice   *float64 `json:"min_price,omitempty" example:"4.50"`
+	MaxPrice   *float64 `json:"max_price,omitempty" example:"9.00"`
+}
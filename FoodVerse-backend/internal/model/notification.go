@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type NotificationType string
+
+const (
+	NotificationSavedSearchMatch NotificationType = "saved_search_match"
+)
+
+// Notification is a message for a user. A saved search match points at the
+// search and the bag that matched; a bag is announced once per search.
+type Notification struct {
+	ID            uint             `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"index"`
+	UserID        uint             `json:"user_id" gorm:"index"`
+	Type          NotificationType `json:"type"`
+	Title         string           `json:"title"`
+	Body          string           `json:"body"`
+	SavedSearchID *uint            `json:"saved_search_id,omitempty" gorm:"uniqueIndex:idx_notifications_saved_search_bag"`
+	FoodBagID     *uint            `json:"food_bag_id,omitempty" gorm:"uniqueIndex:idx_notifications_saved_search_bag"`
+	ReadAt        *time.Time       `json:"read_at,omitempty"`
+}
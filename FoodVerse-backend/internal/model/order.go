@@ -14,6 +14,25 @@ const (
 	OrderStatusReady     OrderStatus = "ready"
 	OrderStatusCompleted OrderStatus = "completed"
 	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusNoShow    OrderStatus = "no_show"
+)
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusConfirmed, OrderStatusReady, OrderStatusCompleted, OrderStatusCancelled, OrderStatusNoShow:
+		return true
+	}
+	return false
+}
+
+// OrderActor identifies who is driving a change to an order.
+type OrderActor string
+
+const (
+	OrderActorBuyer      OrderActor = "buyer"
+	OrderActorStoreOwner OrderActor = "store_owner"
+	OrderActorAdmin      OrderActor = "admin"
+	OrderActorSystem     OrderActor = "system"
 )
 
 type Order struct {
@@ -25,29 +44,181 @@ type Order struct {
 	StoreID    uint        `json:"store_id"`
 	Store      Store       `json:"store" gorm:"foreignKey:StoreID"`
 	Quantity   int         `json:"quantity" binding:"required"`
+	Items      []OrderItem `json:"items,omitempty" gorm:"foreignKey:OrderID"`
 	TotalPrice float64     `json:"total_price"`
 	Status     OrderStatus `json:"status" gorm:"default:'pending'"`
 	PickupCode string      `json:"pickup_code"`
 	Notes      string      `json:"notes"`
 	PickedUpAt *time.Time  `json:"picked_up_at,omitempty"`
+
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancelledByID      *uint      `json:"cancelled_by_id,omitempty"`
+	CancelledByActor   OrderActor `json:"cancelled_by_actor,omitempty"`
+	CancellationReason string     `json:"cancellation_reason,omitempty"`
+
+	// Subtotal is the sum of the items. TotalPrice is Subtotal less the
+	// promo discount.
+	Subtotal       float64 `json:"subtotal"`
+	DiscountAmount float64 `json:"discount_amount"`
+	PromotionID    *uint   `json:"promotion_id,omitempty"`
+	PromoCode      string  `json:"promo_code,omitempty"`
+
+	// WalletAmount is the part of TotalPrice paid from the buyer's wallet.
+	// The rest is paid through the payment provider.
+	WalletAmount float64   `json:"wallet_amount"`
+	Payments     []Payment `json:"payments,omitempty" gorm:"foreignKey:OrderID"`
+	Refunds      []Refund  `json:"refunds,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+// PickupTimes returns the span an order can be collected in: from the
+// earliest pickup start to the latest pickup end of its items' food bags.
+// A store's bags on one order may have different windows, and the buyer
+// collects them together. Items must be loaded with their food bags;
+// orders placed before items existed use FoodBag.
+func (o *Order) PickupTimes() (start, end time.Time) {
+	start, end = o.FoodBag.PickupTimeStart, o.FoodBag.PickupTimeEnd
+	found := o.FoodBag.ID != 0
+	for _, item := range o.Items {
+		if item.FoodBag.ID == 0 {
+			continue
+		}
+		if !found || item.FoodBag.PickupTimeStart.Before(start) {
+			start = item.FoodBag.PickupTimeStart
+		}
+		if !found || item.FoodBag.PickupTimeEnd.After(end) {
+			end = item.FoodBag.PickupTimeEnd
+		}
+		found = true
+	}
+	return start, end
 }
 
 type OrderInput struct {
 	FoodBagID uint   `json:"food_bag_id" binding:"required"`
-	Quantity  int    `json:"quantity" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
 	Notes     string `json:"notes"`
+	// WalletAmount is how much of the wallet balance to spend, at most the
+	// order total.
+	WalletAmount float64 `json:"wallet_amount" binding:"omitempty,gte=0"`
+	PromoCode    string  `json:"promo_code"`
+}
+
+type OrderCancelInput struct {
+	Reason string `json:"reason"`
 }
 
 type OrderResponse struct {
-	ID         uint            `json:"id"`
-	Quantity   int             `json:"quantity"`
-	TotalPrice float64         `json:"total_price"`
-	Status     OrderStatus     `json:"status"`
-	PickupCode string          `json:"pickup_code"`
-	Notes      string          `json:"notes"`
-	FoodBag    FoodBagResponse `json:"food_bag"`
-	Store      StoreResponse   `json:"store"`
-	CreatedAt  time.Time       `json:"created_at"`
-	UpdatedAt  time.Time       `json:"updated_at"`
-	PickedUpAt *time.Time      `json:"picked_up_at,omitempty"`
+	ID         uint                `json:"id"`
+	UserID     uint                `json:"user_id"`
+	User       *UserDTO            `json:"user,omitempty"`
+	FoodBagID  uint                `json:"food_bag_id"`
+	StoreID    uint                `json:"store_id"`
+	Quantity   int                 `json:"quantity"`
+	Items      []OrderItemResponse `json:"items,omitempty"`
+	TotalPrice float64             `json:"total_price"`
+	Status     OrderStatus         `json:"status"`
+	PickupCode string              `json:"pickup_code"`
+	Notes      string              `json:"notes"`
+	FoodBag    FoodBagResponse     `json:"food_bag"`
+	Store      StoreResponse       `json:"store"`
+	// PickupWindow spans the pickup windows of all the order's items, in
+	// UTC and in the store's local time.
+	PickupWindow PickupWindow `json:"pickup_window"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	PickedUpAt   *time.Time   `json:"picked_up_at,omitempty"`
+
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancelledByID      *uint      `json:"cancelled_by_id,omitempty"`
+	CancelledByActor   OrderActor `json:"cancelled_by_actor,omitempty"`
+	CancellationReason string     `json:"cancellation_reason,omitempty"`
+
+	Subtotal       float64   `json:"subtotal"`
+	DiscountAmount float64   `json:"discount_amount"`
+	PromotionID    *uint     `json:"promotion_id,omitempty"`
+	PromoCode      string    `json:"promo_code,omitempty"`
+	WalletAmount   float64   `json:"wallet_amount"`
+	Payments       []Payment `json:"payments,omitempty"`
+	Refunds        []Refund  `json:"refunds,omitempty"`
+}
+
+// OrderItemResponse is one line of an order with its own pickup window.
+type OrderItemResponse struct {
+	ID           uint         `json:"id"`
+	FoodBagID    uint         `json:"food_bag_id"`
+	Title        string       `json:"title"`
+	ImageURL     string       `json:"image_url"`
+	Quantity     int          `json:"quantity"`
+	UnitPrice    float64      `json:"unit_price"`
+	Subtotal     float64      `json:"subtotal"`
+	PickupWindow PickupWindow `json:"pickup_window"`
+}
+
+// ToResponse builds the public view of the order. FoodBag, Store and the
+// items' food bags must be loaded; User is included when it is.
+func (o *Order) ToResponse() OrderResponse {
+	foodBag := o.FoodBag
+	foodBag.Store = o.Store
+	start, end := o.PickupTimes()
+
+	var user *UserDTO
+	if o.User.ID != 0 {
+		dto := o.User.ToDTO()
+		user = &dto
+	}
+	var items []OrderItemResponse
+	for _, item := range o.Items {
+		items = append(items, OrderItemResponse{
+			ID:           item.ID,
+			FoodBagID:    item.FoodBagID,
+			Title:        item.FoodBag.Title,
+			ImageURL:     item.FoodBag.ImageURL,
+			Quantity:     item.Quantity,
+			UnitPrice:    item.UnitPrice,
+			Subtotal:     item.Subtotal,
+			PickupWindow: item.FoodBag.LocalPickupWindow(&o.Store),
+		})
+	}
+
+	return OrderResponse{
+		ID:           o.ID,
+		UserID:       o.UserID,
+		User:         user,
+		FoodBagID:    o.FoodBagID,
+		StoreID:      o.StoreID,
+		Quantity:     o.Quantity,
+		Items:        items,
+		TotalPrice:   o.TotalPrice,
+		Status:       o.Status,
+		PickupCode:   o.PickupCode,
+		Notes:        o.Notes,
+		FoodBag:      foodBag.ToResponse(o.CreatedAt),
+		Store:        o.Store.ToResponse(),
+		PickupWindow: NewPickupWindow(start, end, o.Store.Location()),
+		CreatedAt:    o.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+		PickedUpAt:   o.PickedUpAt,
+
+		CancelledAt:        o.CancelledAt,
+		CancelledByID:      o.CancelledByID,
+		CancelledByActor:   o.CancelledByActor,
+		CancellationReason: o.CancellationReason,
+
+		Subtotal:       o.Subtotal,
+		DiscountAmount: o.DiscountAmount,
+		PromotionID:    o.PromotionID,
+		PromoCode:      o.PromoCode,
+		WalletAmount:   o.WalletAmount,
+		Payments:       o.Payments,
+		Refunds:        o.Refunds,
+	}
+}
+
+// OrderResponses builds the public view of a list of orders.
+func OrderResponses(orders []Order) []OrderResponse {
+	responses := make([]OrderResponse, len(orders))
+	for i := range orders {
+		responses[i] = orders[i].ToResponse()
+	}
+	return responses
 }
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// OrderEventSource records which part of the system changed an order.
+type OrderEventSource string
+
+const (
+	OrderEventSourceAPI       OrderEventSource = "api"
+	OrderEventSourcePickup    OrderEventSource = "pickup_verification"
+	OrderEventSourceScheduler OrderEventSource = "scheduler"
+	OrderEventSourcePayment   OrderEventSource = "payment"
+)
+
+// OrderEvent is one entry in an order's status history.
+type OrderEvent struct {
+	gorm.Model
+	OrderID     uint             `json:"order_id" gorm:"index"`
+	FromStatus  OrderStatus      `json:"from_status"`
+	ToStatus    OrderStatus      `json:"to_status"`
+	ActorUserID *uint            `json:"actor_user_id,omitempty"`
+	Actor       OrderActor       `json:"actor"`
+	Source      OrderEventSource `json:"source"`
+	Note        string           `json:"note,omitempty"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// OrderItem is one food bag line on an order. The unit price is copied from
+// the food bag when the order is placed. Order.FoodBagID points at the first
+// item and Order.Quantity is the total across all items.
+type OrderItem struct {
+	gorm.Model
+	OrderID   uint    `json:"order_id" gorm:"index"`
+	FoodBagID uint    `json:"food_bag_id"`
+	FoodBag   FoodBag `json:"food_bag" gorm:"foreignKey:FoodBagID"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Subtotal  float64 `json:"subtotal"`
+}
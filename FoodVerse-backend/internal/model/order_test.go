@@ -0,0 +1,94 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderPickupTimes(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 1, 10, hour, 0, 0, 0, time.UTC) }
+	bag := func(id uint, start, end int) FoodBag {
+		foodBag := FoodBag{PickupTimeStart: at(start), PickupTimeEnd: at(end)}
+		foodBag.ID = id
+		return foodBag
+	}
+
+	tests := []struct {
+		name      string
+		order     Order
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "order without items",
+			order:     Order{FoodBag: bag(1, 18, 20)},
+			wantStart: at(18),
+			wantEnd:   at(20),
+		},
+		{
+			name: "items with different windows",
+			order: Order{FoodBag: bag(1, 18, 20), Items: []OrderItem{
+				{FoodBag: bag(1, 18, 20)},
+				{FoodBag: bag(2, 17, 19)},
+				{FoodBag: bag(3, 19, 22)},
+			}},
+			wantStart: at(17),
+			wantEnd:   at(22),
+		},
+		{
+			name:      "items loaded without the order's food bag",
+			order:     Order{Items: []OrderItem{{FoodBag: bag(2, 12, 14)}, {FoodBag: bag(3, 13, 15)}}},
+			wantStart: at(12),
+			wantEnd:   at(15),
+		},
+		{
+			name:      "items without their food bags",
+			order:     Order{FoodBag: bag(1, 18, 20), Items: []OrderItem{{FoodBagID: 2}}},
+			wantStart: at(18),
+			wantEnd:   at(20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.order.PickupTimes()
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("PickupTimes() = %v, %v, want %v, %v", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestOrderToResponsePickupWindow(t *testing.T) {
+	store := Store{Timezone: "Asia/Jakarta"}
+	store.ID = 7
+	bag := func(id uint, start, end time.Time) FoodBag {
+		foodBag := FoodBag{StoreID: store.ID, PickupTimeStart: start, PickupTimeEnd: end}
+		foodBag.ID = id
+		return foodBag
+	}
+	first := bag(1, time.Date(2025, 1, 10, 11, 0, 0, 0, time.UTC), time.Date(2025, 1, 10, 13, 0, 0, 0, time.UTC))
+	second := bag(2, time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC), time.Date(2025, 1, 10, 14, 0, 0, 0, time.UTC))
+	order := Order{
+		FoodBag: first,
+		Store:   store,
+		Items:   []OrderItem{{FoodBagID: 1, FoodBag: first}, {FoodBagID: 2, FoodBag: second}},
+	}
+
+	response := order.ToResponse()
+	window := response.PickupWindow
+	if window.Timezone != "Asia/Jakarta" {
+		t.Errorf("Timezone = %q, want Asia/Jakarta", window.Timezone)
+	}
+	if !window.StartUTC.Equal(first.PickupTimeStart) || !window.EndUTC.Equal(second.PickupTimeEnd) {
+		t.Errorf("window = %v to %v, want %v to %v", window.StartUTC, window.EndUTC, first.PickupTimeStart, second.PickupTimeEnd)
+	}
+	if got := window.StartLocal.Format("15:04 -07:00"); got != "18:00 +07:00" {
+		t.Errorf("StartLocal = %s, want 18:00 +07:00", got)
+	}
+	if len(response.Items) != 2 || !response.Items[1].PickupWindow.EndUTC.Equal(second.PickupTimeEnd) {
+		t.Errorf("items = %+v, want each with its own window", response.Items)
+	}
+	if response.User != nil {
+		t.Errorf("User = %+v, want nil when not loaded", response.User)
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+// PageRequest asks for one page of a list. Cursor is the next_cursor of the
+// previous page. Sort names one of the list's sort keys, prefixed with "-"
+// to reverse its usual direction; a cursor only continues the sort it was
+// issued for.
+type PageRequest struct {
+	Cursor string `form:"cursor" json:"cursor,omitempty"`
+	Limit  int    `form:"limit" json:"limit,omitempty"`
+	Sort   string `form:"sort" json:"sort,omitempty" example:"distance"`
+}
+
+// Page is one page of a list. NextCursor is empty on the last page. Total
+// counts the whole list and is left out where counting would be expensive.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending    PaymentStatus = "pending"
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	PaymentStatusCaptured   PaymentStatus = "captured"
+	PaymentStatusFailed     PaymentStatus = "failed"
+
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
+)
+
+// Payment is one attempt to pay for an order through a payment provider.
+// An order can have several failed attempts but at most one that is pending,
+// authorized, captured or (partially) refunded. RefundedAmount includes
+// refunds that are still being processed.
+type Payment struct {
+	gorm.Model
+	OrderID        uint          `json:"order_id" gorm:"index"`
+	Provider       string        `json:"provider"`
+	ProviderRef    string        `json:"provider_ref,omitempty" gorm:"index"`
+	PaymentMethod  string        `json:"payment_method"`
+	Amount         float64       `json:"amount"`
+	RefundedAmount float64       `json:"refunded_amount"`
+	Currency       string        `json:"currency"`
+	Status         PaymentStatus `json:"status" gorm:"default:'pending'"`
+	FailureReason  string        `json:"failure_reason,omitempty"`
+	AuthorizedAt   *time.Time    `json:"authorized_at,omitempty"`
+	CapturedAt     *time.Time    `json:"captured_at,omitempty"`
+}
+
+type PaymentInput struct {
+	PaymentMethod string `json:"payment_method" binding:"required"`
+}
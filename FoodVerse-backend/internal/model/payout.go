@@ -0,0 +1,87 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PayoutStatus string
+
+const (
+	PayoutStatusPending PayoutStatus = "pending"
+	PayoutStatusPaid    PayoutStatus = "paid"
+	PayoutStatusFailed  PayoutStatus = "failed"
+	// PayoutStatusCarriedForward marks a payout whose refunds outweighed its
+	// earnings. The negative net is taken off the store's next payout.
+	PayoutStatusCarriedForward PayoutStatus = "carried_forward"
+)
+
+// Payout is what a store earned in one payout period. Net is Gross less
+// store-funded discounts, the platform commission and refunds, plus any
+// negative balance carried over from earlier payouts.
+type Payout struct {
+	gorm.Model
+	StoreID     uint      `json:"store_id" gorm:"uniqueIndex:idx_payouts_store_period"`
+	Store       Store     `json:"store" gorm:"foreignKey:StoreID"`
+	PeriodStart time.Time `json:"period_start" gorm:"uniqueIndex:idx_payouts_store_period"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	CommissionRate float64 `json:"commission_rate"`
+	Gross          float64 `json:"gross"`
+	Discounts      float64 `json:"discounts"`
+	Commission     float64 `json:"commission"`
+	Refunds        float64 `json:"refunds"`
+	CarriedOver    float64 `json:"carried_over"`
+	Net            float64 `json:"net"`
+	Currency       string  `json:"currency"`
+
+	Status        PayoutStatus `json:"status" gorm:"default:'pending'"`
+	Provider      string       `json:"provider"`
+	ProviderRef   string       `json:"provider_ref,omitempty"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+	PaidAt        *time.Time   `json:"paid_at,omitempty"`
+	// CarriedIntoID is the payout a carried-forward balance was settled in.
+	CarriedIntoID *uint        `json:"carried_into_id,omitempty" gorm:"index"`
+	Lines         []PayoutLine `json:"lines,omitempty" gorm:"foreignKey:PayoutID"`
+}
+
+type PayoutLineType string
+
+const (
+	PayoutLineOrder  PayoutLineType = "order"
+	PayoutLineRefund PayoutLineType = "refund"
+)
+
+// PayoutLine is one completed order or one refund on a payout. An order line
+// has RefundID 0, so each order and each refund is settled exactly once.
+// Refund lines are negative and give back the commission on the refunded
+// amount.
+type PayoutLine struct {
+	gorm.Model
+	PayoutID   uint           `json:"payout_id" gorm:"index"`
+	Type       PayoutLineType `json:"type"`
+	OrderID    uint           `json:"order_id" gorm:"uniqueIndex:idx_payout_lines_source"`
+	RefundID   uint           `json:"refund_id,omitempty" gorm:"uniqueIndex:idx_payout_lines_source"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Gross      float64        `json:"gross"`
+	Discount   float64        `json:"discount"`
+	Commission float64        `json:"commission"`
+	Net        float64        `json:"net"`
+}
+
+// StoreEarnings is what a store has earned since its last payout and will be
+// settled at the end of the current period.
+type StoreEarnings struct {
+	StoreID        uint         `json:"store_id"`
+	CommissionRate float64      `json:"commission_rate"`
+	Gross          float64      `json:"gross"`
+	Discounts      float64      `json:"discounts"`
+	Commission     float64      `json:"commission"`
+	Refunds        float64      `json:"refunds"`
+	CarriedOver    float64      `json:"carried_over"`
+	Net            float64      `json:"net"`
+	Currency       string       `json:"currency"`
+	NextPayoutAt   time.Time    `json:"next_payout_at"`
+	Lines          []PayoutLine `json:"lines"`
+}
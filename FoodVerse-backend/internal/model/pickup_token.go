@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+type PickupTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PickupTokenPublicKeyResponse struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+type PickupTokenVerifyInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type PickupRedemptionInput struct {
+	Token      string    `json:"token" binding:"required"`
+	RedeemedAt time.Time `json:"redeemed_at" binding:"required"`
+}
+
+type PickupRedemptionSyncInput struct {
+	Redemptions []PickupRedemptionInput `json:"redemptions" binding:"required,dive"`
+}
+
+type PickupRedemptionResult struct {
+	Token   string `json:"token"`
+	OrderID uint   `json:"order_id,omitempty"`
+	Status  string `json:"status"` // completed, already_completed or rejected
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromotionDiscountType string
+
+const (
+	PromotionDiscountPercentage PromotionDiscountType = "percentage"
+	PromotionDiscountFixed      PromotionDiscountType = "fixed"
+)
+
+// Promotion is a promo code. A promotion scoped to a store or a category
+// only discounts matching order items. Zero limits mean unlimited.
+type Promotion struct {
+	gorm.Model
+	Code          string                `json:"code" gorm:"uniqueIndex"`
+	Description   string                `json:"description"`
+	DiscountType  PromotionDiscountType `json:"discount_type"`
+	DiscountValue float64               `json:"discount_value"`
+	// MaxDiscount caps a percentage discount.
+	MaxDiscount    float64 `json:"max_discount"`
+	MinOrderAmount float64 `json:"min_order_amount"`
+
+	StoreID  *uint  `json:"store_id,omitempty" gorm:"index"`
+	Store    *Store `json:"store,omitempty" gorm:"foreignKey:StoreID"`
+	Category string `json:"category,omitempty"`
+
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+
+	MaxRedemptions        int  `json:"max_redemptions"`
+	MaxRedemptionsPerUser int  `json:"max_redemptions_per_user"`
+	FirstOrderOnly        bool `json:"first_order_only"`
+	RedemptionCount       int  `json:"redemption_count"`
+
+	IsActive    bool `json:"is_active"`
+	CreatedByID uint `json:"created_by_id"`
+}
+
+type PromotionRedemptionStatus string
+
+const (
+	PromotionRedemptionApplied  PromotionRedemptionStatus = "applied"
+	PromotionRedemptionReversed PromotionRedemptionStatus = "reversed"
+)
+
+// PromotionRedemption records a promo code used on an order. It is reversed
+// when the order is cancelled, which frees the use up again.
+type PromotionRedemption struct {
+	gorm.Model
+	PromotionID    uint                      `json:"promotion_id" gorm:"index"`
+	UserID         uint                      `json:"user_id" gorm:"index"`
+	OrderID        uint                      `json:"order_id" gorm:"index"`
+	DiscountAmount float64                   `json:"discount_amount"`
+	Status         PromotionRedemptionStatus `json:"status"`
+}
+
+type PromotionInput struct {
+	Code                  string                `json:"code" binding:"required"`
+	Description           string                `json:"description"`
+	DiscountType          PromotionDiscountType `json:"discount_type" binding:"required,oneof=percentage fixed"`
+	DiscountValue         float64               `json:"discount_value" binding:"required,gt=0"`
+	MaxDiscount           float64               `json:"max_discount" binding:"gte=0"`
+	MinOrderAmount        float64               `json:"min_order_amount" binding:"gte=0"`
+	StoreID               *uint                 `json:"store_id"`
+	Category              string                `json:"category"`
+	StartsAt              *time.Time            `json:"starts_at"`
+	EndsAt                *time.Time            `json:"ends_at"`
+	MaxRedemptions        int                   `json:"max_redemptions" binding:"gte=0"`
+	MaxRedemptionsPerUser int                   `json:"max_redemptions_per_user" binding:"gte=0"`
+	FirstOrderOnly        bool                  `json:"first_order_only"`
+	IsActive              *bool                 `json:"is_active"`
+}
+
+type PromotionValidateItem struct {
+	FoodBagID uint `json:"food_bag_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
+// PromotionValidateInput checks a code against the given items, or against
+// the user's cart when Items is empty.
+type PromotionValidateInput struct {
+	Code  string                  `json:"code" binding:"required"`
+	Items []PromotionValidateItem `json:"items" binding:"dive"`
+}
+
+// PromotionQuote is the price a code would give. When Valid is false Reason
+// says why and the totals are undiscounted.
+type PromotionQuote struct {
+	Code           string  `json:"code"`
+	Valid          bool    `json:"valid"`
+	Reason         string  `json:"reason,omitempty"`
+	Message        string  `json:"message,omitempty"`
+	Description    string  `json:"description,omitempty"`
+	Subtotal       float64 `json:"subtotal"`
+	DiscountAmount float64 `json:"discount_amount"`
+	Total          float64 `json:"total"`
+	StoreID        uint    `json:"store_id,omitempty"`
+}
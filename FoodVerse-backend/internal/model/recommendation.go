@@ -0,0 +1,19 @@
+package model
+
+// RecommendationRequest asks for the bags near a point that best suit the
+// signed-in user.
+type RecommendationRequest struct {
+	Latitude  float64 `form:"latitude" binding:"required"`
+	Longitude float64 `form:"longitude" binding:"required"`
+	Radius    float64 `form:"radius"` // in kilometers, default 5km
+	Limit     int     `form:"limit"`
+}
+
+// RecommendedFoodBag is a food bag with its recommendation score, from 0
+// to 1, and the reasons it was picked, such as "favorite_category" or
+// "popular_nearby".
+type RecommendedFoodBag struct {
+	FoodBagResponse
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+type RefundDestination string
+
+const (
+	RefundToOriginalPayment RefundDestination = "original_payment"
+	RefundToWallet          RefundDestination = "wallet"
+)
+
+// Refund gives back all or part of a payment. RequestedByActor records who
+// triggered it; RequestedByID is empty for refunds the system issued on its
+// own. A pending refund to the original payment is an outbox entry: it is
+// recorded first and sent to the payment provider afterwards, retrying
+// until the provider answers.
+type Refund struct {
+	gorm.Model
+	OrderID          uint              `json:"order_id" gorm:"index"`
+	PaymentID        uint              `json:"payment_id" gorm:"index"`
+	Amount           float64           `json:"amount"`
+	Status           RefundStatus      `json:"status" gorm:"default:'pending'"`
+	Destination      RefundDestination `json:"destination" gorm:"default:'original_payment'"`
+	ProviderRef      string            `json:"provider_ref,omitempty"`
+	RequestedByID    *uint             `json:"requested_by_id,omitempty"`
+	RequestedByActor OrderActor        `json:"requested_by_actor"`
+	Reason           string            `json:"reason"`
+	FailureReason    string            `json:"failure_reason,omitempty"`
+	RefundedAt       *time.Time        `json:"refunded_at,omitempty"`
+
+	// Attempts counts how often the refund was sent to the payment
+	// provider. A pending refund is due to be sent again at NextAttemptAt,
+	// which is pushed out while an attempt is in flight so that only one
+	// sender handles it.
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" gorm:"index"`
+}
+
+// RefundInput requests a refund. Without an amount whatever is left on the
+// payment is refunded. ToWallet pays it out as store credit.
+type RefundInput struct {
+	Amount   float64 `json:"amount" binding:"omitempty,gt=0"`
+	Reason   string  `json:"reason" binding:"required"`
+	ToWallet bool    `json:"to_wallet"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedSearch is a food bag search a user asked to be alerted about. Bags
+// that go on sale matching it produce notifications, at most one per
+// throttle period. The bounds are the box around the search circle, kept
+// so a new bag is matched against an index rather than every saved search.
+type SavedSearch struct {
+	gorm.Model
+	UserID        uint    `json:"user_id" gorm:"index"`
+	Name          string  `json:"name"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Radius        float64 `json:"radius"` // in kilometers
+	Category      string  `json:"category,omitempty"`
+	MinPrice      float64 `json:"min_price,omitempty"`
+	MaxPrice      float64 `json:"max_price,omitempty"`
+	Query         string  `json:"query,omitempty"`
+	AlertsEnabled bool    `json:"alerts_enabled"`
+	// LastNotifiedAt is when the search last produced a notification.
+	LastNotifiedAt *time.Time `json:"last_notified_at,omitempty"`
+
+	// TSQuery is Query prepared for full-text matching.
+	TSQuery      string  `json:"-"`
+	MinLatitude  float64 `json:"-" gorm:"index:idx_saved_searches_bounds"`
+	MaxLatitude  float64 `json:"-" gorm:"index:idx_saved_searches_bounds"`
+	MinLongitude float64 `json:"-" gorm:"index:idx_saved_searches_bounds"`
+	MaxLongitude float64 `json:"-" gorm:"index:idx_saved_searches_bounds"`
+}
+
+// SavedSearchInput names a food bag search, with the same criteria as a
+// FoodBagSearchRequest, to be alerted about. AlertsEnabled defaults to true.
+type SavedSearchInput struct {
+	Name          string  `json:"name" binding:"required,max=100" example:"Cheap bakery bags"`
+	Latitude      float64 `json:"latitude" binding:"required,min=-90,max=90" example:"40.7128"`
+	Longitude     float64 `json:"longitude" binding:"required,min=-180,max=180" example:"-74.0060"`
+	Radius        float64 `json:"radius" binding:"gte=0,lte=50" example:"2"` // in kilometers, default 5km
+	Category      string  `json:"category,omitempty" example:"Bakery"`
+	MinPrice      float64 `json:"min_price,omitempty" binding:"gte=0"`
+	MaxPrice      float64 `json:"max_price,omitempty" binding:"gte=0" example:"5.00"`
+	Query         string  `json:"query,omitempty" binding:"max=200" example:"croissant"`
+	AlertsEnabled *bool   `json:"alerts_enabled,omitempty" example:"true"`
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+type SuggestionType string
+
+const (
+	SuggestionTypeStore    SuggestionType = "store"
+	SuggestionTypeBag      SuggestionType = "bag"
+	SuggestionTypeCategory SuggestionType = "category"
+)
+
+// SearchSuggestion is a store or food bag in the as-you-type prefix index.
+// Term and CategoryTerm are the lowercased name or title and category,
+// matched by prefix. Rows copy the store's location so suggestions can be
+// scoped without joins, and are rewritten whenever the store or bag is
+// saved. Popularity counts recent orders and is refreshed by a job.
+type SearchSuggestion struct {
+	ID           uint           `json:"id" gorm:"primarykey"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Type         SuggestionType `json:"type" gorm:"uniqueIndex:idx_search_suggestions_ref"`
+	RefID        uint           `json:"ref_id" gorm:"uniqueIndex:idx_search_suggestions_ref"`
+	StoreID      uint           `json:"store_id" gorm:"index"`
+	Label        string         `json:"label"`
+	Term         string         `json:"term"`
+	Category     string         `json:"category"`
+	CategoryTerm string         `json:"category_term"`
+	Latitude     float64        `json:"latitude"`
+	Longitude    float64        `json:"longitude"`
+	// IsAvailable is false for inactive stores and for bags that are
+	// inactive, sold out or at an inactive store. AvailableUntil is a
+	// bag's pickup end.
+	IsAvailable    bool       `json:"is_available"`
+	AvailableUntil *time.Time `json:"available_until,omitempty"`
+	Popularity     int64      `json:"popularity"`
+}
+
+// SuggestRequest asks for suggestions for what a user has typed so far near
+// a point.
+type SuggestRequest struct {
+	Query     string  `form:"q" binding:"required"`
+	Latitude  float64 `form:"latitude" binding:"required"`
+	Longitude float64 `form:"longitude" binding:"required"`
+	Radius    float64 `form:"radius"` // in kilometers, default 5km
+	Limit     int     `form:"limit"`
+}
+
+// SuggestionResponse is one suggestion. A store or bag suggestion carries
+// its ID and the distance to its store; a category suggestion carries
+// neither. Popularity is the number of recent orders behind it.
+type SuggestionResponse struct {
+	Type       SuggestionType `json:"type"`
+	Text       string         `json:"text"`
+	ID         uint           `json:"id,omitempty"`
+	StoreID    uint           `json:"store_id,omitempty"`
+	Distance   float64        `json:"distance,omitempty"` // in kilometers
+	Popularity int64          `json:"popularity"`
+}
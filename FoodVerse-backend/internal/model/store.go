@@ -22,6 +22,25 @@ type Store struct {
 	OwnerID     uint      `json:"owner_id"`
 	Owner       User      `json:"owner" gorm:"foreignKey:OwnerID"`
 	FoodBags    []FoodBag `json:"food_bags,omitempty"`
+	// Timezone is the IANA zone the store's local times are in.
+	Timezone string `json:"timezone" gorm:"default:'UTC'"`
+	// OpeningHours is the weekly schedule; a store without any is always
+	// open. HoursExceptions override it on single dates.
+	OpeningHours    []StoreOpeningHours   `json:"opening_hours,omitempty" gorm:"foreignKey:StoreID"`
+	HoursExceptions []StoreHoursException `json:"hours_exceptions,omitempty" gorm:"foreignKey:StoreID"`
+}
+
+// Location returns the store's timezone, falling back to UTC when it is
+// unset or unknown.
+func (s *Store) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 type StoreInput struct {
@@ -34,6 +53,10 @@ type StoreInput struct {
 	Email       string  `json:"email"`
 	Category    string  `json:"category" binding:"required"`
 	ImageURL    string  `json:"image_url"`
+	Timezone    string  `json:"timezone" example:"Europe/Amsterdam"`
+	// OpeningHours replaces the weekly schedule when present; an empty list
+	// clears it.
+	OpeningHours []StoreOpeningHoursInput `json:"opening_hours"`
 }
 
 type StoreSearchRequest struct {
@@ -41,7 +64,13 @@ type StoreSearchRequest struct {
 	Longitude float64 `json:"longitude" binding:"required"`
 	Radius    float64 `json:"radius"` // in kilometers, default 5km
 	Category  string  `json:"category,omitempty"`
-	Query     string  `json:"query,omitempty"`
+	// Query matches store names, categories and descriptions, and the
+	// stores selling matching bags. Small typos in names are tolerated.
+	Query   string `json:"query,omitempty"`
+	OpenNow bool   `json:"open_now,omitempty"`
+	// Sort is relevance, distance or rating. Relevance, which also weighs
+	// distance, is the default when there is a query.
+	PageRequest
 }
 
 type StoreResponse struct {
@@ -58,8 +87,17 @@ type StoreResponse struct {
 	Rating      float32   `json:"rating"`
 	IsActive    bool      `json:"is_active"`
 	Distance    float64   `json:"distance"` // in kilometers
+	Timezone    string    `json:"timezone"`
+	IsOpenNow   bool      `json:"is_open_now"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	OpeningHours    []StoreOpeningHours   `json:"opening_hours,omitempty"`
+	HoursExceptions []StoreHoursException `json:"hours_exceptions,omitempty"`
+	// Highlights holds the name, category and description of a search
+	// result with the words matching the query marked up as <mark>. Only
+	// fields with a match are present.
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
 
 func (s *Store) ToResponse() StoreResponse {
@@ -77,7 +115,12 @@ func (s *Store) ToResponse() StoreResponse {
 		Rating:      s.Rating,
 		IsActive:    s.IsActive,
 		Distance:    0, // Will be calculated in search
+		Timezone:    s.Timezone,
+		IsOpenNow:   s.IsOpenAt(time.Now()),
 		CreatedAt:   s.CreatedAt,
 		UpdatedAt:   s.UpdatedAt,
+
+		OpeningHours:    s.OpeningHours,
+		HoursExceptions: s.HoursExceptions,
 	}
 }
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// clockLayout is the "HH:MM" layout of opening times.
+	clockLayout = "15:04"
+	// localDateLayout is the layout of dates in the store's timezone.
+	localDateLayout = "2006-01-02"
+)
+
+// StoreOpeningHours is one weekly opening interval. Weekday is 0 (Sunday)
+// through 6 (Saturday). Opens and Closes are "HH:MM" in the store's timezone;
+// a close at or before the open runs past midnight. A day may have several
+// intervals, e.g. a lunch break.
+type StoreOpeningHours struct {
+	ID      uint   `json:"id" gorm:"primarykey"`
+	StoreID uint   `json:"store_id" gorm:"index"`
+	Weekday int    `json:"weekday"`
+	Opens   string `json:"opens"`
+	Closes  string `json:"closes"`
+}
+
+type StoreOpeningHoursInput struct {
+	Weekday int    `json:"weekday" binding:"min=0,max=6" example:"1"`
+	Opens   string `json:"opens" binding:"required" example:"08:00"`
+	Closes  string `json:"closes" binding:"required" example:"20:00"`
+}
+
+// StoreHoursException overrides the weekly hours on one local date, for
+// holidays or special hours. A closed exception closes the store all day;
+// otherwise Opens and Closes replace that day's weekly hours.
+type StoreHoursException struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	StoreID   uint      `json:"store_id" gorm:"uniqueIndex:idx_store_hours_exceptions_date"`
+	Date      string    `json:"date" gorm:"uniqueIndex:idx_store_hours_exceptions_date"`
+	IsClosed  bool      `json:"is_closed"`
+	Opens     string    `json:"opens,omitempty"`
+	Closes    string    `json:"closes,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+type StoreHoursExceptionInput struct {
+	Date     string `json:"date" binding:"required" example:"2025-12-25"`
+	IsClosed bool   `json:"is_closed" example:"true"`
+	Opens    string `json:"opens" example:"10:00"`
+	Closes   string `json:"closes" example:"14:00"`
+	Reason   string `json:"reason" example:"Christmas"`
+}
+
+// openInterval is a span of time during which a store is open.
+type openInterval struct {
+	start, end time.Time
+}
+
+// HasOpeningHours reports whether the store has set any weekly hours.
+// Stores without hours are treated as always open.
+func (s *Store) HasOpeningHours() bool {
+	return len(s.OpeningHours) > 0
+}
+
+// IsOpenAt reports whether the store is open at t. Inactive stores are
+// never open.
+func (s *Store) IsOpenAt(t time.Time) bool {
+	return s.IsActive && s.CoversWindow(t, t)
+}
+
+// CoversWindow reports whether the store is open for the whole of
+// [start, end]. Back-to-back intervals, such as hours running up to and from
+// midnight, count as one.
+func (s *Store) CoversWindow(start, end time.Time) bool {
+	if !s.HasOpeningHours() {
+		return true
+	}
+
+	loc := s.Location()
+	localStart := start.In(loc)
+	// Intervals from the day before can run past midnight into start.
+	first := time.Date(localStart.Year(), localStart.Month(), localStart.Day()-1, 0, 0, 0, 0, loc)
+	intervals := s.openIntervals(first, end.In(loc))
+
+	for _, interval := range intervals {
+		if !interval.start.After(start) && !interval.end.Before(end) && interval.end.After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// openIntervals returns the store's merged open intervals for the local days
+// from first through the day of last.
+func (s *Store) openIntervals(first, last time.Time) []openInterval {
+	exceptions := make(map[string]StoreHoursException, len(s.HoursExceptions))
+	for _, exception := range s.HoursExceptions {
+		exceptions[exception.Date] = exception
+	}
+
+	var intervals []openInterval
+	lastDate := last.Format(localDateLayout)
+	for day := first; day.Format(localDateLayout) <= lastDate; day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location()) {
+		if exception, ok := exceptions[day.Format(localDateLayout)]; ok {
+			if !exception.IsClosed {
+				if interval, ok := clockInterval(day, exception.Opens, exception.Closes); ok {
+					intervals = append(intervals, interval)
+				}
+			}
+			continue
+		}
+
+		for _, hours := range s.OpeningHours {
+			if hours.Weekday != int(day.Weekday()) {
+				continue
+			}
+			if interval, ok := clockInterval(day, hours.Opens, hours.Closes); ok {
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	var merged []openInterval
+	for _, interval := range intervals {
+		if n := len(merged); n > 0 && !interval.start.After(merged[n-1].end) {
+			if interval.end.After(merged[n-1].end) {
+				merged[n-1].end = interval.end
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// clockInterval places "HH:MM" opening times on a local day. A close at or
+// before the open falls on the next day.
+func clockInterval(day time.Time, opens, closes string) (openInterval, bool) {
+	openAt, err := time.Parse(clockLayout, opens)
+	if err != nil {
+		return openInterval{}, false
+	}
+	closeAt, err := time.Parse(clockLayout, closes)
+	if err != nil {
+		return openInterval{}, false
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), openAt.Hour(), openAt.Minute(), 0, 0, day.Location())
+	endDay := day.Day()
+	if !closeAt.After(openAt) {
+		endDay++
+	}
+	end := time.Date(day.Year(), day.Month(), endDay, closeAt.Hour(), closeAt.Minute(), 0, 0, day.Location())
+	return openInterval{start: start, end: end}, true
+}
+
+// ValidClock reports whether value is an "HH:MM" time of day.
+func ValidClock(value string) bool {
+	_, err := time.Parse(clockLayout, value)
+	return err == nil
+}
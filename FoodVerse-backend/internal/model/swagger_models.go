@@ -20,6 +20,10 @@ type SwaggerFoodBag struct {
 	ImageURL        string       `json:"image_url" example:"https://example.com/image.jpg"`
 	Category        string       `json:"category" example:"Mixed"`
 	IsActive        bool         `json:"is_active" example:"true"`
+	PricingStrategy string       `json:"pricing_strategy" example:"linear"`
+	PriceFloor      float64      `json:"price_floor" example:"6.00"`
+	PriceSteps      int          `json:"price_steps" example:"0"`
+	CurrentPrice    float64      `json:"current_price" example:"8.50"`
 }
 
 // SwaggerStore represents a store for Swagger documentation
@@ -42,21 +46,46 @@ type SwaggerStore struct {
 
 // SwaggerOrder represents an order for Swagger documentation
 type SwaggerOrder struct {
-	ID         uint           `json:"id" example:"1"`
-	CreatedAt  time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt  time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
-	UserID     uint           `json:"user_id" example:"1"`
-	User       SwaggerUser    `json:"user"`
-	FoodBagID  uint           `json:"food_bag_id" example:"1"`
-	FoodBag    SwaggerFoodBag `json:"food_bag"`
-	StoreID    uint           `json:"store_id" example:"1"`
-	Store      SwaggerStore   `json:"store"`
-	Quantity   int            `json:"quantity" example:"2"`
-	TotalPrice float64        `json:"total_price" example:"20.00"`
-	Status     string         `json:"status" example:"pending"`
-	PickupCode string         `json:"pickup_code" example:"ABC123"`
-	OrderDate  time.Time      `json:"order_date" example:"2023-01-01T12:00:00Z"`
-	PickedUpAt *time.Time     `json:"picked_up_at,omitempty" example:"2023-01-01T19:00:00Z"`
+	ID                 uint                   `json:"id" example:"1"`
+	UserID             uint                   `json:"user_id" example:"1"`
+	User               *SwaggerUser           `json:"user,omitempty"`
+	FoodBagID          uint                   `json:"food_bag_id" example:"1"`
+	StoreID            uint                   `json:"store_id" example:"1"`
+	Quantity           int                    `json:"quantity" example:"2"`
+	Items              []SwaggerOrderItem     `json:"items,omitempty"`
+	TotalPrice         float64                `json:"total_price" example:"20.00"`
+	Status             string                 `json:"status" example:"pending"`
+	PickupCode         string                 `json:"pickup_code" example:"ABC123"`
+	Notes              string                 `json:"notes" example:"Please add a paper bag"`
+	FoodBag            SwaggerFoodBagResponse `json:"food_bag"`
+	Store              SwaggerStoreResponse   `json:"store"`
+	PickupWindow       PickupWindow           `json:"pickup_window"`
+	CreatedAt          time.Time              `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt          time.Time              `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	PickedUpAt         *time.Time             `json:"picked_up_at,omitempty" example:"2023-01-01T19:00:00Z"`
+	CancelledAt        *time.Time             `json:"cancelled_at,omitempty" example:"2023-01-01T17:00:00Z"`
+	CancelledByID      *uint                  `json:"cancelled_by_id,omitempty" example:"1"`
+	CancelledByActor   string                 `json:"cancelled_by_actor,omitempty" example:"buyer"`
+	CancellationReason string                 `json:"cancellation_reason,omitempty" example:"Plans changed"`
+	Subtotal           float64                `json:"subtotal" example:"22.00"`
+	DiscountAmount     float64                `json:"discount_amount" example:"2.00"`
+	PromotionID        *uint                  `json:"promotion_id,omitempty" example:"1"`
+	PromoCode          string                 `json:"promo_code,omitempty" example:"WELCOME10"`
+	WalletAmount       float64                `json:"wallet_amount" example:"5.00"`
+	Payments           []Payment              `json:"payments,omitempty"`
+	Refunds            []Refund               `json:"refunds,omitempty"`
+}
+
+// SwaggerOrderItem represents an order line for Swagger documentation
+type SwaggerOrderItem struct {
+	ID           uint         `json:"id" example:"1"`
+	FoodBagID    uint         `json:"food_bag_id" example:"1"`
+	Title        string       `json:"title" example:"Mixed Food Bag"`
+	ImageURL     string       `json:"image_url" example:"https://example.com/image.jpg"`
+	Quantity     int          `json:"quantity" example:"2"`
+	UnitPrice    float64      `json:"unit_price" example:"11.00"`
+	Subtotal     float64      `json:"subtotal" example:"22.00"`
+	PickupWindow PickupWindow `json:"pickup_window"`
 }
 
 // SwaggerUser represents a user for Swagger documentation
@@ -74,6 +103,7 @@ type SwaggerUser struct {
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error" example:"Something went wrong"`
+	Code  string `json:"code,omitempty" example:"invalid_transition"`
 }
 
 // SwaggerFoodBagSearchRequest represents a food bag search request for Swagger
@@ -84,6 +114,10 @@ type SwaggerFoodBagSearchRequest struct {
 	Category  string  `json:"category,omitempty" example:"Mixed"`
 	MaxPrice  float64 `json:"max_price,omitempty" example:"20.00"`
 	MinPrice  float64 `json:"min_price,omitempty" example:"5.00"`
+	Query     string  `json:"query,omitempty" example:"croissant"`
+	Cursor    string  `json:"cursor,omitempty"`
+	Limit     int     `json:"limit,omitempty" example:"20"`
+	Sort      string  `json:"sort,omitempty" example:"price"`
 }
 
 // SwaggerFoodBagResponse represents a food bag response for Swagger
@@ -93,15 +127,21 @@ type SwaggerFoodBagResponse struct {
 	Description     string               `json:"description" example:"Assorted fresh items"`
 	OriginalPrice   float64              `json:"original_price" example:"25.00"`
 	DiscountedPrice float64              `json:"discounted_price" example:"10.00"`
-	DiscountPercent int                  `json:"discount_percent" example:"60"`
+	CurrentPrice    float64              `json:"current_price" example:"8.50"`
+	DiscountPercent int                  `json:"discount_percent" example:"66"`
 	QuantityLeft    int                  `json:"quantity_left" example:"3"`
 	PickupTimeStart time.Time            `json:"pickup_time_start" example:"2023-01-01T18:00:00Z"`
 	PickupTimeEnd   time.Time            `json:"pickup_time_end" example:"2023-01-01T21:00:00Z"`
+	PickupWindow    PickupWindow         `json:"pickup_window"`
 	ImageURL        string               `json:"image_url" example:"https://example.com/image.jpg"`
 	Category        string               `json:"category" example:"Mixed"`
 	Store           SwaggerStoreResponse `json:"store"`
 	CreatedAt       time.Time            `json:"created_at" example:"2023-01-01T00:00:00Z"`
 	UpdatedAt       time.Time            `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	PricingStrategy string               `json:"pricing_strategy" example:"step"`
+	PriceFloor      float64              `json:"price_floor,omitempty" example:"6.00"`
+	NextPriceDropAt *time.Time           `json:"next_price_drop_at,omitempty" example:"2023-01-01T19:30:00Z"`
+	Highlights      map[string]string    `json:"highlights,omitempty"`
 }
 
 // SwaggerStoreSearchRequest represents a store search request for Swagger
@@ -111,6 +151,10 @@ type SwaggerStoreSearchRequest struct {
 	Radius    float64 `json:"radius" example:"5.0"`
 	Category  string  `json:"category,omitempty" example:"Grocery"`
 	Query     string  `json:"query,omitempty" example:"Green"`
+	OpenNow   bool    `json:"open_now,omitempty" example:"true"`
+	Cursor    string  `json:"cursor,omitempty"`
+	Limit     int     `json:"limit,omitempty" example:"20"`
+	Sort      string  `json:"sort,omitempty" example:"rating"`
 }
 
 // SwaggerStoreResponse represents a store response for Swagger
@@ -127,16 +171,20 @@ type SwaggerStoreResponse struct {
 	ImageURL    string    `json:"image_url" example:"https://example.com/store.jpg"`
 	Rating      float32   `json:"rating" example:"4.5"`
 	IsActive    bool      `json:"is_active" example:"true"`
+	IsOpenNow   bool      `json:"is_open_now" example:"true"`
 	CreatedAt   time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
 	UpdatedAt   time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
 
 // SwaggerOrderStatusUpdate represents order status update request for Swagger
 type SwaggerOrderStatusUpdate struct {
-	Status string `json:"status" binding:"required" example:"completed" enums:"pending,preparing,ready,completed,cancelled"`
+	Status string `json:"status" binding:"required" example:"ready" enums:"ready,cancelled"`
 }
 
 // SwaggerPickupCodeRequest represents pickup code verification request for Swagger
 type SwaggerPickupCodeRequest struct {
+	StoreID    uint   `json:"store_id" binding:"required" example:"1"`
 	PickupCode string `json:"pickup_code" binding:"required" example:"ABC123"`
 }
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"time"
+)
+
+// Ledger amounts are stored in minor currency units (cents). Each ledger
+// transaction has entries that add up to zero, so money only ever moves
+// between accounts.
+
+type LedgerAccountType string
+
+const (
+	LedgerAccountWallet   LedgerAccountType = "wallet"
+	LedgerAccountPlatform LedgerAccountType = "platform"
+)
+
+// Codes of the platform accounts money moves to and from.
+const (
+	LedgerAccountSales       = "platform:sales"
+	LedgerAccountPromotions  = "platform:promotions"
+	LedgerAccountAdjustments = "platform:adjustments"
+)
+
+// LedgerAccount holds a cached balance that always equals the sum of its
+// entries. HeldAmount is the part of a wallet's balance set aside for orders
+// that are not paid yet. Only platform accounts may go negative.
+type LedgerAccount struct {
+	ID            uint              `json:"id" gorm:"primarykey"`
+	Code          string            `json:"code" gorm:"uniqueIndex"`
+	Type          LedgerAccountType `json:"type"`
+	UserID        *uint             `json:"user_id,omitempty" gorm:"uniqueIndex"`
+	Currency      string            `json:"currency"`
+	Balance       int64             `json:"balance_cents" gorm:"check:chk_ledger_accounts_non_negative,allow_negative OR (balance >= 0 AND held_amount >= 0 AND held_amount <= balance)"`
+	HeldAmount    int64             `json:"held_amount_cents"`
+	AllowNegative bool              `json:"allow_negative"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+type LedgerTransactionType string
+
+const (
+	LedgerTransactionOrderPayment LedgerTransactionType = "order_payment"
+	LedgerTransactionOrderRefund  LedgerTransactionType = "order_refund"
+	LedgerTransactionRefund       LedgerTransactionType = "refund"
+	LedgerTransactionPromotion    LedgerTransactionType = "promotion"
+	LedgerTransactionAdjustment   LedgerTransactionType = "adjustment"
+)
+
+// LedgerTransaction groups the entries of one balance change and records
+// what caused it.
+type LedgerTransaction struct {
+	ID          uint                  `json:"id" gorm:"primarykey"`
+	Type        LedgerTransactionType `json:"type"`
+	OrderID     *uint                 `json:"order_id,omitempty" gorm:"index"`
+	RefundID    *uint                 `json:"refund_id,omitempty" gorm:"index"`
+	PromotionID *uint                 `json:"promotion_id,omitempty" gorm:"index"`
+	AdminID     *uint                 `json:"admin_id,omitempty"`
+	Description string                `json:"description"`
+	Entries     []LedgerEntry         `json:"entries,omitempty" gorm:"foreignKey:TransactionID"`
+	CreatedAt   time.Time             `json:"created_at"`
+}
+
+// LedgerEntry moves Amount into (positive) or out of (negative) an account.
+type LedgerEntry struct {
+	ID            uint               `json:"id" gorm:"primarykey"`
+	TransactionID uint               `json:"transaction_id" gorm:"index"`
+	Transaction   *LedgerTransaction `json:"transaction,omitempty" gorm:"foreignKey:TransactionID"`
+	AccountID     uint               `json:"account_id" gorm:"index"`
+	Amount        int64              `json:"amount_cents"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type WalletHoldStatus string
+
+const (
+	WalletHoldActive   WalletHoldStatus = "active"
+	WalletHoldCaptured WalletHoldStatus = "captured"
+	WalletHoldReleased WalletHoldStatus = "released"
+	WalletHoldRefunded WalletHoldStatus = "refunded"
+)
+
+// WalletHold sets part of a wallet aside for an order until it is paid
+// (captured) or cancelled (released). A captured hold is refunded when the
+// order is cancelled later.
+type WalletHold struct {
+	ID        uint             `json:"id" gorm:"primarykey"`
+	AccountID uint             `json:"account_id" gorm:"index"`
+	OrderID   uint             `json:"order_id" gorm:"index"`
+	Amount    int64            `json:"amount_cents"`
+	Status    WalletHoldStatus `json:"status"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+type WalletResponse struct {
+	Balance   float64 `json:"balance"`
+	Held      float64 `json:"held"`
+	Available float64 `json:"available"`
+	Currency  string  `json:"currency"`
+}
+
+// WalletAdjustmentInput credits (positive) or debits (negative) a wallet.
+type WalletAdjustmentInput struct {
+	Amount float64 `json:"amount" binding:"required"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+// ReconciliationAccount compares an account's cached balances with its
+// entries and holds.
+type ReconciliationAccount struct {
+	AccountID     uint   `json:"account_id"`
+	Code          string `json:"code"`
+	Balance       int64  `json:"balance_cents"`
+	EntryTotal    int64  `json:"entry_total_cents"`
+	HeldAmount    int64  `json:"held_amount_cents"`
+	ActiveHolds   int64  `json:"active_holds_cents"`
+	BalanceMatch  bool   `json:"balance_match"`
+	HoldsMatch    bool   `json:"holds_match"`
+	NegativeFound bool   `json:"negative_found"`
+}
+
+type ReconciliationReport struct {
+	GeneratedAt            time.Time               `json:"generated_at"`
+	Balanced               bool                    `json:"balanced"`
+	LedgerTotal            int64                   `json:"ledger_total_cents"`
+	UnbalancedTransactions []uint                  `json:"unbalanced_transactions"`
+	Accounts               []ReconciliationAccount `json:"accounts"`
+	Mismatches             int                     `json:"mismatches"`
+}
@@ -0,0 +1,269 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Payment methods the mock gateway treats specially, in the spirit of a real
+// gateway's test cards. Any other value is approved, subject to FailureRate.
+const (
+	MockMethodDecline = "mock_decline"
+	MockMethodError   = "mock_error"
+)
+
+const (
+	mockSignatureHeader    = "Mock-Signature"
+	mockSignatureTolerance = 5 * time.Minute
+	mockDeliveryAttempts   = 3
+)
+
+// MockConfig tunes the mock gateway.
+type MockConfig struct {
+	// WebhookURL receives signed event callbacks. Events are dropped when
+	// it is empty.
+	WebhookURL string
+	Secret     string
+	// Delay is how long the gateway "thinks" before sending a webhook.
+	Delay time.Duration
+	// FailureRate is the share of authorizations, between 0 and 1, that are
+	// declined at random.
+	FailureRate float64
+}
+
+// MockProvider is an in-memory gateway that behaves like a real one:
+// authorizations are answered asynchronously through signed webhooks,
+// captures and refunds are checked against what was authorized, and failures
+// can be forced or injected at random. Charges live only as long as the
+// process.
+type MockProvider struct {
+	config MockConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	charges map[string]*mockCharge
+	byRef   map[string]string
+}
+
+type mockCharge struct {
+	amount     int64
+	authorized bool
+	captured   int64
+	refunded   int64
+}
+
+func NewMockProvider(config MockConfig) *MockProvider {
+	return &MockProvider{
+		config:  config,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		charges: map[string]*mockCharge{},
+		byRef:   map[string]string{},
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) Authorize(ctx context.Context, req AuthorizeRequest) (*Result, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if req.PaymentMethod == MockMethodError {
+		return nil, errors.New("mock gateway: connection reset")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Retrying with the same reference returns the original charge.
+	if ref, ok := p.byRef[req.Reference]; ok {
+		return &Result{ProviderRef: ref, Status: StatusPending}, nil
+	}
+
+	ref := "mock_ch_" + randomID()
+	charge := &mockCharge{amount: req.Amount}
+	p.charges[ref] = charge
+	p.byRef[req.Reference] = ref
+
+	event := Event{Type: EventAuthorized, ProviderRef: ref, Amount: req.Amount}
+	if req.PaymentMethod == MockMethodDecline || mathrand.Float64() < p.config.FailureRate {
+		event.Type = EventAuthorizationFailed
+		event.FailureReason = "card_declined"
+	} else {
+		charge.authorized = true
+	}
+	p.send(event)
+
+	return &Result{ProviderRef: ref, Status: StatusPending}, nil
+}
+
+func (p *MockProvider) Capture(ctx context.Context, providerRef string, amount int64) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[providerRef]
+	if !ok {
+		return nil, ErrUnknownPayment
+	}
+	if !charge.authorized {
+		return &Result{ProviderRef: providerRef, Status: StatusFailed, FailureReason: "not_authorized"}, nil
+	}
+	if amount <= 0 || charge.captured+amount > charge.amount {
+		return nil, ErrInvalidAmount
+	}
+
+	charge.captured += amount
+	p.send(Event{Type: EventCaptured, ProviderRef: providerRef, Amount: amount})
+	return &Result{ProviderRef: providerRef, Status: StatusSucceeded}, nil
+}
+
+// Refund gives money back on a charge. Uncaptured authorizations can be
+// refunded too, which releases the hold.
+func (p *MockProvider) Refund(ctx context.Context, providerRef string, amount int64) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[providerRef]
+	if !ok {
+		return nil, ErrUnknownPayment
+	}
+	if !charge.authorized {
+		return &Result{Status: StatusFailed, FailureReason: "not_authorized"}, nil
+	}
+	if amount <= 0 || charge.refunded+amount > charge.amount {
+		return nil, ErrInvalidAmount
+	}
+
+	charge.refunded += amount
+	p.send(Event{Type: EventRefunded, ProviderRef: providerRef, Amount: amount})
+	return &Result{ProviderRef: "mock_re_" + randomID(), Status: StatusSucceeded}, nil
+}
+
+func (p *MockProvider) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	timestamp, signature, ok := parseMockSignature(header.Get(mockSignatureHeader))
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+
+	sentAt := time.Unix(timestamp, 0)
+	if time.Since(sentAt) > mockSignatureTolerance || time.Until(sentAt) > mockSignatureTolerance {
+		return nil, ErrInvalidSignature
+	}
+
+	expected := p.sign(timestamp, payload)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return &event, nil
+}
+
+// send delivers an event after the configured delay, retrying a few times
+// like a real gateway would.
+func (p *MockProvider) send(event Event) {
+	if p.config.WebhookURL == "" {
+		return
+	}
+
+	event.ID = "mock_evt_" + randomID()
+	event.CreatedAt = time.Now()
+
+	go func() {
+		time.Sleep(p.config.Delay)
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("mock payment: encoding %s: %v", event.ID, err)
+			return
+		}
+
+		for attempt := 1; attempt <= mockDeliveryAttempts; attempt++ {
+			err = p.deliver(payload)
+			if err == nil {
+				return
+			}
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		log.Printf("mock payment: giving up on %s %s: %v", event.Type, event.ID, err)
+	}()
+}
+
+func (p *MockProvider) deliver(payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, p.config.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(mockSignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, p.sign(timestamp, payload)))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *MockProvider) sign(timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.config.Secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseMockSignature splits a "t=<unix>,v1=<hex>" header.
+func parseMockSignature(header string) (int64, string, bool) {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return 0, "", false
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, "", false
+			}
+			timestamp = parsed
+		case "v1":
+			signature = value
+		}
+	}
+	return timestamp, signature, timestamp != 0 && signature != ""
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,89 @@
+// Package payment defines the contract between FoodVerse and a payment
+// gateway. Amounts cross this boundary in minor currency units (cents) so no
+// provider ever sees a float.
+package payment
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Status is the outcome of a call to a provider.
+type Status string
+
+const (
+	// StatusPending means the provider accepted the request and will report
+	// the outcome later through a webhook.
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// EventType identifies what a webhook event reports.
+type EventType string
+
+const (
+	EventAuthorized          EventType = "payment.authorized"
+	EventAuthorizationFailed EventType = "payment.authorization_failed"
+	EventCaptured            EventType = "payment.captured"
+	EventRefunded            EventType = "payment.refunded"
+)
+
+var (
+	ErrInvalidSignature = errors.New("payment: invalid webhook signature")
+	ErrUnknownPayment   = errors.New("payment: unknown payment reference")
+	ErrInvalidAmount    = errors.New("payment: invalid amount")
+)
+
+// AuthorizeRequest asks the provider to hold Amount on the buyer's payment
+// method. Reference is our own ID for the payment and doubles as the
+// provider-side idempotency key.
+type AuthorizeRequest struct {
+	Reference     string
+	Amount        int64
+	Currency      string
+	PaymentMethod string
+}
+
+// Result is the immediate answer to a provider call. ProviderRef identifies
+// the charge for Authorize and Capture, and the refund for Refund.
+type Result struct {
+	ProviderRef   string
+	Status        Status
+	FailureReason string
+}
+
+// Event is a verified webhook notification. ProviderRef always identifies the
+// charge, even for refund events.
+type Event struct {
+	ID            string    `json:"id"`
+	Type          EventType `json:"type"`
+	ProviderRef   string    `json:"provider_ref"`
+	Amount        int64     `json:"amount"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Provider is a payment gateway.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Result, error)
+	Capture(ctx context.Context, providerRef string, amount int64) (*Result, error)
+	Refund(ctx context.Context, providerRef string, amount int64) (*Result, error)
+	// VerifyWebhook checks the signature of an incoming webhook and decodes
+	// it. It returns ErrInvalidSignature for anything it cannot trust.
+	VerifyWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// ToMinorUnits converts a price to cents.
+func ToMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromMinorUnits converts cents back to a price.
+func FromMinorUnits(amount int64) float64 {
+	return float64(amount) / 100
+}
@@ -0,0 +1,35 @@
+// Package payout defines the contract between FoodVerse and the service that
+// sends money to stores. Like payment providers, amounts are in minor
+// currency units.
+package payout
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrInvalidAmount = errors.New("payout: invalid amount")
+
+// TransferRequest sends Amount to a store. Reference is our own ID for the
+// payout and doubles as the provider-side idempotency key, so retrying a
+// transfer that already went through does not pay twice.
+type TransferRequest struct {
+	Reference string
+	StoreID   uint
+	Amount    int64
+	Currency  string
+}
+
+// Result is the outcome of a transfer. FailureReason is set when Succeeded is
+// false.
+type Result struct {
+	ProviderRef   string
+	Succeeded     bool
+	FailureReason string
+}
+
+// Provider pays stores out.
+type Provider interface {
+	Name() string
+	Transfer(ctx context.Context, req TransferRequest) (*Result, error)
+}
@@ -0,0 +1,69 @@
+package payout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	mathrand "math/rand"
+	"sync"
+)
+
+// StubConfig tunes the stub provider.
+type StubConfig struct {
+	// FailureRate is the share of transfers, between 0 and 1, that fail at
+	// random.
+	FailureRate float64
+}
+
+// StubProvider pretends to send transfers and only logs them. It remembers
+// successful transfers by reference for as long as the process runs, so
+// retries behave like they would against a real provider.
+type StubProvider struct {
+	config StubConfig
+
+	mu        sync.Mutex
+	transfers map[string]string
+}
+
+func NewStubProvider(config StubConfig) *StubProvider {
+	return &StubProvider{
+		config:    config,
+		transfers: map[string]string{},
+	}
+}
+
+func (p *StubProvider) Name() string {
+	return "stub"
+}
+
+func (p *StubProvider) Transfer(ctx context.Context, req TransferRequest) (*Result, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ref, ok := p.transfers[req.Reference]; ok {
+		return &Result{ProviderRef: ref, Succeeded: true}, nil
+	}
+
+	if p.config.FailureRate > 0 && mathrand.Float64() < p.config.FailureRate {
+		log.Printf("payout stub: transfer %s of %d %s to store %d failed", req.Reference, req.Amount, req.Currency, req.StoreID)
+		return &Result{Succeeded: false, FailureReason: "transfer_rejected"}, nil
+	}
+
+	ref := "stub_tr_" + randomID()
+	p.transfers[req.Reference] = ref
+	log.Printf("payout stub: sent %d %s to store %d as %s (%s)", req.Amount, req.Currency, req.StoreID, ref, req.Reference)
+	return &Result{ProviderRef: ref, Succeeded: true}, nil
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartRepository struct {
+	db *gorm.DB
+}
+
+func NewCartRepository(db *gorm.DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *CartRepository) WithTx(tx *gorm.DB) *CartRepository {
+	return &CartRepository{db: tx}
+}
+
+func (r *CartRepository) GetByUserID(userID uint) ([]model.CartItem, error) {
+	var items []model.CartItem
+	err := r.db.Where("user_id = ?", userID).Preload("FoodBag.Store").Order("created_at asc, id asc").Find(&items).Error
+	return items, err
+}
+
+// Add puts a food bag in the user's cart, adding to the quantity when the bag
+// is already there.
+func (r *CartRepository) Add(userID, foodBagID uint, quantity int) error {
+	item := model.CartItem{UserID: userID, FoodBagID: foodBagID, Quantity: quantity}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "food_bag_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   gorm.Expr("cart_items.quantity + EXCLUDED.quantity"),
+			"updated_at": gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(&item).Error
+}
+
+// SetQuantity replaces the quantity of a cart line. It reports false when the
+// food bag is not in the cart.
+func (r *CartRepository) SetQuantity(userID, foodBagID uint, quantity int) (bool, error) {
+	result := r.db.Model(&model.CartItem{}).
+		Where("user_id = ? AND food_bag_id = ?", userID, foodBagID).
+		Update("quantity", quantity)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Remove takes a food bag out of the cart. It reports false when the food bag
+// was not in the cart.
+func (r *CartRepository) Remove(userID, foodBagID uint) (bool, error) {
+	result := r.db.Where("user_id = ? AND food_bag_id = ?", userID, foodBagID).Delete(&model.CartItem{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *CartRepository) Clear(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.CartItem{}).Error
+}
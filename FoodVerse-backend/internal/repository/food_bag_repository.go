@@ -1,23 +1,62 @@
 package repository
 
 import (
-	"math"
+	"time"
 
 	"github.com/FoodVerse/FoodVerse-backend/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FoodBagRepository struct {
-	db *gorm.DB
+	db   *gorm.DB
+	geo  *GeoSearch
+	text *TextSearch
 }
 
-func NewFoodBagRepository(db *gorm.DB) *FoodBagRepository {
-	return &FoodBagRepository{db: db}
+func NewFoodBagRepository(db *gorm.DB, geo *GeoSearch, text *TextSearch) *FoodBagRepository {
+	return &FoodBagRepository{db: db, geo: geo, text: text}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *FoodBagRepository) WithTx(tx *gorm.DB) *FoodBagRepository {
+	return &FoodBagRepository{db: tx, geo: r.geo, text: r.text}
 }
 
 func (r *FoodBagRepository) Create(foodBag *model.FoodBag) error {
 	foodBag.QuantityLeft = foodBag.QuantityTotal
-	return r.db.Create(foodBag).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(foodBag).Error; err != nil {
+			return err
+		}
+		return syncFoodBagSuggestions(tx, foodBag.ID)
+	})
+}
+
+// CreateScheduled inserts a food bag for a schedule date. It reports false,
+// without an error, when the schedule already has a bag for that date.
+func (r *FoodBagRepository) CreateScheduled(foodBag *model.FoodBag) (bool, error) {
+	foodBag.QuantityLeft = foodBag.QuantityTotal
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(foodBag)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		created = true
+		return syncFoodBagSuggestions(tx, foodBag.ID)
+	})
+	return created, err
+}
+
+// GetBySchedule returns the bag a schedule created for a local date.
+func (r *FoodBagRepository) GetBySchedule(scheduleID uint, date string) (*model.FoodBag, error) {
+	var foodBag model.FoodBag
+	err := r.db.Where("schedule_id = ? AND scheduled_date = ?", scheduleID, date).First(&foodBag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &foodBag, nil
 }
 
 func (r *FoodBagRepository) GetByID(id uint) (*model.FoodBag, error) {
@@ -29,105 +68,274 @@ func (r *FoodBagRepository) GetByID(id uint) (*model.FoodBag, error) {
 	return &foodBag, nil
 }
 
-func (r *FoodBagRepository) GetByStoreID(storeID uint) ([]model.FoodBag, error) {
+// GetByIDForUpdate loads a food bag and locks its row until the surrounding
+// transaction ends.
+func (r *FoodBagRepository) GetByIDForUpdate(id uint) (*model.FoodBag, error) {
+	var foodBag model.FoodBag
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&foodBag, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &foodBag, nil
+}
+
+// bagProgressSQL is how far through its pickup window a bag is at a time,
+// from 0 to 1. Its placeholder is the time.
+const bagProgressSQL = "LEAST(1, GREATEST(0, " +
+	"EXTRACT(EPOCH FROM (CAST(? AS timestamptz) - food_bags.pickup_time_start)) / " +
+	"EXTRACT(EPOCH FROM (food_bags.pickup_time_end - food_bags.pickup_time_start))))"
+
+// bagStepsSQL is the number of price steps of a step-priced bag.
+const bagStepsSQL = "(CASE WHEN food_bags.price_steps < 2 THEN 4 ELSE food_bags.price_steps END)"
+
+// currentPriceSQL is a bag's price at a time, worked out the same way as
+// FoodBag.PriceAt. Its placeholders are the time, twice.
+const currentPriceSQL = "(CASE WHEN food_bags.pricing_strategy IN ('linear', 'step') " +
+	"AND food_bags.price_floor < food_bags.discounted_price " +
+	"AND food_bags.pickup_time_end > food_bags.pickup_time_start " +
+	"THEN GREATEST(food_bags.price_floor, CAST(ROUND(CAST(food_bags.discounted_price - " +
+	"(food_bags.discounted_price - food_bags.price_floor) * " +
+	"(CASE WHEN food_bags.pricing_strategy = 'step' " +
+	"THEN LEAST(1, FLOOR(" + bagProgressSQL + " * " + bagStepsSQL + ") / (" + bagStepsSQL + " - 1)) " +
+	"ELSE " + bagProgressSQL + " END) AS numeric), 2) AS float8)) " +
+	"ELSE food_bags.discounted_price END)"
+
+// discountSQL is a bag's discount off its original price at a time, as a
+// fraction. Its placeholders are the time, twice.
+const discountSQL = "(CASE WHEN food_bags.original_price > 0 " +
+	"THEN (food_bags.original_price - " + currentPriceSQL + ") / food_bags.original_price " +
+	"ELSE 0 END)"
+
+// foodBagSortKeys are the ways food bag lists can be sorted, with prices
+// worked out at asOf. Soonest pickup first is the default.
+func foodBagSortKeys(asOf time.Time) sortKeys {
+	return sortKeys{
+		"pickup_time":      {Expr: "food_bags.pickup_time_start", SQLType: "timestamptz"},
+		"price":            {Expr: currentPriceSQL, Args: []interface{}{asOf, asOf}, SQLType: "float8"},
+		"discount_percent": {Expr: discountSQL, Args: []interface{}{asOf, asOf}, SQLType: "float8", Desc: true},
+		"created_at":       {Expr: "food_bags.created_at", SQLType: "timestamptz", Desc: true},
+	}
+}
+
+// nearbyFoodBagSortKeys adds sorting by distance and store rating to the
+// food bag sort keys. Nearest first is the default.
+func nearbyFoodBagSortKeys(asOf time.Time) sortKeys {
+	keys := foodBagSortKeys(asOf)
+	keys["distance"] = sortKey{Expr: "nearby.distance", SQLType: "float8"}
+	keys["rating"] = sortKey{Expr: "stores.rating", SQLType: "real", Desc: true}
+	return keys
+}
+
+// GetByStoreID returns a page of the store's active bags.
+func (r *FoodBagRepository) GetByStoreID(storeID uint, page model.PageRequest) (*model.Page[model.FoodBag], error) {
+	ks, err := newKeyset(page, "pickup_time", "food_bags.id", foodBagSortKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.db.Model(&model.FoodBag{}).Where("food_bags.store_id = ? AND food_bags.is_active = ?", storeID, true)
+	total, err := countRows(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ks.Scan(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, next := ks.Trim(rows)
+
 	var foodBags []model.FoodBag
-	err := r.db.Where("store_id = ? AND is_active = ?", storeID, true).Preload("Store").Find(&foodBags).Error
-	return foodBags, err
+	if len(rows) > 0 {
+		if err := r.db.Preload("Store").Where("id IN ?", rowIDs(rows)).Find(&foodBags).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &model.Page[model.FoodBag]{
+		Data:       inIDOrder(foodBags, rowIDs(rows), func(f *model.FoodBag) uint { return f.ID }),
+		NextCursor: next,
+		Total:      total,
+	}, nil
 }
 
-func (r *FoodBagRepository) Update(foodBag *model.FoodBag) error {
-	return r.db.Save(foodBag).Error
+// Update saves a food bag. QuantityLeft is not taken from foodBag, which
+// concurrent orders may have made stale; instead it moves by as much as
+// QuantityTotal changed, never below zero. Update reports whether that
+// brought a sold-out bag back in stock.
+func (r *FoodBagRepository) Update(foodBag *model.FoodBag) (restocked bool, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var current model.FoodBag
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "quantity_total", "quantity_left").
+			First(&current, foodBag.ID).Error
+		if err != nil {
+			return err
+		}
+		foodBag.QuantityLeft = max(current.QuantityLeft+foodBag.QuantityTotal-current.QuantityTotal, 0)
+		restocked = current.QuantityLeft == 0 && foodBag.QuantityLeft > 0
+
+		if err := tx.Save(foodBag).Error; err != nil {
+			return err
+		}
+		return syncFoodBagSuggestions(tx, foodBag.ID)
+	})
+	return restocked, err
 }
 
 func (r *FoodBagRepository) Delete(id uint) error {
-	return r.db.Delete(&model.FoodBag{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.FoodBag{}, id).Error; err != nil {
+			return err
+		}
+		return syncFoodBagSuggestions(tx, id)
+	})
 }
 
-func (r *FoodBagRepository) SearchNearby(req model.FoodBagSearchRequest) ([]model.FoodBagResponse, error) {
-	var foodBags []model.FoodBag
+// foodBagHighlightColumns are the bag columns search results highlight.
+var foodBagHighlightColumns = []string{"title", "category", "description"}
+
+// SearchNearby returns a page of the bags on sale at stores within the
+// requested radius, nearest store first unless another sort is asked for.
+// With a query, bags match on their own text or their store's, and are
+// ranked by relevance weighed against distance. Distances, prices,
+// ordering and the limit are all worked out in the database.
+func (r *FoodBagRepository) SearchNearby(req model.FoodBagSearchRequest) (*model.Page[model.FoodBagResponse], error) {
 	radius := req.Radius
 	if radius <= 0 {
-		radius = 5.0 // default 5km
+		radius = defaultNearbyRadiusKm
 	}
 
-	query := r.db.Where("is_active = ? AND quantity_left > 0", true).Preload("Store")
+	keys, defaultSort := nearbyFoodBagSortKeys, "distance"
+	text, hasQuery := r.text.parseTextQuery(req.Query)
+	if hasQuery {
+		// A bag found through its store ranks below one matching itself.
+		bagRelevance, bagArgs := r.text.Relevance("food_bags.search_vector", "food_bags.title", text)
+		storeRelevance, storeArgs := r.text.Relevance("stores.search_vector", "stores.name", text)
+		relevance, args := r.text.Blend("GREATEST("+bagRelevance+", 0.5 * "+storeRelevance+")", append(bagArgs, storeArgs...), radius)
+		keys = func(asOf time.Time) sortKeys {
+			keys := nearbyFoodBagSortKeys(asOf)
+			keys["relevance"] = sortKey{Expr: relevance, Args: args, SQLType: "float8", Desc: true}
+			return keys
+		}
+		defaultSort = "relevance"
+	}
+
+	ks, err := newKeyset(req.PageRequest, defaultSort, "food_bags.id", keys)
+	if err != nil {
+		return nil, err
+	}
+
+	asOf := ks.AsOf()
+	nearby := r.geo.Nearby(r.db.Model(&model.Store{}), req.Latitude, req.Longitude, radius)
+	query := r.db.Model(&model.FoodBag{}).
+		Joins("JOIN (?) AS nearby ON nearby.id = food_bags.store_id", nearby).
+		Joins("JOIN stores ON stores.id = food_bags.store_id").
+		Where("food_bags.is_active = ? AND food_bags.quantity_left > 0 AND food_bags.pickup_time_end > ?", true, asOf)
 
 	// Filter by category if provided
 	if req.Category != "" {
-		query = query.Where("category = ?", req.Category)
+		query = query.Where("food_bags.category = ?", req.Category)
 	}
 
-	// Filter by price range if provided
+	// Filter by price range if provided, against the current price of
+	// decaying bags.
+	if req.MinPrice > 0 {
+		query = query.Where(currentPriceSQL+" >= ?", asOf, asOf, req.MinPrice)
+	}
 	if req.MaxPrice > 0 {
-		query = query.Where("discounted_price <= ?", req.MaxPrice)
+		query = query.Where(currentPriceSQL+" <= ?", asOf, asOf, req.MaxPrice)
 	}
-	if req.MinPrice > 0 {
-		query = query.Where("discounted_price >= ?", req.MinPrice)
+
+	if hasQuery {
+		bagMatch, bagArgs := r.text.Match("food_bags.search_vector", "food_bags.title", text)
+		storeMatch, storeArgs := r.text.Match("stores.search_vector", "stores.name", text)
+		query = query.Where(bagMatch+" OR "+storeMatch, append(bagArgs, storeArgs...)...)
 	}
 
-	err := query.Find(&foodBags).Error
+	total, err := countRows(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ks.Scan(query)
 	if err != nil {
 		return nil, err
 	}
+	rows, next := ks.Trim(rows)
 
-	var responses []model.FoodBagResponse
-	for _, foodBag := range foodBags {
-		distance := r.calculateDistance(req.Latitude, req.Longitude, foodBag.Store.Latitude, foodBag.Store.Longitude)
-		if distance <= radius {
-			discountPercent := int(((foodBag.OriginalPrice - foodBag.DiscountedPrice) / foodBag.OriginalPrice) * 100)
-
-			storeResponse := model.StoreResponse{
-				ID:        foodBag.Store.ID,
-				Name:      foodBag.Store.Name,
-				Address:   foodBag.Store.Address,
-				Category:  foodBag.Store.Category,
-				ImageURL:  foodBag.Store.ImageURL,
-				Rating:    foodBag.Store.Rating,
-				Distance:  distance,
-				CreatedAt: foodBag.Store.CreatedAt,
-				UpdatedAt: foodBag.Store.UpdatedAt,
+	var foodBags []model.FoodBag
+	highlights := map[uint]map[string]string{}
+	if len(rows) > 0 {
+		if err := preloadHours(r.db, "Store").Preload("Store").Where("id IN ?", rowIDs(rows)).Find(&foodBags).Error; err != nil {
+			return nil, err
+		}
+		if hasQuery {
+			if highlights, err = r.text.Highlights(r.db, "food_bags", rowIDs(rows), foodBagHighlightColumns, text); err != nil {
+				return nil, err
 			}
+		}
+	}
+	foodBags = inIDOrder(foodBags, rowIDs(rows), func(f *model.FoodBag) uint { return f.ID })
 
-			response := model.FoodBagResponse{
-				ID:              foodBag.ID,
-				Title:           foodBag.Title,
-				Description:     foodBag.Description,
-				OriginalPrice:   foodBag.OriginalPrice,
-				DiscountedPrice: foodBag.DiscountedPrice,
-				DiscountPercent: discountPercent,
-				QuantityLeft:    foodBag.QuantityLeft,
-				PickupTimeStart: foodBag.PickupTimeStart,
-				PickupTimeEnd:   foodBag.PickupTimeEnd,
-				ImageURL:        foodBag.ImageURL,
-				Category:        foodBag.Category,
-				Store:           storeResponse,
-				CreatedAt:       foodBag.CreatedAt,
-				UpdatedAt:       foodBag.UpdatedAt,
-			}
-			responses = append(responses, response)
+	// Prices are those the page was filtered and sorted by, so they stay
+	// consistent across the pages of one search.
+	now := time.Now()
+	responses := make([]model.FoodBagResponse, 0, len(foodBags))
+	for _, foodBag := range foodBags {
+		response := foodBag.ToResponse(asOf)
+		response.Highlights = highlights[foodBag.ID]
+		response.Store = model.StoreResponse{
+			ID:        foodBag.Store.ID,
+			Name:      foodBag.Store.Name,
+			Address:   foodBag.Store.Address,
+			Category:  foodBag.Store.Category,
+			ImageURL:  foodBag.Store.ImageURL,
+			Rating:    foodBag.Store.Rating,
+			Distance:  haversineKm(req.Latitude, req.Longitude, foodBag.Store.Latitude, foodBag.Store.Longitude),
+			Timezone:  foodBag.Store.Timezone,
+			IsOpenNow: foodBag.Store.IsOpenAt(now),
+			CreatedAt: foodBag.Store.CreatedAt,
+			UpdatedAt: foodBag.Store.UpdatedAt,
 		}
+		responses = append(responses, response)
 	}
 
-	return responses, nil
+	return &model.Page[model.FoodBagResponse]{Data: responses, NextCursor: next, Total: total}, nil
 }
 
 func (r *FoodBagRepository) UpdateQuantity(id uint, newQuantity int) error {
 	return r.db.Model(&model.FoodBag{}).Where("id = ?", id).Update("quantity_left", newQuantity).Error
 }
 
-// calculateDistance calculates the distance between two points using Haversine formula
-func (r *FoodBagRepository) calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const earthRadius = 6371 // Earth radius in kilometers
-
-	dLat := (lat2 - lat1) * math.Pi / 180.0
-	dLon := (lon2 - lon1) * math.Pi / 180.0
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*math.Pi/180.0)*math.Cos(lat2*math.Pi/180.0)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
+// DecrementQuantity takes quantity off a food bag only if enough is left.
+// It reports false when the bag could not cover the requested quantity.
+func (r *FoodBagRepository) DecrementQuantity(id uint, quantity int) (bool, error) {
+	result := r.db.Model(&model.FoodBag{}).
+		Where("id = ? AND quantity_left >= ?", id, quantity).
+		Update("quantity_left", gorm.Expr("quantity_left - ?", quantity))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
 
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	distance := earthRadius * c
+// DeactivateExpired switches off every active food bag whose pickup window
+// ended before cutoff and returns how many were changed.
+func (r *FoodBagRepository) DeactivateExpired(cutoff time.Time) (int64, error) {
+	result := r.db.Model(&model.FoodBag{}).
+		Where("is_active = ? AND pickup_time_end < ?", true, cutoff).
+		Update("is_active", false)
+	return result.RowsAffected, result.Error
+}
 
-	return distance
+// IncrementQuantity puts quantity back on a food bag, e.g. after a
+// cancellation. It reports whether the bag had been sold out.
+func (r *FoodBagRepository) IncrementQuantity(id uint, quantity int) (wasSoldOut bool, err error) {
+	var foodBag model.FoodBag
+	result := r.db.Model(&foodBag).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "quantity_left"}}}).
+		Where("id = ?", id).
+		Update("quantity_left", gorm.Expr("quantity_left + ?", quantity))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return quantity > 0 && foodBag.QuantityLeft <= quantity, nil
 }
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+	"github.com/FoodVerse/FoodVerse-backend/pkg/database"
+	"gorm.io/gorm"
+)
+
+// seedNearbyBags spreads stores, each with one active bag, over roughly
+// 100 km around a point.
+func seedNearbyBags(b *testing.B, db *gorm.DB, lat, lon float64, count int) {
+	b.Helper()
+	rng := rand.New(rand.NewSource(1))
+
+	stores := make([]model.Store, count)
+	for i := range stores {
+		stores[i] = model.Store{
+			Name:      fmt.Sprintf("Store %d", i),
+			Address:   "Main St 1",
+			Category:  "bakery",
+			Latitude:  lat + (rng.Float64()-0.5)*0.9,
+			Longitude: lon + (rng.Float64()-0.5)*1.5,
+			IsActive:  true,
+		}
+	}
+	if err := db.CreateInBatches(&stores, 500).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	now := time.Now()
+	foodBags := make([]model.FoodBag, count)
+	for i, store := range stores {
+		foodBags[i] = model.FoodBag{
+			StoreID:         store.ID,
+			Title:           fmt.Sprintf("Bag %d", i),
+			OriginalPrice:   12,
+			DiscountedPrice: 4,
+			QuantityTotal:   5,
+			QuantityLeft:    5,
+			PickupTimeStart: now.Add(time.Hour),
+			PickupTimeEnd:   now.Add(3 * time.Hour),
+			IsActive:        true,
+		}
+	}
+	if err := db.CreateInBatches(&foodBags, 500).Error; err != nil {
+		b.Fatal(err)
+	}
+	if err := db.Exec("ANALYZE").Error; err != nil {
+		b.Fatal(err)
+	}
+}
+
+// BenchmarkSearchNearby compares the PostGIS search with the bounding box
+// and haversine fallback on the same data. The PostGIS case is skipped when
+// the extension is not installed.
+func BenchmarkSearchNearby(b *testing.B) {
+	db := testdb.Open(b)
+	const lat, lon = 52.37, 4.89
+	seedNearbyBags(b, db, lat, lon, 20000)
+
+	text := NewTextSearch(database.HasExtension(db, "pg_trgm"))
+	modes := []struct {
+		name    string
+		postgis bool
+	}{
+		{"postgis", true},
+		{"bounding_box", false},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			if mode.postgis && !database.HasExtension(db, "postgis") {
+				b.Skip("postgis is not installed")
+			}
+			repo := NewFoodBagRepository(db, NewGeoSearch(mode.postgis), text)
+			req := model.FoodBagSearchRequest{Latitude: lat, Longitude: lon, Radius: 5}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.SearchNearby(req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
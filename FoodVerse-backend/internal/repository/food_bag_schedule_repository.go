@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FoodBagScheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewFoodBagScheduleRepository(db *gorm.DB) *FoodBagScheduleRepository {
+	return &FoodBagScheduleRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *FoodBagScheduleRepository) WithTx(tx *gorm.DB) *FoodBagScheduleRepository {
+	return &FoodBagScheduleRepository{db: tx}
+}
+
+func (r *FoodBagScheduleRepository) Create(schedule *model.FoodBagSchedule) error {
+	return r.db.Omit("Template", "Store").Create(schedule).Error
+}
+
+func (r *FoodBagScheduleRepository) GetByID(id uint) (*model.FoodBagSchedule, error) {
+	var schedule model.FoodBagSchedule
+	err := r.db.Preload("Template").Preload("Skips", func(db *gorm.DB) *gorm.DB {
+		return db.Order("date asc")
+	}).First(&schedule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *FoodBagScheduleRepository) GetByStoreIDs(storeIDs []uint) ([]model.FoodBagSchedule, error) {
+	var schedules []model.FoodBagSchedule
+	err := r.db.Where("store_id IN ?", storeIDs).Preload("Template").Preload("Skips", func(db *gorm.DB) *gorm.DB {
+		return db.Order("date asc")
+	}).Order("id asc").Find(&schedules).Error
+	return schedules, err
+}
+
+// GetActive returns every schedule that is not paused, with the template,
+// store and skips needed to generate its food bags.
+func (r *FoodBagScheduleRepository) GetActive() ([]model.FoodBagSchedule, error) {
+	var schedules []model.FoodBagSchedule
+	err := preloadHours(r.db, "Store").Where("is_paused = ?", false).Preload("Template").Preload("Store").Preload("Skips").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *FoodBagScheduleRepository) Update(schedule *model.FoodBagSchedule) error {
+	return r.db.Omit(clause.Associations).Save(schedule).Error
+}
+
+func (r *FoodBagScheduleRepository) UpdateFields(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&model.FoodBagSchedule{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *FoodBagScheduleRepository) Delete(id uint) error {
+	return r.db.Delete(&model.FoodBagSchedule{}, id).Error
+}
+
+func (r *FoodBagScheduleRepository) DeleteByTemplateID(templateID uint) error {
+	return r.db.Where("template_id = ?", templateID).Delete(&model.FoodBagSchedule{}).Error
+}
+
+// AddSkip records a skipped date. Skipping a date twice is not an error.
+func (r *FoodBagScheduleRepository) AddSkip(scheduleID uint, date string) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.FoodBagScheduleSkip{ScheduleID: scheduleID, Date: date}).Error
+}
+
+// RemoveSkip deletes a skipped date and reports whether there was one.
+func (r *FoodBagScheduleRepository) RemoveSkip(scheduleID uint, date string) (bool, error) {
+	result := r.db.Where("schedule_id = ? AND date = ?", scheduleID, date).Delete(&model.FoodBagScheduleSkip{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type FoodBagTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewFoodBagTemplateRepository(db *gorm.DB) *FoodBagTemplateRepository {
+	return &FoodBagTemplateRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *FoodBagTemplateRepository) WithTx(tx *gorm.DB) *FoodBagTemplateRepository {
+	return &FoodBagTemplateRepository{db: tx}
+}
+
+func (r *FoodBagTemplateRepository) Create(template *model.FoodBagTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *FoodBagTemplateRepository) GetByID(id uint) (*model.FoodBagTemplate, error) {
+	var template model.FoodBagTemplate
+	err := r.db.First(&template, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *FoodBagTemplateRepository) GetByStoreIDs(storeIDs []uint) ([]model.FoodBagTemplate, error) {
+	var templates []model.FoodBagTemplate
+	err := r.db.Where("store_id IN ?", storeIDs).Order("title asc").Find(&templates).Error
+	return templates, err
+}
+
+func (r *FoodBagTemplateRepository) Update(template *model.FoodBagTemplate) error {
+	return r.db.Save(template).Error
+}
+
+func (r *FoodBagTemplateRepository) Delete(id uint) error {
+	return r.db.Delete(&model.FoodBagTemplate{}, id).Error
+}
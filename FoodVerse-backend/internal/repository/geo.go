@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"math"
+
+	"gorm.io/gorm"
+)
+
+const earthRadiusKm = 6371.0
+
+const defaultNearbyRadiusKm = 5.0
+
+// storeGeography is the stores' location as a PostGIS geography. It must
+// match the expression of the idx_stores_geography index.
+const storeGeography = "geography(ST_SetSRID(ST_MakePoint(stores.longitude, stores.latitude), 4326))"
+
+// haversineSQL is the great-circle distance in kilometers from a point to a
+// store. Its placeholders are the point's latitude, latitude again and
+// longitude.
+const haversineSQL = "2 * 6371 * asin(least(1, sqrt(" +
+	"power(sin(radians(stores.latitude - ?) / 2), 2) + " +
+	"cos(radians(?)) * cos(radians(stores.latitude)) * power(sin(radians(stores.longitude - ?) / 2), 2))))"
+
+// GeoSearch finds stores near a point in the database. With PostGIS it uses
+// the GiST index on the stores' geography; without it, a bounding box on the
+// indexed coordinates narrows the rows before exact distances are worked out.
+type GeoSearch struct {
+	postgis bool
+}
+
+func NewGeoSearch(postgis bool) *GeoSearch {
+	return &GeoSearch{postgis: postgis}
+}
+
+// Nearby narrows a query on stores to those within radiusKm of a point. The
+// result is a subquery of store ids with their distance in kilometers,
+// suitable for ordering and joining.
+func (g *GeoSearch) Nearby(stores *gorm.DB, lat, lon, radiusKm float64) *gorm.DB {
+	var candidates *gorm.DB
+	if g.postgis {
+		origin := "geography(ST_SetSRID(ST_MakePoint(?, ?), 4326))"
+		candidates = stores.
+			Select("stores.id, ST_Distance("+storeGeography+", "+origin+") / 1000 AS distance", lon, lat).
+			Where("ST_DWithin("+storeGeography+", "+origin+", ?)", lon, lat, radiusKm*1000)
+	} else {
+		minLat, maxLat, minLon, maxLon, wraps := boundingBox(lat, lon, radiusKm)
+		candidates = stores.
+			Select("stores.id, "+haversineSQL+" AS distance", lat, lat, lon).
+			Where("stores.latitude BETWEEN ? AND ?", minLat, maxLat)
+		if !wraps {
+			candidates = candidates.Where("stores.longitude BETWEEN ? AND ?", minLon, maxLon)
+		}
+	}
+
+	return stores.Session(&gorm.Session{NewDB: true}).
+		Table("(?) AS nearby", candidates).
+		Where("nearby.distance <= ?", radiusKm)
+}
+
+// boundingBox returns the latitude and longitude bounds of a circle. wraps
+// is true when the circle reaches a pole or crosses the antimeridian, in
+// which case the longitude bounds should not be used.
+func boundingBox(lat, lon, radiusKm float64) (minLat, maxLat, minLon, maxLon float64, wraps bool) {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat = lat-dLat, lat+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		return math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180, true
+	}
+
+	dLon := dLat / math.Cos(lat*math.Pi/180)
+	minLon, maxLon = lon-dLon, lon+dLon
+	if minLon < -180 || maxLon > 180 {
+		return minLat, maxLat, -180, 180, true
+	}
+	return minLat, maxLat, minLon, maxLon, false
+}
+
+// haversineKm calculates the distance between two points using the
+// Haversine formula.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180.0
+	dLon := (lon2 - lon1) * math.Pi / 180.0
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180.0)*math.Cos(lat2*math.Pi/180.0)*
+			math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve stores a new key. It reports false when the user already used the
+// key, in which case record is left untouched.
+func (r *IdempotencyRepository) Reserve(record *model.IdempotencyKey) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *IdempotencyRepository) GetByUserAndKey(userID uint, key string) (*model.IdempotencyKey, error) {
+	var record model.IdempotencyKey
+	err := r.db.Where("user_id = ? AND key = ?", userID, key).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete stores the response that retries will replay.
+func (r *IdempotencyRepository) Complete(id uint, statusCode int, contentType string, body []byte) error {
+	return r.db.Model(&model.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+		"completed_at":  time.Now(),
+	}).Error
+}
+
+func (r *IdempotencyRepository) Delete(id uint) error {
+	return r.db.Delete(&model.IdempotencyKey{}, id).Error
+}
+
+// DeleteUnfinished drops a key that is still in progress and was created
+// before cutoff, which happens when the server died mid-request. It reports
+// false when the key completed or was already removed.
+func (r *IdempotencyRepository) DeleteUnfinished(id uint, cutoff time.Time) (bool, error) {
+	result := r.db.Where("id = ? AND completed_at IS NULL AND created_at < ?", id, cutoff).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteCreatedBefore purges keys older than cutoff and returns how many were
+// removed.
+func (r *IdempotencyRepository) DeleteCreatedBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
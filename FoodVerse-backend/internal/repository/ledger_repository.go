@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *LedgerRepository) WithTx(tx *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db: tx}
+}
+
+// EnsureAccount creates the account unless one with the same code exists.
+func (r *LedgerRepository) EnsureAccount(account *model.LedgerAccount) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(account).Error
+}
+
+func (r *LedgerRepository) GetAccountByCode(code string) (*model.LedgerAccount, error) {
+	var account model.LedgerAccount
+	err := r.db.Where("code = ?", code).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetAccountByCodeForUpdate loads an account and locks its row until the
+// surrounding transaction ends.
+func (r *LedgerRepository) GetAccountByCodeForUpdate(code string) (*model.LedgerAccount, error) {
+	var account model.LedgerAccount
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *LedgerRepository) GetAccounts() ([]model.LedgerAccount, error) {
+	var accounts []model.LedgerAccount
+	err := r.db.Order("id asc").Find(&accounts).Error
+	return accounts, err
+}
+
+// ApplyDelta changes an account's balance and held amount. Unless the
+// account may go negative, the change is refused when the balance would drop
+// below zero or below what is held; it reports false in that case.
+func (r *LedgerRepository) ApplyDelta(accountID uint, balanceDelta, heldDelta int64) (bool, error) {
+	result := r.db.Model(&model.LedgerAccount{}).
+		Where("id = ?", accountID).
+		Where("allow_negative OR (balance + ? >= 0 AND held_amount + ? >= 0 AND balance + ? >= held_amount + ?)",
+			balanceDelta, heldDelta, balanceDelta, heldDelta).
+		Updates(map[string]interface{}{
+			"balance":     gorm.Expr("balance + ?", balanceDelta),
+			"held_amount": gorm.Expr("held_amount + ?", heldDelta),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CreateTransaction stores a transaction together with its entries.
+func (r *LedgerRepository) CreateTransaction(transaction *model.LedgerTransaction) error {
+	return r.db.Create(transaction).Error
+}
+
+// GetEntriesByAccount returns an account's entries newest first, with the
+// transaction that explains each of them.
+func (r *LedgerRepository) GetEntriesByAccount(accountID uint, limit, offset int) ([]model.LedgerEntry, int64, error) {
+	var entries []model.LedgerEntry
+	var total int64
+
+	query := r.db.Model(&model.LedgerEntry{}).Where("account_id = ?", accountID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Transaction").Order("id desc").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *LedgerRepository) CreateHold(hold *model.WalletHold) error {
+	return r.db.Create(hold).Error
+}
+
+// GetHoldForUpdate loads the order's hold in the given status and locks it.
+func (r *LedgerRepository) GetHoldForUpdate(orderID uint, status model.WalletHoldStatus) (*model.WalletHold, error) {
+	var hold model.WalletHold
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, status).
+		First(&hold).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *LedgerRepository) UpdateHoldStatus(id uint, status model.WalletHoldStatus) error {
+	return r.db.Model(&model.WalletHold{}).Where("id = ?", id).Update("status", status).Error
+}
+
+type accountTotal struct {
+	AccountID uint
+	Total     int64
+}
+
+// EntryTotals sums the entries of every account.
+func (r *LedgerRepository) EntryTotals() (map[uint]int64, error) {
+	var rows []accountTotal
+	err := r.db.Model(&model.LedgerEntry{}).
+		Select("account_id, SUM(amount) AS total").
+		Group("account_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		totals[row.AccountID] = row.Total
+	}
+	return totals, nil
+}
+
+// ActiveHoldTotals sums the active holds of every account.
+func (r *LedgerRepository) ActiveHoldTotals() (map[uint]int64, error) {
+	var rows []accountTotal
+	err := r.db.Model(&model.WalletHold{}).
+		Select("account_id, SUM(amount) AS total").
+		Where("status = ?", model.WalletHoldActive).
+		Group("account_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		totals[row.AccountID] = row.Total
+	}
+	return totals, nil
+}
+
+// UnbalancedTransactions returns the IDs of transactions whose entries do not
+// add up to zero.
+func (r *LedgerRepository) UnbalancedTransactions() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.LedgerEntry{}).
+		Select("transaction_id").
+		Group("transaction_id").
+		Having("SUM(amount) <> 0").
+		Pluck("transaction_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *NotificationRepository) WithTx(tx *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: tx}
+}
+
+// Create inserts a notification. It reports false, without an error, when
+// the saved search was already told about the bag.
+func (r *NotificationRepository) Create(notification *model.Notification) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(notification)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CountSince counts the notifications a user was sent of a type since a
+// time.
+func (r *NotificationRepository) CountSince(userID uint, notificationType model.NotificationType, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Notification{}).
+		Where("user_id = ? AND type = ? AND created_at > ?", userID, notificationType, since).
+		Count(&count).Error
+	return count, err
+}
+
+// notificationSortKeys are the ways notification lists can be sorted.
+func notificationSortKeys(time.Time) sortKeys {
+	return sortKeys{
+		"created_at": {Expr: "notifications.created_at", SQLType: "timestamptz", Desc: true},
+	}
+}
+
+// GetByUserID returns a page of a user's notifications, newest first,
+// optionally only those not yet read.
+func (r *NotificationRepository) GetByUserID(userID uint, unreadOnly bool, page model.PageRequest) (*model.Page[model.Notification], error) {
+	ks, err := newKeyset(page, "created_at", "notifications.id", notificationSortKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.db.Model(&model.Notification{}).Where("notifications.user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("notifications.read_at IS NULL")
+	}
+	total, err := countRows(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ks.Scan(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, next := ks.Trim(rows)
+
+	var notifications []model.Notification
+	if len(rows) > 0 {
+		if err := r.db.Where("id IN ?", rowIDs(rows)).Find(&notifications).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &model.Page[model.Notification]{
+		Data:       inIDOrder(notifications, rowIDs(rows), func(n *model.Notification) uint { return n.ID }),
+		NextCursor: next,
+		Total:      total,
+	}, nil
+}
+
+// MarkRead marks one of a user's notifications read, keeping the time it
+// was first read. It reports false when the user has no such notification.
+func (r *NotificationRepository) MarkRead(id, userID uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", now))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// MarkAllRead marks all of a user's unread notifications read and returns
+// how many there were.
+func (r *NotificationRepository) MarkAllRead(userID uint, now time.Time) (int64, error) {
+	result := r.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", now)
+	return result.RowsAffected, result.Error
+}
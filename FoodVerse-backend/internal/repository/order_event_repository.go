@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type OrderEventRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderEventRepository(db *gorm.DB) *OrderEventRepository {
+	return &OrderEventRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *OrderEventRepository) WithTx(tx *gorm.DB) *OrderEventRepository {
+	return &OrderEventRepository{db: tx}
+}
+
+func (r *OrderEventRepository) Create(event *model.OrderEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *OrderEventRepository) GetByOrderID(orderID uint) ([]model.OrderEvent, error) {
+	var events []model.OrderEvent
+	err := r.db.Where("order_id = ?", orderID).Order("created_at asc, id asc").Find(&events).Error
+	return events, err
+}
@@ -2,10 +2,14 @@ package repository
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository struct {
@@ -16,31 +20,165 @@ func NewOrderRepository(db *gorm.DB) *OrderRepository {
 	return &OrderRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *OrderRepository) WithTx(tx *gorm.DB) *OrderRepository {
+	return &OrderRepository{db: tx}
+}
+
+// openOrderStatuses are the statuses in which an order's pickup code is live.
+var openOrderStatuses = []model.OrderStatus{
+	model.OrderStatusPending,
+	model.OrderStatusConfirmed,
+	model.OrderStatusReady,
+}
+
+// pickupCodeAttempts bounds the retries when a generated pickup code is
+// already taken at the store.
+const pickupCodeAttempts = 10
+
+// pickupCodeIndex is the partial unique index on the pickup codes of a
+// store's open orders.
+const pickupCodeIndex = "idx_orders_store_open_pickup_code"
+
+// Create inserts an order with a pickup code that is free at its store. A
+// concurrent order can take the same code between the check and the
+// insert; the insert then hits the unique index and is retried with a new
+// code. Each attempt runs in a savepoint, so a collision does not abort a
+// surrounding transaction.
 func (r *OrderRepository) Create(order *model.Order) error {
-	// Generate pickup code
-	order.PickupCode = r.generatePickupCode()
-	return r.db.Create(order).Error
+	for i := 0; i < pickupCodeAttempts; i++ {
+		code, err := r.uniquePickupCode(order.StoreID)
+		if err != nil {
+			return err
+		}
+		order.PickupCode = code
+
+		err = r.db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(order).Error
+		})
+		if !isUniqueViolation(err, pickupCodeIndex) {
+			return err
+		}
+		order.ID = 0
+	}
+	return errors.New("could not generate a unique pickup code")
 }
 
 func (r *OrderRepository) GetByID(id uint) (*model.Order, error) {
 	var order model.Order
-	err := r.db.Preload("User").Preload("FoodBag").Preload("Store").First(&order, id).Error
+	err := r.db.Preload("User").Preload("FoodBag").Preload("Store").Preload("Items.FoodBag").Preload("Payments").Preload("Refunds").First(&order, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &order, nil
 }
 
-func (r *OrderRepository) GetByUserID(userID uint) ([]model.Order, error) {
+// GetByIDForUpdate loads an order and locks its row until the surrounding
+// transaction ends.
+func (r *OrderRepository) GetByIDForUpdate(id uint) (*model.Order, error) {
+	var order model.Order
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CountPlacedByUser counts the user's orders that were not cancelled.
+func (r *OrderRepository) CountPlacedByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Order{}).
+		Where("user_id = ? AND status <> ?", userID, model.OrderStatusCancelled).
+		Count(&count).Error
+	return count, err
+}
+
+// GetRecentPlacedByUser returns up to limit of the user's orders placed
+// since a time and not cancelled, newest first, with their bags and the
+// bags' stores.
+func (r *OrderRepository) GetRecentPlacedByUser(userID uint, since time.Time, limit int) ([]model.Order, error) {
 	var orders []model.Order
-	err := r.db.Where("user_id = ?", userID).Preload("FoodBag").Preload("Store").Order("created_at desc").Find(&orders).Error
+	err := r.db.Where("user_id = ? AND status <> ? AND created_at >= ?", userID, model.OrderStatusCancelled, since).
+		Preload("FoodBag.Store").Preload("Items.FoodBag.Store").
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&orders).Error
 	return orders, err
 }
 
-func (r *OrderRepository) GetByStoreID(storeID uint) ([]model.Order, error) {
+// CountRecentByStore counts the orders placed at each of the stores since
+// a time that were not cancelled. Stores without orders are left out.
+func (r *OrderRepository) CountRecentByStore(storeIDs []uint, since time.Time) (map[uint]int64, error) {
+	counts := map[uint]int64{}
+	if len(storeIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		StoreID uint
+		Count   int64
+	}
+	err := r.db.Model(&model.Order{}).
+		Select("store_id, count(*) AS count").
+		Where("store_id IN ? AND status <> ? AND created_at >= ?", storeIDs, model.OrderStatusCancelled, since).
+		Group("store_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.StoreID] = row.Count
+	}
+	return counts, nil
+}
+
+// orderSortKeys are the ways order lists can be sorted. Newest first is
+// the default.
+func orderSortKeys(time.Time) sortKeys {
+	return sortKeys{
+		"created_at":  {Expr: "orders.created_at", SQLType: "timestamptz", Desc: true},
+		"total_price": {Expr: "orders.total_price", SQLType: "float8", Desc: true},
+	}
+}
+
+func (r *OrderRepository) GetByUserID(userID uint, page model.PageRequest) (*model.Page[model.Order], error) {
+	query := r.db.Model(&model.Order{}).Where("orders.user_id = ?", userID)
+	return r.listPage(query, page, r.db.Preload("FoodBag").Preload("Store").Preload("Items.FoodBag"))
+}
+
+func (r *OrderRepository) GetByStoreID(storeID uint, page model.PageRequest) (*model.Page[model.Order], error) {
+	query := r.db.Model(&model.Order{}).Where("orders.store_id = ?", storeID)
+	return r.listPage(query, page, r.db.Preload("User").Preload("FoodBag").Preload("Store").Preload("Items.FoodBag"))
+}
+
+// listPage loads one page of the orders matched by query, with the
+// associations preloaded by load.
+func (r *OrderRepository) listPage(query *gorm.DB, page model.PageRequest, load *gorm.DB) (*model.Page[model.Order], error) {
+	ks, err := newKeyset(page, "created_at", "orders.id", orderSortKeys)
+	if err != nil {
+		return nil, err
+	}
+	total, err := countRows(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ks.Scan(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, next := ks.Trim(rows)
+
 	var orders []model.Order
-	err := r.db.Where("store_id = ?", storeID).Preload("User").Preload("FoodBag").Order("created_at desc").Find(&orders).Error
-	return orders, err
+	if len(rows) > 0 {
+		if err := load.Where("id IN ?", rowIDs(rows)).Find(&orders).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &model.Page[model.Order]{
+		Data:       inIDOrder(orders, rowIDs(rows), func(o *model.Order) uint { return o.ID }),
+		NextCursor: next,
+		Total:      total,
+	}, nil
 }
 
 func (r *OrderRepository) Update(order *model.Order) error {
@@ -51,15 +189,93 @@ func (r *OrderRepository) UpdateStatus(id uint, status model.OrderStatus) error
 	return r.db.Model(&model.Order{}).Where("id = ?", id).Update("status", status).Error
 }
 
-func (r *OrderRepository) GetByPickupCode(pickupCode string) (*model.Order, error) {
+// UpdateStatusFrom applies updates only while the order is still in the
+// expected status. It reports false when the order had already moved on.
+func (r *OrderRepository) UpdateStatusFrom(id uint, from model.OrderStatus, updates map[string]interface{}) (bool, error) {
+	result := r.db.Model(&model.Order{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// orderPickupEndSQL is when the last pickup window of an order's items
+// ends. Orders placed before items existed use their food bag's window.
+const orderPickupEndSQL = "COALESCE(" +
+	"(SELECT MAX(food_bags.pickup_time_end) FROM order_items " +
+	"JOIN food_bags ON food_bags.id = order_items.food_bag_id " +
+	"WHERE order_items.order_id = orders.id AND order_items.deleted_at IS NULL), " +
+	"(SELECT food_bags.pickup_time_end FROM food_bags WHERE food_bags.id = orders.food_bag_id))"
+
+// GetOpenByFoodBagID returns the open orders that include a food bag, with
+// their items.
+func (r *OrderRepository) GetOpenByFoodBagID(foodBagID uint) ([]model.Order, error) {
+	var orders []model.Order
+	err := r.db.Where("orders.status IN ?", openOrderStatuses).
+		Where("orders.food_bag_id = ? OR EXISTS (SELECT 1 FROM order_items WHERE order_items.order_id = orders.id AND order_items.food_bag_id = ? AND order_items.deleted_at IS NULL)", foodBagID, foodBagID).
+		Preload("Items").
+		Order("orders.id").
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetOpenPastPickup returns orders that are still open although the last
+// pickup window of their items ended before cutoff.
+func (r *OrderRepository) GetOpenPastPickup(cutoff time.Time, limit int) ([]model.Order, error) {
+	var orders []model.Order
+	err := r.db.Where("orders.status IN ? AND "+orderPickupEndSQL+" < ?", openOrderStatuses, cutoff).
+		Preload("Items").
+		Order("orders.id").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetByStoreAndPickupCode finds the order carrying a pickup code at a store.
+// Codes are only unique among a store's open orders, so an open order is
+// preferred over older closed ones that reused the same code.
+func (r *OrderRepository) GetByStoreAndPickupCode(storeID uint, pickupCode string) (*model.Order, error) {
 	var order model.Order
-	err := r.db.Where("pickup_code = ?", pickupCode).Preload("User").Preload("FoodBag").Preload("Store").First(&order).Error
+	err := r.db.Where("store_id = ? AND pickup_code = ?", storeID, pickupCode).
+		Preload("User").Preload("FoodBag").Preload("Store").Preload("Items.FoodBag").
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN status IN ? THEN 0 ELSE 1 END, created_at DESC",
+			Vars: []interface{}{openOrderStatuses},
+		}}).
+		First(&order).Error
 	if err != nil {
 		return nil, err
 	}
 	return &order, nil
 }
 
+// uniquePickupCode generates a pickup code that no other open order at the
+// store is using.
+func (r *OrderRepository) uniquePickupCode(storeID uint) (string, error) {
+	for i := 0; i < pickupCodeAttempts; i++ {
+		code := r.generatePickupCode()
+
+		var count int64
+		err := r.db.Model(&model.Order{}).
+			Where("store_id = ? AND pickup_code = ? AND status IN ?", storeID, code, openOrderStatuses).
+			Count(&count).Error
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+	return "", errors.New("could not generate a unique pickup code")
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a row that
+// breaks the named unique index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
 func (r *OrderRepository) generatePickupCode() string {
 	bytes := make([]byte, 3)
 	rand.Read(bytes)
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// sortKey is one way a list can be ordered. Expr is an SQL expression of
+// type SQLType; its placeholders are filled from Args. Rows with equal
+// values are ordered by the list's id column.
+type sortKey struct {
+	Expr    string
+	Args    []interface{}
+	SQLType string
+	Desc    bool
+}
+
+// sortKeys maps the names clients sort by to their keys.
+type sortKeys map[string]sortKey
+
+// pageCursor is the position after the last row of a page. Values are kept
+// as the database's text form of the sort value so they compare exactly.
+// AsOf pins the time that time-dependent sort keys, like a decaying price,
+// are worked out at.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+	AsOf  int64  `json:"t"`
+}
+
+// keysetRow is a row's id with the text form of its sort value.
+type keysetRow struct {
+	ID        uint
+	SortValue string
+}
+
+// keyset pages through a list by the value it is sorted on rather than by
+// offset, so pages stay stable while rows are added.
+type keyset struct {
+	sort     string
+	key      sortKey
+	desc     bool
+	idColumn string
+	limit    int
+	after    *pageCursor
+	asOf     time.Time
+}
+
+// newKeyset reads a page request against the sort keys of a list. keys is
+// built for a time so that keys depending on the current time can be
+// pinned to the time of the first page.
+func newKeyset(page model.PageRequest, defaultSort, idColumn string, keys func(asOf time.Time) sortKeys) (*keyset, error) {
+	ks := &keyset{idColumn: idColumn, limit: page.Limit, asOf: time.Now()}
+	if ks.limit <= 0 {
+		ks.limit = defaultPageLimit
+	}
+	if ks.limit > maxPageLimit {
+		ks.limit = maxPageLimit
+	}
+
+	ks.sort = page.Sort
+	if ks.sort == "" {
+		ks.sort = defaultSort
+	}
+
+	if page.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		var after pageCursor
+		if err := json.Unmarshal(raw, &after); err != nil || after.Sort != ks.sort {
+			return nil, ErrInvalidCursor
+		}
+		ks.after = &after
+		ks.asOf = time.Unix(0, after.AsOf)
+	}
+
+	name := strings.TrimPrefix(ks.sort, "-")
+	key, ok := keys(ks.asOf)[name]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+	ks.key = key
+	ks.desc = key.Desc != strings.HasPrefix(ks.sort, "-")
+	return ks, nil
+}
+
+// Scan selects the ids and sort values of the next page of query, with one
+// extra row to tell whether another page follows.
+func (ks *keyset) Scan(query *gorm.DB) ([]keysetRow, error) {
+	query = query.Session(&gorm.Session{}).Select(ks.idColumn+" AS id, CAST("+ks.key.Expr+" AS text) AS sort_value", ks.key.Args...)
+
+	if ks.after != nil {
+		op := ">"
+		if ks.desc {
+			op = "<"
+		}
+		value := "CAST(? AS " + ks.key.SQLType + ")"
+		vars := append([]interface{}{}, ks.key.Args...)
+		vars = append(vars, ks.after.Value)
+		vars = append(vars, ks.key.Args...)
+		vars = append(vars, ks.after.Value, ks.after.ID)
+		query = query.Where(clause.Expr{
+			SQL:  "(" + ks.key.Expr + " " + op + " " + value + " OR (" + ks.key.Expr + " = " + value + " AND " + ks.idColumn + " " + op + " ?))",
+			Vars: vars,
+		})
+	}
+
+	direction := " ASC"
+	if ks.desc {
+		direction = " DESC"
+	}
+	var rows []keysetRow
+	err := query.
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  ks.key.Expr + direction + ", " + ks.idColumn + direction,
+			Vars: ks.key.Args,
+		}}).
+		Limit(ks.limit + 1).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Trim cuts the extra row off a scan and returns the cursor for the page
+// after it, or "" when this is the last page.
+func (ks *keyset) Trim(rows []keysetRow) ([]keysetRow, string) {
+	if len(rows) <= ks.limit {
+		return rows, ""
+	}
+	rows = rows[:ks.limit]
+	last := rows[len(rows)-1]
+	raw, _ := json.Marshal(pageCursor{
+		Sort:  ks.sort,
+		Value: last.SortValue,
+		ID:    last.ID,
+		AsOf:  ks.asOf.UnixNano(),
+	})
+	return rows, base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// AsOf is the time time-dependent sort keys are worked out at.
+func (ks *keyset) AsOf() time.Time {
+	return ks.asOf
+}
+
+// rowIDs returns the ids of keyset rows in order.
+func rowIDs(rows []keysetRow) []uint {
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids
+}
+
+// countRows counts the rows of query, for a page's total.
+func countRows(query *gorm.DB) (*int64, error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// inIDOrder arranges items loaded by id in the order of ids, dropping ids
+// that were not found.
+func inIDOrder[T any](items []T, ids []uint, id func(*T) uint) []T {
+	byID := make(map[uint]*T, len(items))
+	for i := range items {
+		byID[id(&items[i])] = &items[i]
+	}
+	ordered := make([]T, 0, len(ids))
+	for _, itemID := range ids {
+		if item, ok := byID[itemID]; ok {
+			ordered = append(ordered, *item)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// openPaymentStatuses are the payment states that block a new attempt.
+var openPaymentStatuses = []model.PaymentStatus{
+	model.PaymentStatusPending,
+	model.PaymentStatusAuthorized,
+	model.PaymentStatusCaptured,
+	model.PaymentStatusPartiallyRefunded,
+	model.PaymentStatusRefunded,
+}
+
+// refundablePaymentStatuses are the payment states that still hold money.
+var refundablePaymentStatuses = []model.PaymentStatus{
+	model.PaymentStatusAuthorized,
+	model.PaymentStatusCaptured,
+	model.PaymentStatusPartiallyRefunded,
+}
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PaymentRepository) WithTx(tx *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: tx}
+}
+
+func (r *PaymentRepository) Create(payment *model.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *PaymentRepository) GetByID(id uint) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.First(&payment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) GetByProviderRef(provider, providerRef string) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.Where("provider = ? AND provider_ref = ?", provider, providerRef).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) GetByOrderID(orderID uint) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.db.Where("order_id = ?", orderID).Order("created_at asc, id asc").Find(&payments).Error
+	return payments, err
+}
+
+// GetRefundableForUpdate loads the order's payment that still holds money and
+// locks it until the surrounding transaction ends.
+func (r *PaymentRepository) GetRefundableForUpdate(orderID uint) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, refundablePaymentStatuses).
+		Order("id desc").
+		First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) GetByIDForUpdate(id uint) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// HasOpenPayment reports whether the order has a payment that has not failed.
+func (r *PaymentRepository) HasOpenPayment(orderID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Payment{}).Where("order_id = ? AND status IN ?", orderID, openPaymentStatuses).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *PaymentRepository) Update(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&model.Payment{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// UpdateStatusFrom applies updates only if the payment still has the given
+// status. It reports whether the row was changed.
+func (r *PaymentRepository) UpdateStatusFrom(id uint, from model.PaymentStatus, updates map[string]interface{}) (bool, error) {
+	result := r.db.Model(&model.Payment{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
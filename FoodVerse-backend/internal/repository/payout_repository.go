@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SettledOrder is a completed order waiting to be paid out. StoreFunded is
+// set when its promo code belonged to the store, which then bears the
+// discount.
+type SettledOrder struct {
+	OrderID        uint
+	StoreID        uint
+	Subtotal       float64
+	TotalPrice     float64
+	DiscountAmount float64
+	StoreFunded    bool
+	PickedUpAt     time.Time
+}
+
+// SettledRefund is a successful refund on a completed order waiting to be
+// taken off a payout.
+type SettledRefund struct {
+	RefundID   uint
+	OrderID    uint
+	StoreID    uint
+	Amount     float64
+	RefundedAt time.Time
+}
+
+// PayoutFilter narrows an admin payout listing. Zero fields are ignored.
+type PayoutFilter struct {
+	StoreID uint
+	Status  model.PayoutStatus
+	From    time.Time
+	To      time.Time
+}
+
+type PayoutRepository struct {
+	db *gorm.DB
+}
+
+func NewPayoutRepository(db *gorm.DB) *PayoutRepository {
+	return &PayoutRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PayoutRepository) WithTx(tx *gorm.DB) *PayoutRepository {
+	return &PayoutRepository{db: tx}
+}
+
+// Create inserts a payout together with its lines.
+func (r *PayoutRepository) Create(payout *model.Payout) error {
+	return r.db.Omit("Store").Create(payout).Error
+}
+
+func (r *PayoutRepository) GetByID(id uint) (*model.Payout, error) {
+	var payout model.Payout
+	err := r.db.Preload("Store").Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("occurred_at asc, id asc")
+	}).First(&payout, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+func (r *PayoutRepository) GetByStoreID(storeID uint, limit, offset int) ([]model.Payout, int64, error) {
+	var payouts []model.Payout
+	var total int64
+
+	query := r.db.Model(&model.Payout{}).Where("store_id = ?", storeID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("period_start desc").Limit(limit).Offset(offset).Find(&payouts).Error
+	return payouts, total, err
+}
+
+// List returns payouts newest period first. A limit of -1 returns them all.
+func (r *PayoutRepository) List(filter PayoutFilter, limit, offset int) ([]model.Payout, int64, error) {
+	var payouts []model.Payout
+	var total int64
+
+	query := r.db.Model(&model.Payout{})
+	if filter.StoreID != 0 {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("period_start >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("period_start < ?", filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Store").Order("period_start desc, store_id asc").Limit(limit).Offset(offset).Find(&payouts).Error
+	return payouts, total, err
+}
+
+// ExistsForPeriod reports whether the store already has a payout for the
+// period starting at periodStart.
+func (r *PayoutRepository) ExistsForPeriod(storeID uint, periodStart time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Payout{}).Where("store_id = ? AND period_start = ?", storeID, periodStart).Count(&count).Error
+	return count > 0, err
+}
+
+// UnsettledOrders returns completed orders picked up before cutoff that are
+// on no payout yet. storeID 0 means every store.
+func (r *PayoutRepository) UnsettledOrders(storeID uint, cutoff time.Time) ([]SettledOrder, error) {
+	var rows []SettledOrder
+	query := r.db.Table("orders").
+		Select("orders.id AS order_id, orders.store_id, orders.subtotal, orders.total_price, orders.discount_amount, promotions.store_id IS NOT NULL AS store_funded, orders.picked_up_at").
+		Joins("LEFT JOIN promotions ON promotions.id = orders.promotion_id").
+		Where("orders.deleted_at IS NULL AND orders.status = ? AND orders.picked_up_at < ?", model.OrderStatusCompleted, cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM payout_lines WHERE payout_lines.order_id = orders.id AND payout_lines.refund_id = 0 AND payout_lines.deleted_at IS NULL)")
+	if storeID != 0 {
+		query = query.Where("orders.store_id = ?", storeID)
+	}
+	err := query.Order("orders.picked_up_at asc, orders.id asc").Scan(&rows).Error
+	return rows, err
+}
+
+// UnsettledRefunds returns successful refunds made before cutoff on orders
+// picked up before cutoff that are on no payout yet. storeID 0 means every
+// store.
+func (r *PayoutRepository) UnsettledRefunds(storeID uint, cutoff time.Time) ([]SettledRefund, error) {
+	var rows []SettledRefund
+	query := r.db.Table("refunds").
+		Select("refunds.id AS refund_id, refunds.order_id, orders.store_id, refunds.amount, refunds.refunded_at").
+		Joins("JOIN orders ON orders.id = refunds.order_id").
+		Where("refunds.deleted_at IS NULL AND refunds.status = ? AND refunds.refunded_at < ?", model.RefundStatusSucceeded, cutoff).
+		Where("orders.status = ? AND orders.picked_up_at < ?", model.OrderStatusCompleted, cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM payout_lines WHERE payout_lines.refund_id = refunds.id AND payout_lines.deleted_at IS NULL)")
+	if storeID != 0 {
+		query = query.Where("orders.store_id = ?", storeID)
+	}
+	err := query.Order("refunds.refunded_at asc, refunds.id asc").Scan(&rows).Error
+	return rows, err
+}
+
+// GetCarriedForward returns the store's negative balances that no later
+// payout has absorbed yet. With forUpdate the rows stay locked until the
+// surrounding transaction ends.
+func (r *PayoutRepository) GetCarriedForward(storeID uint, forUpdate bool) ([]model.Payout, error) {
+	var payouts []model.Payout
+	query := r.db
+	if forUpdate {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	err := query.Where("store_id = ? AND status = ? AND carried_into_id IS NULL", storeID, model.PayoutStatusCarriedForward).
+		Order("period_start asc").Find(&payouts).Error
+	return payouts, err
+}
+
+func (r *PayoutRepository) MarkCarriedInto(ids []uint, payoutID uint) error {
+	return r.db.Model(&model.Payout{}).Where("id IN ?", ids).Update("carried_into_id", payoutID).Error
+}
+
+// UpdateStatusFrom changes a payout only if it still has the expected status.
+// It reports false when another process got there first.
+func (r *PayoutRepository) UpdateStatusFrom(id uint, from model.PayoutStatus, updates map[string]interface{}) (bool, error) {
+	result := r.db.Model(&model.Payout{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PromotionRepository struct {
+	db *gorm.DB
+}
+
+func NewPromotionRepository(db *gorm.DB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PromotionRepository) WithTx(tx *gorm.DB) *PromotionRepository {
+	return &PromotionRepository{db: tx}
+}
+
+func (r *PromotionRepository) Create(promotion *model.Promotion) error {
+	return r.db.Create(promotion).Error
+}
+
+func (r *PromotionRepository) Update(promotion *model.Promotion) error {
+	return r.db.Save(promotion).Error
+}
+
+func (r *PromotionRepository) GetByID(id uint) (*model.Promotion, error) {
+	var promotion model.Promotion
+	err := r.db.Preload("Store").First(&promotion, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// GetByCode looks a code up case-insensitively. With forUpdate the row stays
+// locked until the surrounding transaction ends.
+func (r *PromotionRepository) GetByCode(code string, forUpdate bool) (*model.Promotion, error) {
+	var promotion model.Promotion
+	query := r.db
+	if forUpdate {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	err := query.Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&promotion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// List returns promotions newest first. Non-empty storeIDs limits the result
+// to promotions scoped to those stores.
+func (r *PromotionRepository) List(storeIDs []uint, limit, offset int) ([]model.Promotion, int64, error) {
+	var promotions []model.Promotion
+	var total int64
+
+	query := r.db.Model(&model.Promotion{})
+	if storeIDs != nil {
+		query = query.Where("store_id IN ?", storeIDs)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Store").Order("created_at desc").Limit(limit).Offset(offset).Find(&promotions).Error
+	return promotions, total, err
+}
+
+func (r *PromotionRepository) AdjustRedemptionCount(id uint, delta int) error {
+	return r.db.Model(&model.Promotion{}).Where("id = ?", id).
+		Update("redemption_count", gorm.Expr("redemption_count + ?", delta)).Error
+}
+
+func (r *PromotionRepository) CreateRedemption(redemption *model.PromotionRedemption) error {
+	return r.db.Create(redemption).Error
+}
+
+func (r *PromotionRepository) CountUserRedemptions(promotionID, userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.PromotionRedemption{}).
+		Where("promotion_id = ? AND user_id = ? AND status = ?", promotionID, userID, model.PromotionRedemptionApplied).
+		Count(&count).Error
+	return count, err
+}
+
+// GetAppliedRedemption returns the order's redemption that still counts.
+func (r *PromotionRepository) GetAppliedRedemption(orderID uint) (*model.PromotionRedemption, error) {
+	var redemption model.PromotionRedemption
+	err := r.db.Where("order_id = ? AND status = ?", orderID, model.PromotionRedemptionApplied).First(&redemption).Error
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+func (r *PromotionRepository) UpdateRedemptionStatus(id uint, status model.PromotionRedemptionStatus) error {
+	return r.db.Model(&model.PromotionRedemption{}).Where("id = ?", id).Update("status", status).Error
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type RefundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) *RefundRepository {
+	return &RefundRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *RefundRepository) WithTx(tx *gorm.DB) *RefundRepository {
+	return &RefundRepository{db: tx}
+}
+
+func (r *RefundRepository) Create(refund *model.Refund) error {
+	return r.db.Create(refund).Error
+}
+
+func (r *RefundRepository) GetByID(id uint) (*model.Refund, error) {
+	var refund model.Refund
+	err := r.db.First(&refund, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *RefundRepository) GetByOrderID(orderID uint) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.Where("order_id = ?", orderID).Order("created_at asc, id asc").Find(&refunds).Error
+	return refunds, err
+}
+
+// GetDue returns pending refunds whose next attempt is due at now, oldest
+// first.
+func (r *RefundRepository) GetDue(now time.Time, limit int) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", model.RefundStatusPending, now).
+		Order("next_attempt_at asc, id asc").
+		Limit(limit).
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// Claim takes a pending refund that is due at now for one attempt, keeping
+// other senders off it until leaseUntil. It reports whether the refund was
+// claimed.
+func (r *RefundRepository) Claim(id uint, now, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&model.Refund{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, model.RefundStatusPending, now).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// List returns refunds newest first, optionally filtered by status.
+func (r *RefundRepository) List(status model.RefundStatus, limit, offset int) ([]model.Refund, int64, error) {
+	var refunds []model.Refund
+	var total int64
+
+	query := r.db.Model(&model.Refund{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&refunds).Error
+	return refunds, total, err
+}
+
+func (r *RefundRepository) Update(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&model.Refund{}).Where("id = ?", id).Updates(updates).Error
+}
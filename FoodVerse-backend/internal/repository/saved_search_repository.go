@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// savedSearchHaversineSQL is the distance from each saved search's point
+// to a store, for matching many saved searches against one bag.
+var savedSearchHaversineSQL = strings.Replace(
+	strings.Replace(haversineSQL, "?", "saved_searches.latitude", 2), "?", "saved_searches.longitude", 1)
+
+type SavedSearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSavedSearchRepository(db *gorm.DB) *SavedSearchRepository {
+	return &SavedSearchRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *SavedSearchRepository) WithTx(tx *gorm.DB) *SavedSearchRepository {
+	return &SavedSearchRepository{db: tx}
+}
+
+// prepareSavedSearch works out the columns derived from a saved search's criteria.
+func prepareSavedSearch(savedSearch *model.SavedSearch) {
+	if savedSearch.Radius <= 0 {
+		savedSearch.Radius = defaultNearbyRadiusKm
+	}
+	savedSearch.TSQuery = prefixTSQuery(savedSearch.Query)
+	savedSearch.MinLatitude, savedSearch.MaxLatitude, savedSearch.MinLongitude, savedSearch.MaxLongitude, _ =
+		boundingBox(savedSearch.Latitude, savedSearch.Longitude, savedSearch.Radius)
+}
+
+func (r *SavedSearchRepository) Create(savedSearch *model.SavedSearch) error {
+	prepareSavedSearch(savedSearch)
+	return r.db.Create(savedSearch).Error
+}
+
+func (r *SavedSearchRepository) GetByID(id uint) (*model.SavedSearch, error) {
+	var savedSearch model.SavedSearch
+	err := r.db.First(&savedSearch, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &savedSearch, nil
+}
+
+func (r *SavedSearchRepository) GetByUserID(userID uint) ([]model.SavedSearch, error) {
+	var savedSearches []model.SavedSearch
+	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Find(&savedSearches).Error
+	return savedSearches, err
+}
+
+func (r *SavedSearchRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.SavedSearch{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// Update saves a saved search's criteria. The time it last alerted is left
+// to ClaimAlert, so an edit does not undo a concurrent claim.
+func (r *SavedSearchRepository) Update(savedSearch *model.SavedSearch) error {
+	prepareSavedSearch(savedSearch)
+	return r.db.Omit("last_notified_at").Save(savedSearch).Error
+}
+
+func (r *SavedSearchRepository) Delete(id uint) error {
+	return r.db.Delete(&model.SavedSearch{}, id).Error
+}
+
+// MatchFoodBag returns the saved searches with alerts on that a food bag on
+// sale matches and that have not alerted since throttledSince. A bag
+// matches on location, category, price at now and, for searches with a
+// query, the words of its own or its store's text. Searches of the store's
+// owner and searches already told about the bag are left out. The bounding
+// box narrows the saved searches by index before distances are worked out.
+func (r *SavedSearchRepository) MatchFoodBag(foodBagID uint, now, throttledSince time.Time) ([]model.SavedSearch, error) {
+	var savedSearches []model.SavedSearch
+	err := r.db.Model(&model.SavedSearch{}).
+		Select("saved_searches.*").
+		Joins("JOIN food_bags ON food_bags.id = ? AND food_bags.deleted_at IS NULL", foodBagID).
+		Joins("JOIN stores ON stores.id = food_bags.store_id AND stores.deleted_at IS NULL").
+		Where("food_bags.is_active = ? AND stores.is_active = ? AND food_bags.quantity_left > 0 AND food_bags.pickup_time_end > ?", true, true, now).
+		Where("saved_searches.alerts_enabled = ? AND saved_searches.user_id <> stores.owner_id", true).
+		Where("saved_searches.last_notified_at IS NULL OR saved_searches.last_notified_at <= ?", throttledSince).
+		Where("stores.latitude BETWEEN saved_searches.min_latitude AND saved_searches.max_latitude").
+		Where("stores.longitude BETWEEN saved_searches.min_longitude AND saved_searches.max_longitude").
+		Where(savedSearchHaversineSQL+" <= saved_searches.radius").
+		Where("saved_searches.category = '' OR saved_searches.category = food_bags.category").
+		Where("saved_searches.min_price <= 0 OR "+currentPriceSQL+" >= saved_searches.min_price", now, now).
+		Where("saved_searches.max_price <= 0 OR "+currentPriceSQL+" <= saved_searches.max_price", now, now).
+		Where("saved_searches.ts_query = '' OR food_bags.search_vector @@ to_tsquery('simple', saved_searches.ts_query) " +
+			"OR stores.search_vector @@ to_tsquery('simple', saved_searches.ts_query)").
+		Where("NOT EXISTS (SELECT 1 FROM notifications WHERE notifications.saved_search_id = saved_searches.id " +
+			"AND notifications.food_bag_id = food_bags.id)").
+		Find(&savedSearches).Error
+	return savedSearches, err
+}
+
+// ClaimAlert records that a saved search alerts at now, unless it already
+// has since throttledSince. It reports false when another alert got there
+// first. The owner's user row stays locked until the transaction ends, so
+// alerts for one user's saved searches are counted against the hourly cap
+// one at a time.
+func (r *SavedSearchRepository) ClaimAlert(id, userID uint, now, throttledSince time.Time) (bool, error) {
+	var user model.User
+	if err := r.db.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+		return false, err
+	}
+
+	result := r.db.Model(&model.SavedSearch{}).
+		Where("id = ? AND (last_notified_at IS NULL OR last_notified_at <= ?)", id, throttledSince).
+		Update("last_notified_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSuggestionLimit = 8
+	maxSuggestionLimit     = 20
+)
+
+// suggestionPopularityWindow is how far back orders count towards the
+// popularity of a suggestion.
+const suggestionPopularityWindow = 30 * 24 * time.Hour
+
+// suggestionHaversineSQL is haversineSQL against the location copied into
+// the suggestion index.
+var suggestionHaversineSQL = strings.ReplaceAll(haversineSQL, "stores.", "search_suggestions.")
+
+// suggestionUpsert finishes the inserts below, so a row written by a
+// concurrent sync is overwritten rather than failing the save.
+const suggestionUpsert = " ON CONFLICT (type, ref_id) DO UPDATE SET " +
+	"updated_at = EXCLUDED.updated_at, store_id = EXCLUDED.store_id, label = EXCLUDED.label, term = EXCLUDED.term, " +
+	"category = EXCLUDED.category, category_term = EXCLUDED.category_term, " +
+	"latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude, " +
+	"is_available = EXCLUDED.is_available, available_until = EXCLUDED.available_until, popularity = EXCLUDED.popularity"
+
+// storeSuggestionsSQL copies stores matching a condition, appended after
+// it, into the index. Popularity counts the store's orders. Its
+// placeholder is the time orders count from.
+const storeSuggestionsSQL = "INSERT INTO search_suggestions " +
+	"(updated_at, type, ref_id, store_id, label, term, category, category_term, latitude, longitude, is_available, available_until, popularity) " +
+	"SELECT now(), 'store', stores.id, stores.id, stores.name, lower(stores.name), stores.category, lower(stores.category), " +
+	"stores.latitude, stores.longitude, stores.is_active, NULL, " +
+	"(SELECT count(*) FROM orders WHERE orders.store_id = stores.id AND orders.status <> 'cancelled' " +
+	"AND orders.deleted_at IS NULL AND orders.created_at > ?) " +
+	"FROM stores WHERE stores.deleted_at IS NULL AND "
+
+// foodBagSuggestionsSQL copies bags still to be picked up and matching a
+// condition, appended after it, into the index. A store's bags with the
+// same title, such as the daily bags of a schedule, share their popularity.
+// Its placeholders are the time orders count from and the current time.
+const foodBagSuggestionsSQL = "INSERT INTO search_suggestions " +
+	"(updated_at, type, ref_id, store_id, label, term, category, category_term, latitude, longitude, is_available, available_until, popularity) " +
+	"SELECT now(), 'bag', food_bags.id, food_bags.store_id, food_bags.title, lower(food_bags.title), " +
+	"food_bags.category, lower(food_bags.category), stores.latitude, stores.longitude, " +
+	"food_bags.is_active AND stores.is_active AND food_bags.quantity_left > 0, food_bags.pickup_time_end, " +
+	"(SELECT count(DISTINCT orders.id) FROM orders " +
+	"LEFT JOIN order_items ON order_items.order_id = orders.id AND order_items.deleted_at IS NULL " +
+	"JOIN food_bags AS ordered ON ordered.id = COALESCE(order_items.food_bag_id, orders.food_bag_id) " +
+	"WHERE ordered.store_id = food_bags.store_id AND lower(ordered.title) = lower(food_bags.title) " +
+	"AND orders.status <> 'cancelled' AND orders.deleted_at IS NULL AND orders.created_at > ?) " +
+	"FROM food_bags JOIN stores ON stores.id = food_bags.store_id AND stores.deleted_at IS NULL " +
+	"WHERE food_bags.deleted_at IS NULL AND food_bags.pickup_time_end > ? AND "
+
+// SearchSuggestionRepository serves as-you-type suggestions from the
+// search_suggestions prefix index. The store and food bag repositories
+// rewrite a row whenever they save its store or bag; Refresh rebuilds the
+// whole index to pick up new orders and bags that sold out or expired.
+type SearchSuggestionRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchSuggestionRepository(db *gorm.DB) *SearchSuggestionRepository {
+	return &SearchSuggestionRepository{db: db}
+}
+
+// Refresh rebuilds the index from the stores and bags.
+func (r *SearchSuggestionRepository) Refresh() error {
+	since, now := time.Now().Add(-suggestionPopularityWindow), time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM search_suggestions").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(storeSuggestionsSQL+"TRUE"+suggestionUpsert, since).Error; err != nil {
+			return err
+		}
+		return tx.Exec(foodBagSuggestionsSQL+"TRUE"+suggestionUpsert, since, now).Error
+	})
+}
+
+// suggestionPattern returns the LIKE pattern for terms starting with what
+// the user has typed, lower-cased like the stored terms and with runs of
+// spaces collapsed. LIKE wildcards typed by the user match literally. It
+// reports false when nothing but spaces was typed.
+func suggestionPattern(query string) (string, bool) {
+	prefix := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	if prefix == "" {
+		return "", false
+	}
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%", true
+}
+
+// suggestionRow is a store or bag suggestion with its distance.
+type suggestionRow struct {
+	Type       model.SuggestionType
+	RefID      uint
+	StoreID    uint
+	Label      string
+	Term       string
+	Popularity int64
+	Distance   float64
+}
+
+// Suggest returns the stores, bags and categories near a point whose name,
+// title or category starts with what the user has typed, most popular
+// first. A store's bags sharing a title are suggested once, and a category
+// is as popular as its most popular store or bag nearby.
+func (r *SearchSuggestionRepository) Suggest(req model.SuggestRequest) ([]model.SuggestionResponse, error) {
+	suggestions := []model.SuggestionResponse{}
+	pattern, ok := suggestionPattern(req.Query)
+	if !ok {
+		return suggestions, nil
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSuggestionLimit
+	}
+	if limit > maxSuggestionLimit {
+		limit = maxSuggestionLimit
+	}
+	radius := req.Radius
+	if radius <= 0 {
+		radius = defaultNearbyRadiusKm
+	}
+
+	distance := suggestionHaversineSQL
+	distanceArgs := []interface{}{req.Latitude, req.Latitude, req.Longitude}
+	minLat, maxLat, minLon, maxLon, wraps := boundingBox(req.Latitude, req.Longitude, radius)
+	nearby := func() *gorm.DB {
+		query := r.db.Model(&model.SearchSuggestion{}).
+			Where("search_suggestions.is_available AND (search_suggestions.available_until IS NULL OR search_suggestions.available_until > ?)", time.Now()).
+			Where("search_suggestions.latitude BETWEEN ? AND ?", minLat, maxLat).
+			Where(distance+" <= ?", append(distanceArgs, radius)...)
+		if !wraps {
+			query = query.Where("search_suggestions.longitude BETWEEN ? AND ?", minLon, maxLon)
+		}
+		return query
+	}
+
+	// Bags repeat under one title, so more rows than needed are read to
+	// fill the limit once they are folded together.
+	var rows []suggestionRow
+	err := nearby().
+		Select("type, ref_id, store_id, label, term, popularity, "+distance+" AS distance", distanceArgs...).
+		Where("search_suggestions.term LIKE ?", pattern).
+		Order("popularity DESC, distance, ref_id").
+		Limit(limit * 3).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []suggestionRow
+	err = nearby().
+		Select("MIN(category) AS label, MAX(popularity) AS popularity").
+		Where("search_suggestions.category_term LIKE ? AND search_suggestions.category_term <> ''", pattern).
+		Group("category_term").
+		Order("popularity DESC, label").
+		Limit(limit).
+		Scan(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		suggestions = append(suggestions, model.SuggestionResponse{
+			Type:       model.SuggestionTypeCategory,
+			Text:       category.Label,
+			Popularity: category.Popularity,
+		})
+	}
+	seen := map[suggestionRow]bool{}
+	for _, row := range rows {
+		if row.Type == model.SuggestionTypeBag {
+			key := suggestionRow{Type: row.Type, StoreID: row.StoreID, Term: row.Term}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		suggestions = append(suggestions, model.SuggestionResponse{
+			Type:       row.Type,
+			Text:       row.Label,
+			ID:         row.RefID,
+			StoreID:    row.StoreID,
+			Distance:   row.Distance,
+			Popularity: row.Popularity,
+		})
+	}
+
+	// Categories lead ties, as the broadest suggestion, then stores.
+	typeOrder := map[model.SuggestionType]int{
+		model.SuggestionTypeCategory: 0,
+		model.SuggestionTypeStore:    1,
+		model.SuggestionTypeBag:      2,
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Popularity != suggestions[j].Popularity {
+			return suggestions[i].Popularity > suggestions[j].Popularity
+		}
+		return typeOrder[suggestions[i].Type] < typeOrder[suggestions[j].Type]
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// syncStoreSuggestions rewrites the index rows of stores and their bags.
+// Rows of deleted stores are removed.
+func syncStoreSuggestions(db *gorm.DB, storeIDs ...uint) error {
+	since, now := time.Now().Add(-suggestionPopularityWindow), time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("store_id IN ?", storeIDs).Delete(&model.SearchSuggestion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(storeSuggestionsSQL+"stores.id IN ?"+suggestionUpsert, since, storeIDs).Error; err != nil {
+			return err
+		}
+		return tx.Exec(foodBagSuggestionsSQL+"food_bags.store_id IN ?"+suggestionUpsert, since, now, storeIDs).Error
+	})
+}
+
+// syncFoodBagSuggestions rewrites the index rows of bags. Rows of deleted
+// and ended bags are removed.
+func syncFoodBagSuggestions(db *gorm.DB, foodBagIDs ...uint) error {
+	since, now := time.Now().Add(-suggestionPopularityWindow), time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("type = ? AND ref_id IN ?", model.SuggestionTypeBag, foodBagIDs).Delete(&model.SearchSuggestion{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(foodBagSuggestionsSQL+"food_bags.id IN ?"+suggestionUpsert, since, now, foodBagIDs).Error
+	})
+}
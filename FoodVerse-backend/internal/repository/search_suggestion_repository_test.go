@@ -0,0 +1,22 @@
+package repository
+
+import "testing"
+
+func TestSuggestionPattern(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		ok    bool
+	}{
+		{"  \t ", "", false},
+		{" green   GROCERY\tstore ", "green grocery store%", true},
+		{"ÉCLAIR", "éclair%", true},
+		{`50%_off\now`, `50\%\_off\\now%`, true},
+	}
+	for _, tt := range tests {
+		got, ok := suggestionPattern(tt.query)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("suggestionPattern(%q) = %q, %v, want %q, %v", tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,111 +1,445 @@
 package repository
 
 import (
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/FoodVerse/FoodVerse-backend/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type StoreRepository struct {
-	db *gorm.DB
+	db   *gorm.DB
+	geo  *GeoSearch
+	text *TextSearch
 }
 
-func NewStoreRepository(db *gorm.DB) *StoreRepository {
-	return &StoreRepository{db: db}
+func NewStoreRepository(db *gorm.DB, geo *GeoSearch, text *TextSearch) *StoreRepository {
+	return &StoreRepository{db: db, geo: geo, text: text}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *StoreRepository) WithTx(tx *gorm.DB) *StoreRepository {
+	return &StoreRepository{db: tx, geo: r.geo, text: r.text}
 }
 
 func (r *StoreRepository) Create(store *model.Store) error {
-	return r.db.Create(store).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(store).Error; err != nil {
+			return err
+		}
+		return syncStoreSuggestions(tx, store.ID)
+	})
 }
 
 func (r *StoreRepository) GetByID(id uint) (*model.Store, error) {
 	var store model.Store
-	err := r.db.Preload("Owner").Preload("FoodBags").First(&store, id).Error
+	err := preloadHours(r.db, "").Preload("Owner").Preload("FoodBags").First(&store, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &store, nil
 }
 
+// LockByID locks a store row, deleted or not, until the surrounding
+// transaction ends.
+func (r *StoreRepository) LockByID(id uint) error {
+	var store model.Store
+	return r.db.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&store, id).Error
+}
+
 func (r *StoreRepository) GetByOwnerID(ownerID uint) ([]model.Store, error) {
 	var stores []model.Store
-	err := r.db.Where("owner_id = ?", ownerID).Preload("FoodBags").Find(&stores).Error
+	err := preloadHours(r.db, "").Where("owner_id = ?", ownerID).Preload("FoodBags").Find(&stores).Error
 	return stores, err
 }
 
+// Update saves the store's own columns. Opening hours and exceptions are
+// changed through their own methods.
 func (r *StoreRepository) Update(store *model.Store) error {
-	return r.db.Save(store).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(store).Error; err != nil {
+			return err
+		}
+		return syncStoreSuggestions(tx, store.ID)
+	})
+}
+
+// ReplaceOpeningHours swaps the store's weekly schedule for hours.
+func (r *StoreRepository) ReplaceOpeningHours(storeID uint, hours []model.StoreOpeningHours) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("store_id = ?", storeID).Delete(&model.StoreOpeningHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		for i := range hours {
+			hours[i].StoreID = storeID
+		}
+		return tx.Create(&hours).Error
+	})
+}
+
+// GetHoursExceptions lists the store's exceptions on or after a local date.
+func (r *StoreRepository) GetHoursExceptions(storeID uint, from string) ([]model.StoreHoursException, error) {
+	var exceptions []model.StoreHoursException
+	err := r.db.Where("store_id = ? AND date >= ?", storeID, from).Order("date").Find(&exceptions).Error
+	return exceptions, err
+}
+
+// SaveHoursException creates the exception for its date or replaces the one
+// already there.
+func (r *StoreRepository) SaveHoursException(exception *model.StoreHoursException) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "store_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"is_closed", "opens", "closes", "reason"}),
+	}).Create(exception).Error
+}
+
+// DeleteHoursException removes one of the store's exceptions. It reports
+// false when the store has no such exception.
+func (r *StoreRepository) DeleteHoursException(storeID, id uint) (bool, error) {
+	result := r.db.Where("id = ? AND store_id = ?", id, storeID).Delete(&model.StoreHoursException{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
 }
 
 func (r *StoreRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Store{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.Store{}, id).Error; err != nil {
+			return err
+		}
+		return syncStoreSuggestions(tx, id)
+	})
 }
 
-func (r *StoreRepository) SearchNearby(req model.StoreSearchRequest) ([]model.StoreResponse, error) {
-	var stores []model.Store
+// storeSortKeys are the ways nearby store searches can be sorted. Nearest
+// first is the default.
+func storeSortKeys(time.Time) sortKeys {
+	return sortKeys{
+		"distance": {Expr: "nearby.distance", SQLType: "float8"},
+		"rating":   {Expr: "stores.rating", SQLType: "real", Desc: true},
+	}
+}
+
+// storeHighlightColumns are the store columns search results highlight.
+var storeHighlightColumns = []string{"name", "category", "description"}
+
+// SearchNearby returns a page of the active stores within the requested
+// radius. Distances, ordering and the limit are worked out in the database.
+// With a query, stores match on their own text or on the bags they have on
+// sale, and are ranked by relevance weighed against distance. The open-now
+// filter is checked in the database too, at the time the search started.
+func (r *StoreRepository) SearchNearby(req model.StoreSearchRequest) (*model.Page[model.StoreResponse], error) {
 	radius := req.Radius
 	if radius <= 0 {
-		radius = 5.0 // default 5km
+		radius = defaultNearbyRadiusKm
 	}
 
-	query := r.db.Where("is_active = ?", true)
+	candidates := r.db.Model(&model.Store{}).Where("stores.is_active = ?", true)
 
 	// Filter by category if provided
 	if req.Category != "" {
-		query = query.Where("category = ?", req.Category)
+		candidates = candidates.Where("stores.category = ?", req.Category)
 	}
 
-	// Filter by name if query provided
-	if req.Query != "" {
-		query = query.Where("name ILIKE ?", "%"+req.Query+"%")
+	keys, defaultSort := storeSortKeys, "distance"
+	text, hasQuery := r.text.parseTextQuery(req.Query)
+	if hasQuery {
+		storeMatch, storeMatchArgs := r.text.Match("stores.search_vector", "stores.name", text)
+		bagMatch, bagMatchArgs := r.text.Match("food_bags.search_vector", "food_bags.title", text)
+		candidates = candidates.Where(storeMatch+" OR EXISTS (SELECT 1 FROM food_bags "+
+			"WHERE food_bags.store_id = stores.id AND food_bags.is_active AND food_bags.deleted_at IS NULL AND "+bagMatch+")",
+			append(storeMatchArgs, bagMatchArgs...)...)
+
+		// A store matched through its bags is as relevant as its best bag.
+		storeRelevance, storeArgs := r.text.Relevance("stores.search_vector", "stores.name", text)
+		bagRelevance, bagArgs := r.text.Relevance("food_bags.search_vector", "food_bags.title", text)
+		relevance, args := r.text.Blend("GREATEST("+storeRelevance+", COALESCE((SELECT MAX("+bagRelevance+") FROM food_bags "+
+			"WHERE food_bags.store_id = stores.id AND food_bags.is_active AND food_bags.deleted_at IS NULL), 0))",
+			append(storeArgs, bagArgs...), radius)
+		keys = func(asOf time.Time) sortKeys {
+			keys := storeSortKeys(asOf)
+			keys["relevance"] = sortKey{Expr: relevance, Args: args, SQLType: "float8", Desc: true}
+			return keys
+		}
+		defaultSort = "relevance"
 	}
 
-	err := query.Find(&stores).Error
+	ks, err := newKeyset(req.PageRequest, defaultSort, "stores.id", keys)
 	if err != nil {
 		return nil, err
 	}
 
-	var responses []model.StoreResponse
-	for _, store := range stores {
-		distance := r.calculateDistance(req.Latitude, req.Longitude, store.Latitude, store.Longitude)
-		if distance <= radius {
-			response := model.StoreResponse{
-				ID:          store.ID,
-				Name:        store.Name,
-				Description: store.Description,
-				Address:     store.Address,
-				Latitude:    store.Latitude,
-				Longitude:   store.Longitude,
-				Phone:       store.Phone,
-				Email:       store.Email,
-				Category:    store.Category,
-				ImageURL:    store.ImageURL,
-				Rating:      store.Rating,
-				Distance:    distance,
-				CreatedAt:   store.CreatedAt,
-				UpdatedAt:   store.UpdatedAt,
+	if req.OpenNow {
+		candidates = candidates.Where(storeOpenAtSQL, ks.AsOf())
+	}
+
+	query := r.db.Model(&model.Store{}).
+		Joins("JOIN (?) AS nearby ON nearby.id = stores.id", r.geo.Nearby(candidates, req.Latitude, req.Longitude, radius))
+
+	total, err := countRows(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ks.Scan(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, next := ks.Trim(rows)
+
+	var stores []model.Store
+	highlights := map[uint]map[string]string{}
+	if len(rows) > 0 {
+		if err := preloadHours(r.db, "").Where("id IN ?", rowIDs(rows)).Find(&stores).Error; err != nil {
+			return nil, err
+		}
+		if hasQuery {
+			if highlights, err = r.text.Highlights(r.db, "stores", rowIDs(rows), storeHighlightColumns, text); err != nil {
+				return nil, err
 			}
-			responses = append(responses, response)
 		}
 	}
+	stores = inIDOrder(stores, rowIDs(rows), func(s *model.Store) uint { return s.ID })
+
+	now := time.Now()
+	responses := make([]model.StoreResponse, 0, len(stores))
+	for _, store := range stores {
+		responses = append(responses, model.StoreResponse{
+			ID:          store.ID,
+			Name:        store.Name,
+			Description: store.Description,
+			Address:     store.Address,
+			Latitude:    store.Latitude,
+			Longitude:   store.Longitude,
+			Phone:       store.Phone,
+			Email:       store.Email,
+			Category:    store.Category,
+			ImageURL:    store.ImageURL,
+			Rating:      store.Rating,
+			Distance:    haversineKm(req.Latitude, req.Longitude, store.Latitude, store.Longitude),
+			Timezone:    store.Timezone,
+			IsOpenNow:   store.IsOpenAt(now),
+			CreatedAt:   store.CreatedAt,
+			UpdatedAt:   store.UpdatedAt,
+			Highlights:  highlights[store.ID],
+		})
+	}
+
+	return &model.Page[model.StoreResponse]{Data: responses, NextCursor: next, Total: total}, nil
+}
+
+// storeOpenAtSQL matches the stores that are open at a time, the same way
+// as Store.IsOpenAt: stores without weekly hours are always open, an
+// exception replaces the weekly hours of its local date, and hours that
+// close at or before they open run into the next day, so the day before
+// is checked too. Its placeholder is the time. Malformed times, which the
+// API never stores, are skipped rather than failing the query.
+const storeOpenAtSQL = `(NOT EXISTS (SELECT 1 FROM store_opening_hours WHERE store_opening_hours.store_id = stores.id) OR EXISTS (
+	SELECT 1
+	FROM (SELECT CAST(? AS timestamptz) AT TIME ZONE COALESCE(NULLIF(stores.timezone, ''), 'UTC') AS local_at) AS store_time
+	CROSS JOIN LATERAL (VALUES (CAST(store_time.local_at AS date) - 1), (CAST(store_time.local_at AS date))) AS days(day)
+	LEFT JOIN store_hours_exceptions AS hours_exception
+		ON hours_exception.store_id = stores.id AND hours_exception.date = to_char(days.day, 'YYYY-MM-DD')
+	CROSS JOIN LATERAL (
+		SELECT hours_exception.opens, hours_exception.closes WHERE hours_exception.id IS NOT NULL AND NOT hours_exception.is_closed
+		UNION ALL
+		SELECT hours.opens, hours.closes FROM store_opening_hours AS hours
+		WHERE hours_exception.id IS NULL AND hours.store_id = stores.id AND hours.weekday = EXTRACT(DOW FROM days.day)
+	) AS clock
+	CROSS JOIN LATERAL (SELECT
+		CASE WHEN clock.opens ~ '^([01]{0,1}[0-9]|2[0-3]):[0-5][0-9]$' THEN CAST(clock.opens AS time) END AS opens,
+		CASE WHEN clock.closes ~ '^([01]{0,1}[0-9]|2[0-3]):[0-5][0-9]$' THEN CAST(clock.closes AS time) END AS closes
+	) AS parsed
+	WHERE parsed.opens IS NOT NULL AND parsed.closes IS NOT NULL
+		AND days.day + parsed.opens <= store_time.local_at
+		AND store_time.local_at < days.day + parsed.closes + CASE WHEN parsed.closes <= parsed.opens THEN interval '1 day' ELSE interval '0' END
+))`
+
+// preloadHours loads the opening hours of the store at path, or of the
+// queried stores when path is empty, with the exceptions that can still
+// apply. Local dates lag UTC by up to a day and the day before can run past
+// midnight, so exceptions from two days back are kept.
+func preloadHours(db *gorm.DB, path string) *gorm.DB {
+	if path != "" {
+		path += "."
+	}
+	since := time.Now().UTC().AddDate(0, 0, -2).Format("2006-01-02")
+	return db.Preload(path+"OpeningHours").Preload(path+"HoursExceptions", "date >= ?", since)
+}
 
-	return responses, nil
+const (
+	// mapClusterMaxZoom is the first zoom level at which the map shows
+	// stores one by one instead of clusters.
+	mapClusterMaxZoom = 15
+	// mapCellsPerTile is how many cluster cells fit across a map tile.
+	mapCellsPerTile = 4
+	// maxMapStores bounds how many stores the map returns one by one.
+	maxMapStores = 2000
+)
+
+// liveBagsSQL sums up the bags on sale at each store: how many there are
+// and the range of their current prices. Its placeholders are the time,
+// five times.
+const liveBagsSQL = "SELECT food_bags.store_id, count(*) AS bag_count, " +
+	"MIN(" + currentPriceSQL + ") AS min_price, MAX(" + currentPriceSQL + ") AS max_price " +
+	"FROM food_bags WHERE food_bags.is_active AND food_bags.quantity_left > 0 " +
+	"AND food_bags.pickup_time_end > ? AND food_bags.deleted_at IS NULL GROUP BY food_bags.store_id"
+
+// mapStoreRow is a store on the map with its bags on sale.
+type mapStoreRow struct {
+	ID        uint
+	Name      string
+	Category  string
+	ImageURL  string
+	Rating    float32
+	Latitude  float64
+	Longitude float64
+	BagCount  int
+	MinPrice  *float64
+	MaxPrice  *float64
 }
 
-// calculateDistance calculates the distance between two points using Haversine formula
-func (r *StoreRepository) calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const earthRadius = 6371 // Earth radius in kilometers
+// mapClusterRow is one grid cell of stores on the map. StoreID is the
+// lowest store id in the cell, for cells holding a single store.
+type mapClusterRow struct {
+	StoreCount   int
+	StoreID      uint
+	Latitude     float64
+	Longitude    float64
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+	BagCount     int
+	MinPrice     *float64
+	MaxPrice     *float64
+}
 
-	dLat := (lat2 - lat1) * math.Pi / 180.0
-	dLon := (lon2 - lon1) * math.Pi / 180.0
+// mapCellSize returns the width in degrees of a cluster cell at a zoom
+// level: a tile spans 360/2^zoom degrees and holds mapCellsPerTile cells.
+func mapCellSize(zoom int) float64 {
+	return 360 / math.Pow(2, float64(zoom)) / mapCellsPerTile
+}
 
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*math.Pi/180.0)*math.Cos(lat2*math.Pi/180.0)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
+// mapCellSQL groups stores by the cluster cell they fall in at a zoom
+// level. Cells are aligned to 0,0 so they don't move with the viewport.
+func mapCellSQL(zoom int) string {
+	cell := mapCellSize(zoom)
+	return fmt.Sprintf("floor(stores.latitude / %g), floor(stores.longitude / %g)", cell, cell)
+}
 
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	distance := earthRadius * c
+// GetMapFeatures returns the active stores inside a viewport as GeoJSON,
+// each with a count and price range of its bags on sale. Below the
+// clustering zoom, stores are grouped on a fixed grid whose cells halve
+// with each zoom level, so clusters stay put while the map pans. A cell
+// holding one store is returned as that store.
+func (r *StoreRepository) GetMapFeatures(req model.MapRequest) (*model.FeatureCollection, error) {
+	now := time.Now()
+	viewport := r.db.Model(&model.Store{}).
+		Joins("LEFT JOIN ("+liveBagsSQL+") AS live ON live.store_id = stores.id", now, now, now, now, now).
+		Where("stores.is_active = ? AND stores.latitude BETWEEN ? AND ?", true, req.MinLatitude, req.MaxLatitude)
+	if req.MinLongitude <= req.MaxLongitude {
+		viewport = viewport.Where("stores.longitude BETWEEN ? AND ?", req.MinLongitude, req.MaxLongitude)
+	} else {
+		viewport = viewport.Where("(stores.longitude >= ? OR stores.longitude <= ?)", req.MinLongitude, req.MaxLongitude)
+	}
+	if req.Category != "" {
+		viewport = viewport.Where("stores.category = ?", req.Category)
+	}
 
-	return distance
+	collection := &model.FeatureCollection{Type: "FeatureCollection", Features: []model.MapFeature{}}
+	if *req.Zoom >= mapClusterMaxZoom {
+		stores, err := r.mapStores(viewport, maxMapStores+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(stores) > maxMapStores {
+			stores = stores[:maxMapStores]
+			collection.Truncated = true
+		}
+		for _, store := range stores {
+			collection.Features = append(collection.Features, store.feature())
+		}
+		return collection, nil
+	}
+
+	var cells []mapClusterRow
+	err := viewport.Session(&gorm.Session{}).
+		Select("count(*) AS store_count, MIN(stores.id) AS store_id, " +
+			"AVG(stores.latitude) AS latitude, AVG(stores.longitude) AS longitude, " +
+			"MIN(stores.latitude) AS min_latitude, MAX(stores.latitude) AS max_latitude, " +
+			"MIN(stores.longitude) AS min_longitude, MAX(stores.longitude) AS max_longitude, " +
+			"COALESCE(SUM(live.bag_count), 0) AS bag_count, MIN(live.min_price) AS min_price, MAX(live.max_price) AS max_price").
+		Group(mapCellSQL(*req.Zoom)).
+		Scan(&cells).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var singles []uint
+	for _, c := range cells {
+		if c.StoreCount == 1 {
+			singles = append(singles, c.StoreID)
+			continue
+		}
+		collection.Features = append(collection.Features, model.MapFeature{
+			Type:     "Feature",
+			ID:       fmt.Sprintf("cluster-%d", c.StoreID),
+			BBox:     []float64{c.MinLongitude, c.MinLatitude, c.MaxLongitude, c.MaxLatitude},
+			Geometry: model.GeoJSONPoint{Type: "Point", Coordinates: [2]float64{c.Longitude, c.Latitude}},
+			Properties: model.MapProperties{
+				Cluster:    true,
+				StoreCount: c.StoreCount,
+				BagCount:   c.BagCount,
+				MinPrice:   c.MinPrice,
+				MaxPrice:   c.MaxPrice,
+			},
+		})
+	}
+	if len(singles) > 0 {
+		stores, err := r.mapStores(viewport.Session(&gorm.Session{}).Where("stores.id IN ?", singles), len(singles))
+		if err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			collection.Features = append(collection.Features, store.feature())
+		}
+	}
+	return collection, nil
+}
+
+// mapStores loads up to limit stores of a viewport query.
+func (r *StoreRepository) mapStores(viewport *gorm.DB, limit int) ([]mapStoreRow, error) {
+	var stores []mapStoreRow
+	err := viewport.Session(&gorm.Session{}).
+		Select("stores.id, stores.name, stores.category, stores.image_url, stores.rating, stores.latitude, stores.longitude, " +
+			"COALESCE(live.bag_count, 0) AS bag_count, live.min_price, live.max_price").
+		Order("stores.id").
+		Limit(limit).
+		Scan(&stores).Error
+	return stores, err
+}
+
+func (s mapStoreRow) feature() model.MapFeature {
+	return model.MapFeature{
+		Type:     "Feature",
+		ID:       fmt.Sprintf("store-%d", s.ID),
+		Geometry: model.GeoJSONPoint{Type: "Point", Coordinates: [2]float64{s.Longitude, s.Latitude}},
+		Properties: model.MapProperties{
+			StoreID:  s.ID,
+			Name:     s.Name,
+			Category: s.Category,
+			ImageURL: s.ImageURL,
+			Rating:   s.Rating,
+			BagCount: s.BagCount,
+			MinPrice: s.MinPrice,
+			MaxPrice: s.MaxPrice,
+		},
+	}
 }
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+)
+
+// TestStoreOpenAtSQLMatchesIsOpenAt checks the open-now search filter
+// against Store.IsOpenAt over a week of times, for stores with overnight
+// hours, lunch breaks, exceptions and different timezones.
+func TestStoreOpenAtSQLMatchesIsOpenAt(t *testing.T) {
+	db := testdb.Open(t)
+
+	weekdays := func(opens, closes string) []model.StoreOpeningHours {
+		var hours []model.StoreOpeningHours
+		for weekday := 0; weekday < 7; weekday++ {
+			hours = append(hours, model.StoreOpeningHours{Weekday: weekday, Opens: opens, Closes: closes})
+		}
+		return hours
+	}
+	stores := []model.Store{
+		{Name: "always open"},
+		{Name: "daytime", Timezone: "Europe/Amsterdam", OpeningHours: weekdays("08:00", "18:00")},
+		{Name: "overnight", Timezone: "Asia/Jakarta", OpeningHours: weekdays("20:00", "02:00")},
+		{Name: "lunch break", Timezone: "America/New_York", OpeningHours: []model.StoreOpeningHours{
+			{Weekday: 1, Opens: "09:00", Closes: "12:00"},
+			{Weekday: 1, Opens: "13:00", Closes: "17:00"},
+		}},
+		{Name: "exceptions", Timezone: "Europe/Amsterdam", OpeningHours: weekdays("08:00", "18:00"), HoursExceptions: []model.StoreHoursException{
+			{Date: "2025-01-14", IsClosed: true},
+			{Date: "2025-01-15", Opens: "22:00", Closes: "03:00"},
+		}},
+	}
+	for i := range stores {
+		stores[i].Address = "Main St 1"
+		stores[i].Category = "bakery"
+		stores[i].IsActive = true
+		if err := db.Create(&stores[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)
+	for at := start; at.Before(start.AddDate(0, 0, 7)); at = at.Add(30 * time.Minute) {
+		var open []uint
+		if err := db.Model(&model.Store{}).Where(storeOpenAtSQL, at).Pluck("id", &open).Error; err != nil {
+			t.Fatal(err)
+		}
+		openInSQL := map[uint]bool{}
+		for _, id := range open {
+			openInSQL[id] = true
+		}
+
+		for _, store := range stores {
+			if want := store.IsOpenAt(at); openInSQL[store.ID] != want {
+				t.Errorf("%s at %s: open in SQL = %v, IsOpenAt = %v", store.Name, at.Format(time.RFC3339), openInSQL[store.ID], want)
+			}
+		}
+	}
+}
+
+// TestMapCellSize checks the cluster cell width at the widest zoom and at
+// the last zoom that still clusters.
+func TestMapCellSize(t *testing.T) {
+	for zoom, want := range map[int]float64{0: 90, mapClusterMaxZoom - 1: 0.0054931640625} {
+		if got := mapCellSize(zoom); got != want {
+			t.Errorf("mapCellSize(%d) = %g, want %g", zoom, got, want)
+		}
+	}
+}
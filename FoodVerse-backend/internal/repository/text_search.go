@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// fuzzyMatchThreshold is the trigram word similarity above which a name or
+// title counts as a match for a mistyped query.
+const fuzzyMatchThreshold = 0.4
+
+// distanceWeight is how much of its text relevance a result loses at the
+// edge of the search radius.
+const distanceWeight = 0.5
+
+// highlightOptions mark matches with placeholders that are turned into
+// <mark> tags once the rest of the snippet has been escaped.
+const (
+	highlightStart   = "{{mark}}"
+	highlightStop    = "{{/mark}}"
+	highlightOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+)
+
+// TextSearch matches and ranks rows against a free-text query using the
+// weighted search_vector columns. With pg_trgm it also matches names and
+// titles that are similar to the query, so small typos still find results.
+type TextSearch struct {
+	trigram bool
+}
+
+func NewTextSearch(trigram bool) *TextSearch {
+	return &TextSearch{trigram: trigram}
+}
+
+// textQuery is a free-text query prepared for the database.
+type textQuery struct {
+	// Text is the query as typed, for trigram similarity.
+	Text string
+	// TSQuery matches every word of the query as a prefix, e.g.
+	// "croiss:* & butter:*". It is empty when the query has no words.
+	TSQuery string
+}
+
+// parseTextQuery prepares a query. It reports false when there is nothing
+// to search for.
+func (t *TextSearch) parseTextQuery(query string) (textQuery, bool) {
+	q := textQuery{Text: strings.TrimSpace(query), TSQuery: prefixTSQuery(query)}
+	if q.TSQuery == "" && (!t.trigram || q.Text == "") {
+		return q, false
+	}
+	return q, true
+}
+
+// prefixTSQuery turns the words of a query into a tsquery matching each of
+// them as a prefix. It is empty when the query has no words.
+func prefixTSQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Match returns a condition for rows whose search vector matches q, or
+// whose name column is similar to it.
+func (t *TextSearch) Match(vector, name string, q textQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if q.TSQuery != "" {
+		conditions = append(conditions, vector+" @@ to_tsquery('simple', ?)")
+		args = append(args, q.TSQuery)
+	}
+	if t.trigram {
+		conditions = append(conditions, "word_similarity(?, "+name+") >= ?")
+		args = append(args, q.Text, fuzzyMatchThreshold)
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// Relevance returns an expression from 0 to 1 for how well a row matches
+// q: the better of its normalized text rank and its name's similarity.
+func (t *TextSearch) Relevance(vector, name string, q textQuery) (string, []interface{}) {
+	var scores []string
+	var args []interface{}
+	if q.TSQuery != "" {
+		scores = append(scores, "LEAST(1, 2 * ts_rank("+vector+", to_tsquery('simple', ?), 32))")
+		args = append(args, q.TSQuery)
+	}
+	if t.trigram {
+		scores = append(scores, "word_similarity(?, "+name+")")
+		args = append(args, q.Text)
+	}
+	if len(scores) == 1 {
+		return "CAST(" + scores[0] + " AS float8)", args
+	}
+	return "CAST(GREATEST(" + strings.Join(scores, ", ") + ") AS float8)", args
+}
+
+// Blend scales a relevance expression down with distance, so a close match
+// a little further away can still beat a poor match next door.
+func (t *TextSearch) Blend(relevance string, args []interface{}, radiusKm float64) (string, []interface{}) {
+	blended := "(" + relevance + " * (1 - ? * LEAST(nearby.distance / ?, 1)))"
+	return blended, append(append([]interface{}{}, args...), distanceWeight, radiusKm)
+}
+
+// Highlights returns, for each id, snippets of the given columns with the
+// words matching q marked up as <mark>. Columns without a match are left
+// out. Only full-text matches are highlighted.
+func (t *TextSearch) Highlights(db *gorm.DB, table string, ids []uint, columns []string, q textQuery) (map[uint]map[string]string, error) {
+	highlights := map[uint]map[string]string{}
+	if q.TSQuery == "" || len(ids) == 0 {
+		return highlights, nil
+	}
+
+	selects := []string{"id"}
+	var args []interface{}
+	for _, column := range columns {
+		selects = append(selects, "ts_headline('simple', coalesce("+column+", ''), to_tsquery('simple', ?), ?) AS "+column)
+		args = append(args, q.TSQuery, highlightOptions)
+	}
+
+	var rows []map[string]interface{}
+	err := db.Table(table).Select(strings.Join(selects, ", "), args...).Where("id IN ?", ids).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		id, ok := toUint(row["id"])
+		if !ok {
+			continue
+		}
+		for _, column := range columns {
+			snippet, _ := row[column].(string)
+			if !strings.Contains(snippet, highlightStart) {
+				continue
+			}
+			if highlights[id] == nil {
+				highlights[id] = map[string]string{}
+			}
+			snippet = html.EscapeString(snippet)
+			snippet = strings.ReplaceAll(snippet, html.EscapeString(highlightStart), "<mark>")
+			snippet = strings.ReplaceAll(snippet, html.EscapeString(highlightStop), "</mark>")
+			highlights[id][column] = snippet
+		}
+	}
+	return highlights, nil
+}
+
+// toUint reads an id scanned into an interface value.
+func toUint(value interface{}) (uint, bool) {
+	switch v := value.(type) {
+	case int64:
+		return uint(v), true
+	case int32:
+		return uint(v), true
+	case uint:
+		return v, true
+	case uint64:
+		return uint(v), true
+	}
+	return 0, false
+}
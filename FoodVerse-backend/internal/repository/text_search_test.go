@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+	"github.com/FoodVerse/FoodVerse-backend/pkg/database"
+	"gorm.io/gorm"
+)
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"  pain   au  chocolat ", "pain:* & au:* & chocolat:*"},
+		{"crème brûlée", "crème:* & brûlée:*"},
+		{"bagels & lox | !sushi", "bagels:* & lox:* & sushi:*"},
+		{"'); DROP TABLE stores; --", "drop:* & table:* & stores:*"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		if got := prefixTSQuery(tt.query); got != tt.want {
+			t.Errorf("prefixTSQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// seedTextSearch lists bags at two stores on the search point, so distance
+// does not affect their ranking, and returns their ids by title.
+func seedTextSearch(t *testing.T, db *gorm.DB) map[string]uint {
+	t.Helper()
+	const lat, lon = 52.37, 4.89
+	bakery := model.Store{Name: "Bakker Bart", Address: "Main St 1", Category: "bakery", Latitude: lat, Longitude: lon, IsActive: true}
+	grocer := model.Store{Name: "Green Grocery", Address: "Main St 2", Category: "grocery", Latitude: lat, Longitude: lon, IsActive: true}
+	for _, store := range []*model.Store{&bakery, &grocer} {
+		if err := db.Create(store).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	foodBags := []model.FoodBag{
+		{StoreID: bakery.ID, Title: "Butter croissants", Category: "bakery", Description: "Fresh from the oven"},
+		{StoreID: bakery.ID, Title: "Sourdough bread", Category: "bakery", Description: "Sometimes with a croissant on top"},
+		{StoreID: grocer.ID, Title: "Vegetable box", Category: "grocery", Description: "Seasonal vegetables"},
+		{StoreID: grocer.ID, Title: "Sushi platter", Category: "sushi"},
+	}
+	ids := map[string]uint{}
+	for i := range foodBags {
+		foodBag := &foodBags[i]
+		foodBag.OriginalPrice, foodBag.DiscountedPrice = 12, 4
+		foodBag.QuantityTotal, foodBag.QuantityLeft = 5, 5
+		foodBag.PickupTimeStart, foodBag.PickupTimeEnd = now.Add(time.Hour), now.Add(3*time.Hour)
+		foodBag.IsActive = true
+		if err := db.Create(foodBag).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[foodBag.Title] = foodBag.ID
+	}
+	return ids
+}
+
+// TestSearchNearbyText checks which bags a query finds and in what order,
+// with full-text search alone and with the trigram fallback for typos.
+func TestSearchNearbyText(t *testing.T) {
+	db := testdb.Open(t)
+	ids := seedTextSearch(t, db)
+
+	tests := []struct {
+		name    string
+		trigram bool
+		query   string
+		want    []string
+		// ordered is set when the ranking between the results matters.
+		ordered bool
+	}{
+		{name: "title beats description", query: "croissant", want: []string{"Butter croissants", "Sourdough bread"}, ordered: true},
+		{name: "every word as a prefix", query: "butter CROISS", want: []string{"Butter croissants"}},
+		{name: "punctuation", query: "vegetable!", want: []string{"Vegetable box"}},
+		{name: "store name", query: "grocery", want: []string{"Vegetable box", "Sushi platter"}},
+		{name: "typo without trigram", query: "croisant", want: nil},
+		{name: "nothing matches", query: "pizza", want: nil},
+		{name: "typo in a title", trigram: true, query: "croisant", want: []string{"Butter croissants"}},
+		{name: "typo in a store name", trigram: true, query: "bakkr bart", want: []string{"Butter croissants", "Sourdough bread"}},
+		{name: "full text still ranks with trigram", trigram: true, query: "croissant", want: []string{"Butter croissants", "Sourdough bread"}, ordered: true},
+	}
+	hasTrigram := database.HasExtension(db, "pg_trgm")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.trigram && !hasTrigram {
+				t.Skip("pg_trgm is not installed")
+			}
+			repo := NewFoodBagRepository(db, NewGeoSearch(false), NewTextSearch(tt.trigram))
+			page, err := repo.SearchNearby(model.FoodBagSearchRequest{Latitude: 52.37, Longitude: 4.89, Radius: 1, Query: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got, want []uint
+			for _, foodBag := range page.Data {
+				got = append(got, foodBag.ID)
+			}
+			for _, title := range tt.want {
+				want = append(want, ids[title])
+			}
+			if !tt.ordered {
+				slices.Sort(got)
+				slices.Sort(want)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("SearchNearby(%q) = bags %v, want %v (%v)", tt.query, got, want, tt.want)
+			}
+		})
+	}
+}
@@ -55,6 +55,6 @@ func (r *UserRepository) GetAllUsers() ([]model.User, error) {
 // Get all orders for a user (for stats)
 func (r *UserRepository) GetOrdersByUserId(userId uint) ([]model.Order, error) {
 	var orders []model.Order
-	err := r.db.Where("user_id = ?", userId).Preload("FoodBag").Find(&orders).Error
+	err := r.db.Where("user_id = ?", userId).Preload("FoodBag").Preload("Items.FoodBag").Find(&orders).Error
 	return orders, err
 }
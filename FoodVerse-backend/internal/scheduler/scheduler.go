@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job is a piece of background work that runs on a fixed interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs in-process. Each run holds a Postgres advisory lock
+// keyed by the job name, so when several replicas are up only one of them
+// executes a given job at a time and the others skip that tick.
+type Scheduler struct {
+	db   *gorm.DB
+	jobs []Job
+}
+
+func New(db *gorm.DB) *Scheduler {
+	return &Scheduler{db: db}
+}
+
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start launches every registered job in its own goroutine. Jobs stop when
+// ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runLocked(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runLocked runs a job once if this replica can take its advisory lock.
+// Session-level advisory locks belong to a connection, so the lock is taken
+// and released on a dedicated connection from the pool.
+func (s *Scheduler) runLocked(ctx context.Context, job Job) {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		log.Printf("scheduler: %s: %v", job.Name, err)
+		return
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		log.Printf("scheduler: %s: failed to get connection: %v", job.Name, err)
+		return
+	}
+	defer conn.Close()
+
+	key := lockKey(job.Name)
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		log.Printf("scheduler: %s: failed to take lock: %v", job.Name, err)
+		return
+	}
+	if !locked {
+		return
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("scheduler: %s: failed to release lock: %v", job.Name, err)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		log.Printf("scheduler: %s: %v", job.Name, err)
+	}
+}
+
+// lockKey derives a stable advisory lock key from a job name.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("foodverse:" + name))
+	return int64(h.Sum64())
+}
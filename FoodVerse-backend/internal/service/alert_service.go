@@ -0,0 +1,251 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxSavedSearches is how many saved searches a user may keep.
+const maxSavedSearches = 20
+
+var (
+	ErrSavedSearchNotFound = errors.New("saved search not found")
+	ErrInvalidSavedSearch  = errors.New("invalid saved search")
+	ErrSavedSearchLimit    = fmt.Errorf("no more than %d saved searches are allowed", maxSavedSearches)
+)
+
+// errAlertSkipped rolls back an alert that turned out not to be sent.
+var errAlertSkipped = errors.New("alert skipped")
+
+// AlertConfig holds the rules that keep alerts from flooding a user.
+type AlertConfig struct {
+	// Throttle is the least time between two alerts of one saved search.
+	Throttle time.Duration
+	// MaxPerHour caps the alerts a user gets in an hour across all their
+	// saved searches. Zero means no cap.
+	MaxPerHour int
+}
+
+// AlertService manages users' saved searches and alerts them when a bag
+// matching one goes on sale.
+type AlertService struct {
+	db               *gorm.DB
+	savedSearchRepo  *repository.SavedSearchRepository
+	notificationRepo *repository.NotificationRepository
+	foodBagRepo      *repository.FoodBagRepository
+	config           AlertConfig
+}
+
+func NewAlertService(db *gorm.DB, savedSearchRepo *repository.SavedSearchRepository, notificationRepo *repository.NotificationRepository, foodBagRepo *repository.FoodBagRepository, config AlertConfig) *AlertService {
+	return &AlertService{
+		db:               db,
+		savedSearchRepo:  savedSearchRepo,
+		notificationRepo: notificationRepo,
+		foodBagRepo:      foodBagRepo,
+		config:           config,
+	}
+}
+
+// ListSavedSearches returns a user's saved searches, newest first.
+func (s *AlertService) ListSavedSearches(userID uint) ([]model.SavedSearch, error) {
+	return s.savedSearchRepo.GetByUserID(userID)
+}
+
+// CreateSavedSearch saves a search for a user, with alerts on unless asked
+// otherwise.
+func (s *AlertService) CreateSavedSearch(userID uint, input *model.SavedSearchInput) (*model.SavedSearch, error) {
+	if err := validateSavedSearchInput(input); err != nil {
+		return nil, err
+	}
+	count, err := s.savedSearchRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxSavedSearches {
+		return nil, ErrSavedSearchLimit
+	}
+
+	savedSearch := &model.SavedSearch{UserID: userID, AlertsEnabled: true}
+	applySavedSearchInput(savedSearch, input)
+	if err := s.savedSearchRepo.Create(savedSearch); err != nil {
+		return nil, err
+	}
+	return savedSearch, nil
+}
+
+// UpdateSavedSearch changes a saved search. Bags already on sale that now
+// match are not announced; only bags listed from now on are.
+func (s *AlertService) UpdateSavedSearch(id, userID uint, input *model.SavedSearchInput) (*model.SavedSearch, error) {
+	if err := validateSavedSearchInput(input); err != nil {
+		return nil, err
+	}
+	savedSearch, err := s.getSavedSearch(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	applySavedSearchInput(savedSearch, input)
+	if err := s.savedSearchRepo.Update(savedSearch); err != nil {
+		return nil, err
+	}
+	return savedSearch, nil
+}
+
+// DeleteSavedSearch removes a saved search. Notifications it already sent
+// are kept.
+func (s *AlertService) DeleteSavedSearch(id, userID uint) error {
+	if _, err := s.getSavedSearch(id, userID); err != nil {
+		return err
+	}
+	return s.savedSearchRepo.Delete(id)
+}
+
+// getSavedSearch loads one of a user's saved searches. Other users' saved
+// searches are reported as not found.
+func (s *AlertService) getSavedSearch(id, userID uint) (*model.SavedSearch, error) {
+	savedSearch, err := s.savedSearchRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSavedSearchNotFound
+		}
+		return nil, err
+	}
+	if savedSearch.UserID != userID {
+		return nil, ErrSavedSearchNotFound
+	}
+	return savedSearch, nil
+}
+
+// FoodBagListed alerts the users whose saved searches a bag that just went
+// on sale matches. Each saved search alerts at most once per throttle
+// period and once per bag, and a user gets no more than the hourly cap.
+// Errors are logged: the bag is listed either way. It implements
+// ListingListener.
+func (s *AlertService) FoodBagListed(foodBag *model.FoodBag) {
+	now := time.Now()
+	throttledSince := now.Add(-s.config.Throttle)
+	savedSearches, err := s.savedSearchRepo.MatchFoodBag(foodBag.ID, now, throttledSince)
+	if err != nil {
+		log.Printf("alerts: food bag %d: %v", foodBag.ID, err)
+		return
+	}
+	if len(savedSearches) == 0 {
+		return
+	}
+
+	// The bag is loaded again with its store for the message.
+	listed, err := s.foodBagRepo.GetByID(foodBag.ID)
+	if err != nil {
+		log.Printf("alerts: food bag %d: %v", foodBag.ID, err)
+		return
+	}
+
+	sent := 0
+	for i := range savedSearches {
+		ok, err := s.alert(&savedSearches[i], listed, now, throttledSince)
+		if err != nil {
+			log.Printf("alerts: saved search %d, food bag %d: %v", savedSearches[i].ID, foodBag.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	if sent > 0 {
+		log.Printf("alerts: food bag %d matched %d saved searches", foodBag.ID, sent)
+	}
+}
+
+// alert notifies a saved search's user about a bag. The throttle is claimed
+// in the same transaction as the notification is written, so a skipped
+// alert leaves the saved search free to alert about the next bag.
+func (s *AlertService) alert(savedSearch *model.SavedSearch, foodBag *model.FoodBag, now, throttledSince time.Time) (bool, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		claimed, err := s.savedSearchRepo.WithTx(tx).ClaimAlert(savedSearch.ID, savedSearch.UserID, now, throttledSince)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return errAlertSkipped
+		}
+
+		notificationRepo := s.notificationRepo.WithTx(tx)
+		if s.config.MaxPerHour > 0 {
+			count, err := notificationRepo.CountSince(savedSearch.UserID, model.NotificationSavedSearchMatch, now.Add(-time.Hour))
+			if err != nil {
+				return err
+			}
+			if count >= int64(s.config.MaxPerHour) {
+				return errAlertSkipped
+			}
+		}
+
+		created, err := notificationRepo.Create(savedSearchNotification(savedSearch, foodBag, now))
+		if err != nil {
+			return err
+		}
+		if !created {
+			return errAlertSkipped
+		}
+		return nil
+	})
+	if errors.Is(err, errAlertSkipped) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// savedSearchNotification describes a bag matching a saved search, with
+// its pickup time in the store's timezone.
+func savedSearchNotification(savedSearch *model.SavedSearch, foodBag *model.FoodBag, now time.Time) *model.Notification {
+	window := foodBag.LocalPickupWindow(&foodBag.Store)
+	savedSearchID, foodBagID := savedSearch.ID, foodBag.ID
+	return &model.Notification{
+		UserID: savedSearch.UserID,
+		Type:   model.NotificationSavedSearchMatch,
+		Title:  fmt.Sprintf("%s at %s", foodBag.Title, foodBag.Store.Name),
+		Body: fmt.Sprintf("New match for \"%s\": %.2f, pick up %s-%s.",
+			savedSearch.Name, foodBag.PriceAt(now), window.StartLocal.Format("Mon 15:04"), window.EndLocal.Format("15:04")),
+		SavedSearchID: &savedSearchID,
+		FoodBagID:     &foodBagID,
+	}
+}
+
+func validateSavedSearchInput(input *model.SavedSearchInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	input.Query = strings.TrimSpace(input.Query)
+	if input.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSavedSearch)
+	}
+	if input.MaxPrice > 0 && input.MinPrice > input.MaxPrice {
+		return fmt.Errorf("%w: min_price must not be above max_price", ErrInvalidSavedSearch)
+	}
+	if input.Query != "" && strings.IndexFunc(input.Query, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r)
+	}) < 0 {
+		return fmt.Errorf("%w: query must contain a word", ErrInvalidSavedSearch)
+	}
+	return nil
+}
+
+func applySavedSearchInput(savedSearch *model.SavedSearch, input *model.SavedSearchInput) {
+	savedSearch.Name = input.Name
+	savedSearch.Latitude = input.Latitude
+	savedSearch.Longitude = input.Longitude
+	savedSearch.Radius = input.Radius
+	savedSearch.Category = input.Category
+	savedSearch.MinPrice = input.MinPrice
+	savedSearch.MaxPrice = input.MaxPrice
+	savedSearch.Query = input.Query
+	if input.AlertsEnabled != nil {
+		savedSearch.AlertsEnabled = *input.AlertsEnabled
+	}
+}
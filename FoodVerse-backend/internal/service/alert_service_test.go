@@ -0,0 +1,240 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestValidateSavedSearchInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     model.SavedSearchInput
+		wantErr   bool
+		wantName  string
+		wantQuery string
+	}{
+		{name: "valid", input: model.SavedSearchInput{Name: "Bakery", Query: "croissant"}, wantName: "Bakery", wantQuery: "croissant"},
+		{name: "trims", input: model.SavedSearchInput{Name: "  Bakery ", Query: " pain au chocolat  "}, wantName: "Bakery", wantQuery: "pain au chocolat"},
+		{name: "no query", input: model.SavedSearchInput{Name: "Anything"}, wantName: "Anything"},
+		{name: "blank name", input: model.SavedSearchInput{Name: "   "}, wantErr: true},
+		{name: "min above max", input: model.SavedSearchInput{Name: "Cheap", MinPrice: 6, MaxPrice: 5}, wantErr: true},
+		{name: "min without max", input: model.SavedSearchInput{Name: "Cheap", MinPrice: 6}, wantName: "Cheap"},
+		{name: "query without words", input: model.SavedSearchInput{Name: "Odd", Query: "&& !"}, wantErr: true},
+		{name: "query with a number", input: model.SavedSearchInput{Name: "Odd", Query: "& 7"}, wantName: "Odd", wantQuery: "& 7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.input
+			err := validateSavedSearchInput(&input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSavedSearch) {
+					t.Fatalf("err = %v, want ErrInvalidSavedSearch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if input.Name != tt.wantName || input.Query != tt.wantQuery {
+				t.Errorf("input = %q, %q, want %q, %q", input.Name, input.Query, tt.wantName, tt.wantQuery)
+			}
+		})
+	}
+}
+
+// alertFixture is a user with saved searches and bags on sale to alert
+// them about.
+type alertFixture struct {
+	db            *gorm.DB
+	savedSearches []model.SavedSearch
+	foodBags      []model.FoodBag
+}
+
+func newAlertFixture(t *testing.T, savedSearches, foodBags int) *alertFixture {
+	t.Helper()
+	db := testdb.Open(t)
+
+	user := model.User{Name: "Buyer", Email: "buyer@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	owner := model.User{Name: "Owner", Email: "owner@example.com", UserType: model.UserTypeSeller}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	store := model.Store{Name: "Bakery", Address: "Main St 1", Category: "bakery", OwnerID: owner.ID}
+	if err := db.Create(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	f := &alertFixture{db: db}
+	for i := 0; i < savedSearches; i++ {
+		f.savedSearches = append(f.savedSearches, model.SavedSearch{UserID: user.ID, Name: "Bakery bags", AlertsEnabled: true})
+	}
+	if err := db.Create(&f.savedSearches).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 0; i < foodBags; i++ {
+		f.foodBags = append(f.foodBags, model.FoodBag{
+			StoreID:         store.ID,
+			Store:           store,
+			Title:           "Surprise bag",
+			OriginalPrice:   12,
+			DiscountedPrice: 4,
+			QuantityTotal:   5,
+			QuantityLeft:    5,
+			PickupTimeStart: now.Add(time.Hour),
+			PickupTimeEnd:   now.Add(2 * time.Hour),
+			IsActive:        true,
+		})
+	}
+	if err := db.Omit("Store").Create(&f.foodBags).Error; err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f *alertFixture) service(config AlertConfig) *AlertService {
+	geo := repository.NewGeoSearch(false)
+	text := repository.NewTextSearch(false)
+	return NewAlertService(f.db, repository.NewSavedSearchRepository(f.db), repository.NewNotificationRepository(f.db),
+		repository.NewFoodBagRepository(f.db, geo, text), config)
+}
+
+func (f *alertFixture) notifications(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	if err := f.db.Model(&model.Notification{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestAlertThrottle(t *testing.T) {
+	f := newAlertFixture(t, 1, 3)
+	s := f.service(AlertConfig{Throttle: time.Hour})
+	savedSearch := &f.savedSearches[0]
+
+	start := time.Now()
+	steps := []struct {
+		name    string
+		foodBag int
+		after   time.Duration
+		want    bool
+	}{
+		{"first match", 0, 0, true},
+		{"within the throttle", 1, 30 * time.Minute, false},
+		{"throttle passed", 1, 61 * time.Minute, true},
+		{"bag already alerted", 0, 3 * time.Hour, false},
+		// The skipped alert above left the throttle unclaimed.
+		{"next bag", 2, 3 * time.Hour, true},
+	}
+	for _, step := range steps {
+		now := start.Add(step.after)
+		sent, err := s.alert(savedSearch, &f.foodBags[step.foodBag], now, now.Add(-s.config.Throttle))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if sent != step.want {
+			t.Errorf("%s: sent = %v, want %v", step.name, sent, step.want)
+		}
+	}
+	if got := f.notifications(t); got != 3 {
+		t.Errorf("notifications = %d, want 3", got)
+	}
+}
+
+func TestAlertHourlyCap(t *testing.T) {
+	f := newAlertFixture(t, 3, 1)
+	s := f.service(AlertConfig{Throttle: time.Hour, MaxPerHour: 2})
+	foodBag := &f.foodBags[0]
+
+	now := time.Now()
+	for i, want := range []bool{true, true, false} {
+		sent, err := s.alert(&f.savedSearches[i], foodBag, now, now.Add(-s.config.Throttle))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent != want {
+			t.Errorf("saved search %d: sent = %v, want %v", i, sent, want)
+		}
+	}
+
+	// A capped alert must not claim the saved search's throttle.
+	var capped model.SavedSearch
+	if err := f.db.First(&capped, f.savedSearches[2].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if capped.LastNotifiedAt != nil {
+		t.Errorf("capped saved search LastNotifiedAt = %v, want nil", capped.LastNotifiedAt)
+	}
+
+	// Once the earlier alerts are over an hour old the cap lets it through.
+	if err := f.db.Model(&model.Notification{}).Where("1 = 1").
+		Update("created_at", now.Add(-61*time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	sent, err := s.alert(&f.savedSearches[2], foodBag, now, now.Add(-s.config.Throttle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sent {
+		t.Error("alert after the hour: sent = false, want true")
+	}
+	if got := f.notifications(t); got != 3 {
+		t.Errorf("notifications = %d, want 3", got)
+	}
+}
+
+// TestAlertHourlyCapConcurrent alerts all of one user's saved searches at
+// once; the cap must hold even though every alert counts in its own
+// transaction.
+func TestAlertHourlyCapConcurrent(t *testing.T) {
+	f := newAlertFixture(t, 8, 1)
+	s := f.service(AlertConfig{Throttle: time.Hour, MaxPerHour: 2})
+	foodBag := &f.foodBags[0]
+
+	now := time.Now()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		sent     int
+		failures []error
+	)
+	start := make(chan struct{})
+	for i := range f.savedSearches {
+		wg.Add(1)
+		go func(savedSearch *model.SavedSearch) {
+			defer wg.Done()
+			<-start
+			ok, err := s.alert(savedSearch, foodBag, now, now.Add(-s.config.Throttle))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, err)
+			} else if ok {
+				sent++
+			}
+		}(&f.savedSearches[i])
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range failures {
+		t.Errorf("alert: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("sent = %d, want 2", sent)
+	}
+	if got := f.notifications(t); got != 2 {
+		t.Errorf("notifications = %d, want 2", got)
+	}
+}
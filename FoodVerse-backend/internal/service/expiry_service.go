@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+)
+
+// expiryBatchSize caps how many orders a single run closes out.
+const expiryBatchSize = 500
+
+// ExpiryService closes out food bags and orders whose pickup window is over.
+type ExpiryService struct {
+	orderService *OrderService
+	orderRepo    *repository.OrderRepository
+	foodBagRepo  *repository.FoodBagRepository
+}
+
+func NewExpiryService(orderService *OrderService, orderRepo *repository.OrderRepository, foodBagRepo *repository.FoodBagRepository) *ExpiryService {
+	return &ExpiryService{
+		orderService: orderService,
+		orderRepo:    orderRepo,
+		foodBagRepo:  foodBagRepo,
+	}
+}
+
+// Run deactivates expired food bags and expires their open orders. It is
+// meant to be run periodically by the scheduler.
+func (s *ExpiryService) Run(ctx context.Context) error {
+	now := time.Now()
+
+	deactivated, err := s.foodBagRepo.DeactivateExpired(now)
+	if err != nil {
+		return err
+	}
+
+	// Orders stay collectable during the pickup grace period.
+	orders, err := s.orderRepo.GetOpenPastPickup(now.Add(-s.orderService.timing.PickupGrace), expiryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	expired := 0
+	for i := range orders {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := s.orderService.ExpireOrder(&orders[i]); err != nil {
+			// Another request may have moved the order on since it was
+			// loaded; that is fine and the order no longer needs expiring.
+			var transitionErr *OrderTransitionError
+			if errors.As(err, &transitionErr) {
+				continue
+			}
+			return err
+		}
+		expired++
+	}
+
+	if deactivated > 0 || expired > 0 {
+		log.Printf("expiry: deactivated %d food bags, expired %d orders", deactivated, expired)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+)
+
+// TestExpiryUncollectedPaidOrderIsNoShow runs the expiry job over a bag
+// whose pickup window has ended: the paid order that was never collected
+// becomes a no-show and keeps its payment, while the unpaid one is
+// cancelled and its stock returned.
+func TestExpiryUncollectedPaidOrderIsNoShow(t *testing.T) {
+	db := testdb.Open(t)
+
+	owner := model.User{Name: "Owner", Email: "owner@example.com", UserType: model.UserTypeSeller}
+	buyer := model.User{Name: "Buyer", Email: "buyer@example.com"}
+	for _, user := range []*model.User{&owner, &buyer} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := model.Store{Name: "Bakery", Address: "Main St 1", Category: "bakery", OwnerID: owner.ID}
+	if err := db.Create(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	foodBag := model.FoodBag{
+		StoreID:         store.ID,
+		Title:           "Surprise bag",
+		OriginalPrice:   12,
+		DiscountedPrice: 4,
+		QuantityTotal:   5,
+		QuantityLeft:    3,
+		PickupTimeStart: now.Add(-3 * time.Hour),
+		PickupTimeEnd:   now.Add(-time.Hour),
+		IsActive:        true,
+	}
+	if err := db.Create(&foodBag).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	newOrder := func(status model.OrderStatus, code string) model.Order {
+		order := model.Order{
+			UserID:     buyer.ID,
+			FoodBagID:  foodBag.ID,
+			StoreID:    store.ID,
+			Quantity:   1,
+			Subtotal:   4,
+			TotalPrice: 4,
+			Status:     status,
+			PickupCode: code,
+			Items:      []model.OrderItem{{FoodBagID: foodBag.ID, Quantity: 1, UnitPrice: 4, Subtotal: 4}},
+		}
+		if err := db.Create(&order).Error; err != nil {
+			t.Fatal(err)
+		}
+		return order
+	}
+	paid := newOrder(model.OrderStatusConfirmed, "111111")
+	unpaid := newOrder(model.OrderStatusPending, "222222")
+	capturedAt := now.Add(-4 * time.Hour)
+	err := db.Create(&model.Payment{
+		OrderID:    paid.ID,
+		Provider:   "mock",
+		Amount:     4,
+		Currency:   "EUR",
+		Status:     model.PaymentStatusCaptured,
+		CapturedAt: &capturedAt,
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orderService := newTestOrderService(db)
+	orderRepo := repository.NewOrderRepository(db)
+	NewPaymentService(db, payment.NewMockProvider(payment.MockConfig{}), repository.NewPaymentRepository(db),
+		repository.NewRefundRepository(db), orderRepo, orderService, orderService.walletService, "EUR")
+	geo := repository.NewGeoSearch(false)
+	text := repository.NewTextSearch(false)
+	expiry := NewExpiryService(orderService, orderRepo, repository.NewFoodBagRepository(db, geo, text))
+
+	if err := expiry.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		id     uint
+		status model.OrderStatus
+	}{
+		{paid.ID, model.OrderStatusNoShow},
+		{unpaid.ID, model.OrderStatusCancelled},
+	} {
+		var order model.Order
+		if err := db.First(&order, want.id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if order.Status != want.status {
+			t.Errorf("order %d status = %s, want %s", want.id, order.Status, want.status)
+		}
+	}
+
+	var refunds int64
+	if err := db.Model(&model.Refund{}).Count(&refunds).Error; err != nil {
+		t.Fatal(err)
+	}
+	if refunds != 0 {
+		t.Errorf("%d refunds recorded, want none", refunds)
+	}
+	var reloaded model.FoodBag
+	if err := db.Unscoped().First(&reloaded, foodBag.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Only the unpaid order's bag goes back on the shelf.
+	if reloaded.QuantityLeft != 4 {
+		t.Errorf("QuantityLeft = %d, want 4", reloaded.QuantityLeft)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+)
+
+// Machine-readable codes returned with rejected status changes.
+const (
+	OrderErrorUnknownStatus     = "unknown_status"
+	OrderErrorInvalidTransition = "invalid_transition"
+	OrderErrorNotPermitted      = "transition_not_permitted"
+	OrderErrorStatusChanged     = "status_changed"
+	OrderErrorPickupRequired    = "pickup_required"
+)
+
+// orderTransitions lists every legal status change and the actors allowed to
+// make it. Completed, cancelled and no-show orders are final. Only the system
+// confirms an order, once its payment has been authorized, and orders are
+// only completed by pickup verification, never by a plain status update.
+var orderTransitions = map[model.OrderStatus]map[model.OrderStatus][]model.OrderActor{
+	model.OrderStatusPending: {
+		model.OrderStatusConfirmed: {model.OrderActorSystem},
+		model.OrderStatusCancelled: {model.OrderActorBuyer, model.OrderActorStoreOwner, model.OrderActorAdmin, model.OrderActorSystem},
+	},
+	model.OrderStatusConfirmed: {
+		model.OrderStatusReady:     {model.OrderActorStoreOwner, model.OrderActorAdmin},
+		model.OrderStatusCompleted: {model.OrderActorStoreOwner, model.OrderActorAdmin},
+		model.OrderStatusCancelled: {model.OrderActorBuyer, model.OrderActorStoreOwner, model.OrderActorAdmin, model.OrderActorSystem},
+		model.OrderStatusNoShow:    {model.OrderActorSystem},
+	},
+	model.OrderStatusReady: {
+		model.OrderStatusCompleted: {model.OrderActorStoreOwner, model.OrderActorAdmin},
+		model.OrderStatusCancelled: {model.OrderActorStoreOwner, model.OrderActorAdmin, model.OrderActorSystem},
+		model.OrderStatusNoShow:    {model.OrderActorSystem},
+	},
+}
+
+// OrderTransitionError describes a rejected order status change.
+type OrderTransitionError struct {
+	Code  string
+	From  model.OrderStatus
+	To    model.OrderStatus
+	Actor model.OrderActor
+}
+
+func (e *OrderTransitionError) Error() string {
+	switch e.Code {
+	case OrderErrorUnknownStatus:
+		return fmt.Sprintf("unknown order status %q", e.To)
+	case OrderErrorNotPermitted:
+		return fmt.Sprintf("%s may not move an order from %s to %s", e.Actor, e.From, e.To)
+	case OrderErrorStatusChanged:
+		return fmt.Sprintf("order is no longer %s", e.From)
+	case OrderErrorPickupRequired:
+		return "orders are completed by verifying their pickup code or token"
+	default:
+		return fmt.Sprintf("cannot move an order from %s to %s", e.From, e.To)
+	}
+}
+
+// ValidateOrderTransition checks that actor may move an order from one status
+// to another.
+func ValidateOrderTransition(from, to model.OrderStatus, actor model.OrderActor) error {
+	if !to.IsValid() {
+		return &OrderTransitionError{Code: OrderErrorUnknownStatus, From: from, To: to, Actor: actor}
+	}
+
+	actors, ok := orderTransitions[from][to]
+	if !ok {
+		return &OrderTransitionError{Code: OrderErrorInvalidTransition, From: from, To: to, Actor: actor}
+	}
+
+	for _, allowed := range actors {
+		if allowed == actor {
+			return nil
+		}
+	}
+	return &OrderTransitionError{Code: OrderErrorNotPermitted, From: from, To: to, Actor: actor}
+}
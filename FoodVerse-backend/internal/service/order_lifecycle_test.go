@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+)
+
+func TestValidateOrderTransition(t *testing.T) {
+	const (
+		buyer  = model.OrderActorBuyer
+		owner  = model.OrderActorStoreOwner
+		admin  = model.OrderActorAdmin
+		system = model.OrderActorSystem
+	)
+	tests := []struct {
+		from, to model.OrderStatus
+		actor    model.OrderActor
+		wantCode string // "" when the change is allowed
+	}{
+		{model.OrderStatusPending, model.OrderStatusConfirmed, system, ""},
+		{model.OrderStatusPending, model.OrderStatusConfirmed, owner, OrderErrorNotPermitted},
+		{model.OrderStatusPending, model.OrderStatusCancelled, buyer, ""},
+		{model.OrderStatusConfirmed, model.OrderStatusReady, owner, ""},
+		{model.OrderStatusConfirmed, model.OrderStatusReady, admin, ""},
+		{model.OrderStatusConfirmed, model.OrderStatusReady, buyer, OrderErrorNotPermitted},
+		{model.OrderStatusConfirmed, model.OrderStatusCancelled, buyer, ""},
+		{model.OrderStatusReady, model.OrderStatusCancelled, buyer, OrderErrorNotPermitted},
+		{model.OrderStatusReady, model.OrderStatusCancelled, owner, ""},
+		{model.OrderStatusReady, model.OrderStatusCompleted, buyer, OrderErrorNotPermitted},
+		{model.OrderStatusConfirmed, model.OrderStatusNoShow, system, ""},
+		{model.OrderStatusReady, model.OrderStatusNoShow, system, ""},
+		{model.OrderStatusReady, model.OrderStatusNoShow, owner, OrderErrorNotPermitted},
+		{model.OrderStatusCompleted, model.OrderStatusPending, admin, OrderErrorInvalidTransition},
+		{model.OrderStatusCancelled, model.OrderStatusConfirmed, system, OrderErrorInvalidTransition},
+		{model.OrderStatusNoShow, model.OrderStatusCompleted, owner, OrderErrorInvalidTransition},
+		{model.OrderStatusPending, model.OrderStatusReady, owner, OrderErrorInvalidTransition},
+		{model.OrderStatusConfirmed, "preparing", owner, OrderErrorUnknownStatus},
+	}
+	for _, tt := range tests {
+		err := ValidateOrderTransition(tt.from, tt.to, tt.actor)
+		if tt.wantCode == "" {
+			if err != nil {
+				t.Errorf("%s: %s -> %s: %v, want allowed", tt.actor, tt.from, tt.to, err)
+			}
+			continue
+		}
+		var transitionErr *OrderTransitionError
+		if !errors.As(err, &transitionErr) {
+			t.Errorf("%s: %s -> %s: err = %v, want code %s", tt.actor, tt.from, tt.to, err, tt.wantCode)
+			continue
+		}
+		if transitionErr.Code != tt.wantCode {
+			t.Errorf("%s: %s -> %s: code = %s, want %s", tt.actor, tt.from, tt.to, transitionErr.Code, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,929 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidQuantity    = errors.New("quantity must be at least 1")
+	ErrFoodBagNotFound    = errors.New("food bag not found")
+	ErrFoodBagUnavailable = errors.New("food bag is no longer available")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrOrderForbidden     = errors.New("not authorized to manage this order")
+
+	ErrCartEmpty                  = errors.New("cart is empty")
+	ErrStoreNotFound              = errors.New("store not found")
+	ErrOutsidePickupWindow        = errors.New("order is outside its pickup window")
+	ErrCancellationCutoffPassed   = errors.New("order can no longer be cancelled this close to pickup")
+	ErrCancellationReasonRequired = errors.New("a reason is required when a store cancels an order")
+)
+
+// SoldOutError is returned when a food bag does not have enough quantity left
+// to cover an order.
+type SoldOutError struct {
+	FoodBagID uint
+	Requested int
+	Available int
+}
+
+func (e *SoldOutError) Error() string {
+	return fmt.Sprintf("food bag %d is sold out: requested %d, %d left", e.FoodBagID, e.Requested, e.Available)
+}
+
+type OrderService struct {
+	db               *gorm.DB
+	orderRepo        *repository.OrderRepository
+	orderEventRepo   *repository.OrderEventRepository
+	foodBagRepo      *repository.FoodBagRepository
+	cartRepo         *repository.CartRepository
+	storeRepo        *repository.StoreRepository
+	userRepo         *repository.UserRepository
+	walletService    *WalletService
+	promotionService *PromotionService
+	timing           OrderTiming
+
+	cancellationHandlers  []CancellationHandler
+	cancellationListeners []CancellationListener
+	listingListeners      []ListingListener
+}
+
+// CancellationHandler is called inside the transaction that cancels an
+// order, so whatever it records is committed or rolled back together with
+// the cancellation.
+type CancellationHandler interface {
+	OrderCancelling(tx *gorm.DB, order *model.Order, actor model.OrderActor, actorID *uint, reason string) error
+}
+
+// CancellationListener is told about every cancelled order once the
+// cancellation has been committed.
+type CancellationListener interface {
+	OrderCancelled(order *model.Order, actor model.OrderActor, actorID *uint, reason string)
+}
+
+// OrderTiming holds the time-based rules applied to orders.
+type OrderTiming struct {
+	// CancelCutoff is how long before pickup starts a buyer may still cancel.
+	CancelCutoff time.Duration
+	// PickupGrace widens the pickup window on both ends when a pickup is
+	// verified, and delays no-show expiry by the same amount.
+	PickupGrace time.Duration
+}
+
+func NewOrderService(db *gorm.DB, orderRepo *repository.OrderRepository, orderEventRepo *repository.OrderEventRepository, foodBagRepo *repository.FoodBagRepository, cartRepo *repository.CartRepository, storeRepo *repository.StoreRepository, userRepo *repository.UserRepository, walletService *WalletService, promotionService *PromotionService, timing OrderTiming) *OrderService {
+	return &OrderService{
+		db:               db,
+		orderRepo:        orderRepo,
+		orderEventRepo:   orderEventRepo,
+		foodBagRepo:      foodBagRepo,
+		cartRepo:         cartRepo,
+		storeRepo:        storeRepo,
+		userRepo:         userRepo,
+		walletService:    walletService,
+		promotionService: promotionService,
+		timing:           timing,
+	}
+}
+
+// statusChange describes a status transition and who is making it. Every
+// change is written to the order's history.
+type statusChange struct {
+	to      model.OrderStatus
+	actor   model.OrderActor
+	actorID *uint
+	source  model.OrderEventSource
+	note    string
+
+	// updates holds extra order columns to set along with the status.
+	updates map[string]interface{}
+}
+
+// AddCancellationHandler registers a handler that runs as part of every
+// cancellation.
+func (s *OrderService) AddCancellationHandler(handler CancellationHandler) {
+	s.cancellationHandlers = append(s.cancellationHandlers, handler)
+}
+
+// AddCancellationListener registers a listener for cancelled orders.
+func (s *OrderService) AddCancellationListener(listener CancellationListener) {
+	s.cancellationListeners = append(s.cancellationListeners, listener)
+}
+
+func (s *OrderService) notifyCancelled(order *model.Order, change statusChange) {
+	for _, listener := range s.cancellationListeners {
+		listener.OrderCancelled(order, change.actor, change.actorID, change.note)
+	}
+}
+
+// AddListingListener registers a listener for sold-out bags that a
+// cancellation puts back on sale.
+func (s *OrderService) AddListingListener(listener ListingListener) {
+	s.listingListeners = append(s.listingListeners, listener)
+}
+
+// notifyRestocked tells the listing listeners about bags that were sold out
+// until a cancellation gave stock back.
+func (s *OrderService) notifyRestocked(foodBagIDs []uint) {
+	if len(s.listingListeners) == 0 {
+		return
+	}
+	for _, foodBagID := range foodBagIDs {
+		foodBag, err := s.foodBagRepo.GetByID(foodBagID)
+		if err != nil {
+			// The bag may have been withdrawn along with the order.
+			continue
+		}
+		for _, listener := range s.listingListeners {
+			listener.FoodBagListed(foodBag)
+		}
+	}
+}
+
+// orderLine is a food bag and quantity to be ordered.
+type orderLine struct {
+	foodBagID uint
+	quantity  int
+}
+
+// orderOptions are the buyer's choices that apply to every order created
+// together.
+type orderOptions struct {
+	notes        string
+	walletAmount float64
+	promoCode    string
+}
+
+// PlaceOrder reserves stock and creates a single-bag order in one
+// transaction.
+func (s *OrderService) PlaceOrder(userID uint, input *model.OrderInput) (*model.Order, error) {
+	if input.Quantity < 1 {
+		return nil, ErrInvalidQuantity
+	}
+
+	var orders []*model.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		orders, err = s.createOrders(tx, userID, []orderLine{{foodBagID: input.FoodBagID, quantity: input.Quantity}}, orderOptions{
+			notes:        input.Notes,
+			walletAmount: input.WalletAmount,
+			promoCode:    input.PromoCode,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.GetByID(orders[0].ID)
+}
+
+// Checkout turns the user's cart into orders, one per store, and empties the
+// cart. Either every line is reserved or nothing is.
+func (s *OrderService) Checkout(userID uint, input *model.CheckoutInput) ([]model.Order, error) {
+	var created []*model.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		cartRepo := s.cartRepo.WithTx(tx)
+
+		items, err := cartRepo.GetByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return ErrCartEmpty
+		}
+
+		created, err = s.createOrders(tx, userID, cartLines(items), orderOptions{
+			notes:        input.Notes,
+			walletAmount: input.WalletAmount,
+			promoCode:    input.PromoCode,
+		})
+		if err != nil {
+			return err
+		}
+
+		return cartRepo.Clear(userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]model.Order, 0, len(created))
+	for _, order := range created {
+		loaded, err := s.orderRepo.GetByID(order.ID)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *loaded)
+	}
+	return orders, nil
+}
+
+// QuotePromotion prices the given food bags, or the user's cart when there
+// are none, with a promo code applied, without reserving anything.
+func (s *OrderService) QuotePromotion(userID uint, input *model.PromotionValidateInput) (*model.PromotionQuote, error) {
+	lines := make([]orderLine, len(input.Items))
+	for i, item := range input.Items {
+		lines[i] = orderLine{foodBagID: item.FoodBagID, quantity: item.Quantity}
+	}
+	if len(lines) == 0 {
+		items, err := s.cartRepo.GetByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, ErrCartEmpty
+		}
+		lines = cartLines(items)
+	}
+
+	orders, foodBags, err := s.buildOrders(s.db, userID, lines, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &model.PromotionQuote{Code: input.Code}
+	for _, order := range orders {
+		quote.Subtotal += order.TotalPrice
+	}
+	quote.Total = quote.Subtotal
+
+	applied, err := s.promotionService.Evaluate(s.db, userID, input.Code, orders, foodBags, false)
+	if err != nil {
+		var promoErr *PromotionError
+		if errors.As(err, &promoErr) {
+			quote.Reason = promoErr.Code
+			quote.Message = promoErr.Error()
+			return quote, nil
+		}
+		return nil, err
+	}
+
+	quote.Valid = true
+	quote.Description = applied.promotion.Description
+	quote.DiscountAmount = applied.discount
+	quote.Total = quote.Subtotal - applied.discount
+	quote.StoreID = applied.order.StoreID
+	return quote, nil
+}
+
+// createOrders reserves stock for every line and creates one order per store.
+//
+// A promo code is applied to the one order it saves the most on. Up to
+// walletAmount is then taken from the buyer's wallet, order by order. An
+// order the wallet or the discount covers in full is confirmed straight
+// away; otherwise the wallet share stays on hold until the rest is paid.
+func (s *OrderService) createOrders(tx *gorm.DB, userID uint, lines []orderLine, options orderOptions) ([]*model.Order, error) {
+	orderRepo := s.orderRepo.WithTx(tx)
+	orderEventRepo := s.orderEventRepo.WithTx(tx)
+
+	orders, foodBags, err := s.buildOrders(tx, userID, lines, options.notes, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied *appliedPromotion
+	if options.promoCode != "" {
+		applied, err = s.promotionService.Evaluate(tx, userID, options.promoCode, orders, foodBags, true)
+		if err != nil {
+			return nil, err
+		}
+		applied.order.DiscountAmount = applied.discount
+		applied.order.TotalPrice = applied.order.Subtotal - applied.discount
+		applied.order.PromotionID = &applied.promotion.ID
+		applied.order.PromoCode = applied.promotion.Code
+	}
+
+	walletLeft := payment.ToMinorUnits(options.walletAmount)
+	for _, order := range orders {
+		total := payment.ToMinorUnits(order.TotalPrice)
+		fromWallet := min(walletLeft, total)
+		walletLeft -= fromWallet
+		order.WalletAmount = payment.FromMinorUnits(fromWallet)
+
+		if err := orderRepo.Create(order); err != nil {
+			return nil, err
+		}
+
+		err := orderEventRepo.Create(&model.OrderEvent{
+			OrderID:     order.ID,
+			ToStatus:    order.Status,
+			ActorUserID: &userID,
+			Actor:       model.OrderActorBuyer,
+			Source:      model.OrderEventSourceAPI,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if applied != nil && applied.order == order {
+			if err := s.promotionService.Redeem(tx, applied, userID); err != nil {
+				return nil, err
+			}
+		}
+
+		note := "fully discounted"
+		if fromWallet > 0 {
+			if err := s.walletService.Hold(tx, userID, order.ID, fromWallet); err != nil {
+				return nil, err
+			}
+			if fromWallet < total {
+				continue
+			}
+			if err := s.walletService.CaptureHold(tx, order.ID); err != nil {
+				return nil, err
+			}
+			note = "paid from wallet"
+		} else if total > 0 {
+			continue
+		}
+
+		err = s.transition(tx, order, statusChange{
+			to:     model.OrderStatusConfirmed,
+			actor:  model.OrderActorSystem,
+			source: model.OrderEventSourcePayment,
+			note:   note,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return orders, nil
+}
+
+// buildOrders prices the lines and groups them into one unsaved order per
+// store. With reserve set, food bag rows are locked in ID order for the rest
+// of the transaction and their stock is taken, so concurrent buyers are
+// serialized without deadlocking and QuantityLeft can never go negative.
+// Totals are computed here from the food bags' current prices, which the
+// order items keep even if the price drops later.
+func (s *OrderService) buildOrders(tx *gorm.DB, userID uint, lines []orderLine, notes string, reserve bool) ([]*model.Order, map[uint]*model.FoodBag, error) {
+	foodBagRepo := s.foodBagRepo.WithTx(tx)
+	now := time.Now()
+
+	// Merge repeated food bags and fix the locking order.
+	quantities := map[uint]int{}
+	var foodBagIDs []uint
+	for _, line := range lines {
+		if line.quantity < 1 {
+			return nil, nil, ErrInvalidQuantity
+		}
+		if _, seen := quantities[line.foodBagID]; !seen {
+			foodBagIDs = append(foodBagIDs, line.foodBagID)
+		}
+		quantities[line.foodBagID] += line.quantity
+	}
+	sort.Slice(foodBagIDs, func(i, j int) bool { return foodBagIDs[i] < foodBagIDs[j] })
+
+	foodBags := map[uint]*model.FoodBag{}
+	ordersByStore := map[uint]*model.Order{}
+	var orders []*model.Order
+	for _, foodBagID := range foodBagIDs {
+		quantity := quantities[foodBagID]
+
+		var foodBag *model.FoodBag
+		var err error
+		if reserve {
+			foodBag, err = foodBagRepo.GetByIDForUpdate(foodBagID)
+		} else {
+			foodBag, err = foodBagRepo.GetByID(foodBagID)
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil, ErrFoodBagNotFound
+			}
+			return nil, nil, err
+		}
+		foodBags[foodBag.ID] = foodBag
+
+		// A bag whose pickup window has closed stays active until the
+		// expiry job gets to it, but can no longer be ordered.
+		if !foodBag.IsActive || !foodBag.PickupTimeEnd.After(now) {
+			return nil, nil, ErrFoodBagUnavailable
+		}
+
+		if foodBag.QuantityLeft < quantity {
+			return nil, nil, &SoldOutError{FoodBagID: foodBag.ID, Requested: quantity, Available: foodBag.QuantityLeft}
+		}
+
+		if reserve {
+			reserved, err := foodBagRepo.DecrementQuantity(foodBag.ID, quantity)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !reserved {
+				return nil, nil, &SoldOutError{FoodBagID: foodBag.ID, Requested: quantity, Available: foodBag.QuantityLeft}
+			}
+		}
+
+		order, ok := ordersByStore[foodBag.StoreID]
+		if !ok {
+			order = &model.Order{
+				UserID:    userID,
+				FoodBagID: foodBag.ID,
+				StoreID:   foodBag.StoreID,
+				Status:    model.OrderStatusPending,
+				Notes:     notes,
+			}
+			ordersByStore[foodBag.StoreID] = order
+			orders = append(orders, order)
+		}
+
+		unitPrice := foodBag.PriceAt(now)
+		subtotal := unitPrice * float64(quantity)
+		order.Items = append(order.Items, model.OrderItem{
+			FoodBagID: foodBag.ID,
+			Quantity:  quantity,
+			UnitPrice: unitPrice,
+			Subtotal:  subtotal,
+		})
+		order.Quantity += quantity
+		order.Subtotal += subtotal
+		order.TotalPrice += subtotal
+	}
+
+	return orders, foodBags, nil
+}
+
+func cartLines(items []model.CartItem) []orderLine {
+	lines := make([]orderLine, len(items))
+	for i, item := range items {
+		lines[i] = orderLine{foodBagID: item.FoodBagID, quantity: item.Quantity}
+	}
+	return lines
+}
+
+// UpdateStatus moves an order to a new status on behalf of userID, enforcing
+// the order lifecycle. Orders are only completed through pickup
+// verification, which checks the pickup code and window.
+func (s *OrderService) UpdateStatus(orderID, userID uint, status model.OrderStatus) (*model.Order, error) {
+	order, err := s.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancelling has to give the stock back, so it always goes through
+	// CancelOrder.
+	if status == model.OrderStatusCancelled {
+		return s.CancelOrder(orderID, userID, "")
+	}
+	if status == model.OrderStatusCompleted {
+		return nil, &OrderTransitionError{Code: OrderErrorPickupRequired, From: order.Status, To: status}
+	}
+
+	actor, err := s.resolveActor(order, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return s.transition(tx, order, statusChange{
+			to:      status,
+			actor:   actor,
+			actorID: &userID,
+			source:  model.OrderEventSourceAPI,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.GetByID(order.ID)
+}
+
+// CancelOrder cancels an order and returns its quantity to the food bag in
+// the same transaction. Buyers may only cancel until CancelCutoff before
+// pickup starts; stores and admins must give a reason.
+func (s *OrderService) CancelOrder(orderID, userID uint, reason string) (*model.Order, error) {
+	order, err := s.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, err := s.resolveActor(order, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch actor {
+	case model.OrderActorBuyer:
+		pickupStart, _ := order.PickupTimes()
+		if !now.Before(pickupStart.Add(-s.timing.CancelCutoff)) {
+			return nil, ErrCancellationCutoffPassed
+		}
+	case model.OrderActorStoreOwner, model.OrderActorAdmin:
+		if reason == "" {
+			return nil, ErrCancellationReasonRequired
+		}
+	}
+
+	change := statusChange{
+		actor:   actor,
+		actorID: &userID,
+		source:  model.OrderEventSourceAPI,
+		note:    reason,
+	}
+	var restocked []uint
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		restocked, err = s.cancel(tx, order, change, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notifyCancelled(order, change)
+	s.notifyRestocked(restocked)
+
+	return s.orderRepo.GetByID(order.ID)
+}
+
+// cancel moves an order to cancelled, records who did it and why, gives the
+// wallet share and any promo redemption back, puts the ordered quantity
+// back on the food bag and runs the cancellation handlers. The change's
+// note is stored as the cancellation reason. It returns the bags that were
+// sold out and are back in stock.
+func (s *OrderService) cancel(tx *gorm.DB, order *model.Order, change statusChange, now time.Time) ([]uint, error) {
+	change.to = model.OrderStatusCancelled
+	change.updates = map[string]interface{}{
+		"cancelled_at":        now,
+		"cancelled_by_id":     change.actorID,
+		"cancelled_by_actor":  change.actor,
+		"cancellation_reason": change.note,
+	}
+	if err := s.transition(tx, order, change); err != nil {
+		return nil, err
+	}
+
+	if err := s.walletService.ReleaseOrder(tx, order.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.promotionService.Release(tx, order.ID); err != nil {
+		return nil, err
+	}
+
+	// Bags are updated in ID order, the order buildOrders locks them in.
+	quantities := map[uint]int{}
+	if len(order.Items) == 0 {
+		// Orders placed before line items existed.
+		quantities[order.FoodBagID] = order.Quantity
+	}
+	for _, item := range order.Items {
+		quantities[item.FoodBagID] += item.Quantity
+	}
+	foodBagIDs := make([]uint, 0, len(quantities))
+	for foodBagID := range quantities {
+		foodBagIDs = append(foodBagIDs, foodBagID)
+	}
+	sort.Slice(foodBagIDs, func(i, j int) bool { return foodBagIDs[i] < foodBagIDs[j] })
+
+	foodBagRepo := s.foodBagRepo.WithTx(tx)
+	var restocked []uint
+	for _, foodBagID := range foodBagIDs {
+		wasSoldOut, err := foodBagRepo.IncrementQuantity(foodBagID, quantities[foodBagID])
+		if err != nil {
+			return nil, err
+		}
+		if wasSoldOut {
+			restocked = append(restocked, foodBagID)
+		}
+	}
+
+	for _, handler := range s.cancellationHandlers {
+		if err := handler.OrderCancelling(tx, order, change.actor, change.actorID, change.note); err != nil {
+			return nil, err
+		}
+	}
+	return restocked, nil
+}
+
+// VerifyPickup completes the order carrying pickupCode at storeID. The caller
+// must own the store (or be an admin), the order must be collectable and the
+// current time must fall inside the order's pickup times, widened by
+// PickupGrace.
+func (s *OrderService) VerifyPickup(storeID uint, pickupCode string, userID uint) (*model.Order, error) {
+	actor, err := s.storeActor(storeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetByStoreAndPickupCode(storeID, strings.ToUpper(strings.TrimSpace(pickupCode)))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	return s.completePickup(order, actor, userID, time.Now(), "")
+}
+
+// RedeemPickup completes an order named by a verified pickup token. The
+// pickup window is checked against redeemedAt, which lets store devices sync
+// pickups they accepted while offline.
+func (s *OrderService) RedeemPickup(orderID, storeID, userID uint, redeemedAt time.Time, note string) (*model.Order, error) {
+	actor, err := s.storeActor(storeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.StoreID != storeID {
+		return nil, ErrOrderNotFound
+	}
+
+	return s.completePickup(order, actor, userID, redeemedAt, note)
+}
+
+// storeActor checks that userID may manage the orders of storeID.
+func (s *OrderService) storeActor(storeID, userID uint) (model.OrderActor, error) {
+	store, err := s.storeRepo.GetByID(storeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrStoreNotFound
+		}
+		return "", err
+	}
+
+	if store.OwnerID == userID {
+		return model.OrderActorStoreOwner, nil
+	}
+
+	user, err := s.userRepo.FindUserById(userID)
+	if err != nil {
+		return "", err
+	}
+	if user.UserType != model.UserTypeAdmin {
+		return "", ErrOrderForbidden
+	}
+	return model.OrderActorAdmin, nil
+}
+
+// completePickup marks an order as picked up at the given time, provided it
+// is collectable and the time falls inside its pickup times.
+func (s *OrderService) completePickup(order *model.Order, actor model.OrderActor, userID uint, at time.Time, note string) (*model.Order, error) {
+	if err := ValidateOrderTransition(order.Status, model.OrderStatusCompleted, actor); err != nil {
+		return nil, err
+	}
+
+	pickupStart, pickupEnd := order.PickupTimes()
+	opensAt := pickupStart.Add(-s.timing.PickupGrace)
+	closesAt := pickupEnd.Add(s.timing.PickupGrace)
+	if at.Before(opensAt) || at.After(closesAt) {
+		return nil, ErrOutsidePickupWindow
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.transition(tx, order, statusChange{
+			to:      model.OrderStatusCompleted,
+			actor:   actor,
+			actorID: &userID,
+			source:  model.OrderEventSourcePickup,
+			note:    note,
+			updates: map[string]interface{}{"picked_up_at": at},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.GetByID(order.ID)
+}
+
+// PickupDeadline is the last moment an order can still be collected.
+func (s *OrderService) PickupDeadline(order *model.Order) time.Time {
+	_, pickupEnd := order.PickupTimes()
+	return pickupEnd.Add(s.timing.PickupGrace)
+}
+
+// WithdrawFoodBag deletes a food bag on behalf of its store owner and
+// cancels every open order that includes it, so their buyers are refunded.
+// Orders spanning several bags are cancelled whole. The bag is locked
+// first, so no new order can reserve it in the meantime.
+func (s *OrderService) WithdrawFoodBag(foodBagID, userID uint) error {
+	change := statusChange{
+		actor:   model.OrderActorStoreOwner,
+		actorID: &userID,
+		source:  model.OrderEventSourceAPI,
+		note:    "food bag withdrawn by the store",
+	}
+
+	var cancelled []model.Order
+	var restocked []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		foodBagRepo := s.foodBagRepo.WithTx(tx)
+		if _, err := foodBagRepo.GetByIDForUpdate(foodBagID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFoodBagNotFound
+			}
+			return err
+		}
+
+		orders, err := s.orderRepo.WithTx(tx).GetOpenByFoodBagID(foodBagID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range orders {
+			wasSoldOut, err := s.cancel(tx, &orders[i], change, now)
+			if err != nil {
+				return err
+			}
+			restocked = append(restocked, wasSoldOut...)
+		}
+		cancelled = orders
+
+		return foodBagRepo.Delete(foodBagID)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range cancelled {
+		s.notifyCancelled(&cancelled[i], change)
+	}
+	s.notifyRestocked(restocked)
+	return nil
+}
+
+// ExpireOrder closes an order whose last pickup window has passed. Paid
+// orders that were never collected become no-shows and keep their payment;
+// unpaid ones are cancelled and their stock returned.
+func (s *OrderService) ExpireOrder(order *model.Order) error {
+	change := statusChange{
+		actor:  model.OrderActorSystem,
+		source: model.OrderEventSourceScheduler,
+		note:   "pickup window ended",
+	}
+	if order.Status == model.OrderStatusConfirmed || order.Status == model.OrderStatusReady {
+		change.to = model.OrderStatusNoShow
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			return s.transition(tx, order, change)
+		})
+	}
+
+	var restocked []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		restocked, err = s.cancel(tx, order, change, time.Now())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.notifyCancelled(order, change)
+	s.notifyRestocked(restocked)
+	return nil
+}
+
+// ConfirmPayment confirms a pending order once its payment is authorized.
+func (s *OrderService) ConfirmPayment(orderID uint) error {
+	order, err := s.getOrder(orderID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := s.transition(tx, order, statusChange{
+			to:     model.OrderStatusConfirmed,
+			actor:  model.OrderActorSystem,
+			source: model.OrderEventSourcePayment,
+			note:   "payment authorized",
+		})
+		if err != nil {
+			return err
+		}
+
+		// The wallet share of a split payment is taken once the rest is
+		// secured.
+		return s.walletService.CaptureHold(tx, order.ID)
+	})
+}
+
+// GetForBuyer returns an order if userID placed it.
+func (s *OrderService) GetForBuyer(orderID, userID uint) (*model.Order, error) {
+	order, err := s.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// GetForParticipant returns an order to its buyer, the store owner or an
+// admin.
+func (s *OrderService) GetForParticipant(orderID, userID uint) (*model.Order, error) {
+	order, err := s.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.resolveActor(order, userID); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// GetStoreOrders returns a page of a store's orders to the store owner or
+// an admin.
+func (s *OrderService) GetStoreOrders(storeID, userID uint, page model.PageRequest) (*model.Page[model.Order], error) {
+	if _, err := s.storeActor(storeID, userID); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetByStoreID(storeID, page)
+}
+
+// GetHistory returns the status history of an order to its buyer, the store
+// owner or an admin.
+func (s *OrderService) GetHistory(orderID, userID uint) ([]model.OrderEvent, error) {
+	order, err := s.GetForParticipant(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderEventRepo.GetByOrderID(order.ID)
+}
+
+func (s *OrderService) getOrder(orderID uint) (*model.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// resolveActor works out in which capacity userID is acting on an order.
+// The store owner role wins over the buyer role.
+func (s *OrderService) resolveActor(order *model.Order, userID uint) (model.OrderActor, error) {
+	if order.Store.OwnerID == userID {
+		return model.OrderActorStoreOwner, nil
+	}
+
+	user, err := s.userRepo.FindUserById(userID)
+	if err != nil {
+		return "", err
+	}
+	if user.UserType == model.UserTypeAdmin {
+		return model.OrderActorAdmin, nil
+	}
+
+	if order.UserID == userID {
+		return model.OrderActorBuyer, nil
+	}
+	return "", ErrOrderForbidden
+}
+
+// transition validates and applies a status change and appends it to the
+// order's history. The update only succeeds if the order still has the status
+// it was loaded with, so two concurrent changes cannot both win.
+func (s *OrderService) transition(tx *gorm.DB, order *model.Order, change statusChange) error {
+	from := order.Status
+	if err := ValidateOrderTransition(from, change.to, change.actor); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"status": change.to}
+	for column, value := range change.updates {
+		updates[column] = value
+	}
+	if _, ok := updates["picked_up_at"]; !ok && change.to == model.OrderStatusCompleted {
+		updates["picked_up_at"] = time.Now()
+	}
+
+	updated, err := s.orderRepo.WithTx(tx).UpdateStatusFrom(order.ID, from, updates)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return &OrderTransitionError{Code: OrderErrorStatusChanged, From: from, To: change.to, Actor: change.actor}
+	}
+
+	order.Status = change.to
+	return s.orderEventRepo.WithTx(tx).Create(&model.OrderEvent{
+		OrderID:     order.ID,
+		FromStatus:  from,
+		ToStatus:    change.to,
+		ActorUserID: change.actorID,
+		Actor:       change.actor,
+		Source:      change.source,
+		Note:        change.note,
+	})
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func newTestOrderService(db *gorm.DB) *OrderService {
+	geo := repository.NewGeoSearch(false)
+	text := repository.NewTextSearch(false)
+	orderRepo := repository.NewOrderRepository(db)
+	storeRepo := repository.NewStoreRepository(db, geo, text)
+	userRepo := repository.NewUserRepository(db)
+	walletService := NewWalletService(db, repository.NewLedgerRepository(db), "EUR")
+	promotionService := NewPromotionService(repository.NewPromotionRepository(db), orderRepo, storeRepo, userRepo)
+	return NewOrderService(db, orderRepo, repository.NewOrderEventRepository(db), repository.NewFoodBagRepository(db, geo, text),
+		repository.NewCartRepository(db), storeRepo, userRepo, walletService, promotionService, OrderTiming{})
+}
+
+func TestPlaceOrderConcurrentBuyersNeverOversell(t *testing.T) {
+	db := testdb.Open(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep well below the server's connection limit; the buyers still
+	// contend for the same row.
+	sqlDB.SetMaxOpenConns(20)
+
+	const buyers = 300
+	const quantityTotal = 25
+
+	owner := model.User{Name: "Owner", Email: "owner@example.com", UserType: model.UserTypeSeller}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	store := model.Store{Name: "Bakery", Address: "Main St 1", Category: "bakery", OwnerID: owner.ID}
+	if err := db.Create(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	foodBag := model.FoodBag{
+		StoreID:         store.ID,
+		Title:           "Surprise bag",
+		OriginalPrice:   12,
+		DiscountedPrice: 4,
+		QuantityTotal:   quantityTotal,
+		QuantityLeft:    quantityTotal,
+		PickupTimeStart: now.Add(time.Hour),
+		PickupTimeEnd:   now.Add(2 * time.Hour),
+		IsActive:        true,
+	}
+	if err := db.Create(&foodBag).Error; err != nil {
+		t.Fatal(err)
+	}
+	users := make([]model.User, buyers)
+	for i := range users {
+		users[i] = model.User{Name: "Buyer", Email: fmt.Sprintf("buyer%d@example.com", i)}
+	}
+	if err := db.CreateInBatches(&users, 100).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	orderService := newTestOrderService(db)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		failures  []error
+	)
+	start := make(chan struct{})
+	for _, user := range users {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			<-start
+			_, err := orderService.PlaceOrder(userID, &model.OrderInput{FoodBagID: foodBag.ID, Quantity: 1})
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+				return
+			}
+			var soldOut *SoldOutError
+			if !errors.As(err, &soldOut) {
+				failures = append(failures, err)
+			}
+		}(user.ID)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range failures {
+		t.Errorf("PlaceOrder failed with an unexpected error: %v", err)
+	}
+	if succeeded != quantityTotal {
+		t.Errorf("%d orders succeeded, want %d", succeeded, quantityTotal)
+	}
+
+	var reloaded model.FoodBag
+	if err := db.First(&reloaded, foodBag.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.QuantityLeft != 0 {
+		t.Errorf("QuantityLeft = %d, want 0", reloaded.QuantityLeft)
+	}
+
+	var reserved int64
+	if err := db.Model(&model.OrderItem{}).Where("food_bag_id = ?", foodBag.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&reserved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reserved != quantityTotal {
+		t.Errorf("order items reserve %d bags, want %d", reserved, quantityTotal)
+	}
+}
@@ -0,0 +1,526 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderNotPayable    = errors.New("order cannot be paid in its current status")
+	ErrPaymentInProgress  = errors.New("order already has a payment in progress")
+	ErrPaymentDeclined    = errors.New("payment was declined")
+	ErrPaymentNotFound    = errors.New("payment not found")
+	ErrPaymentUnavailable = errors.New("payment provider is unavailable")
+
+	ErrNothingToRefund      = errors.New("order has no payment left to refund")
+	ErrRefundExceedsPayment = errors.New("refund amount exceeds what is left on the payment")
+	ErrRefundFailed         = errors.New("payment provider rejected the refund")
+)
+
+const (
+	// refundLease is how long a sender has to hear back from the provider
+	// before the refund may be sent again.
+	refundLease = 5 * time.Minute
+	// refundRetryDelay is the wait after the first failed attempt; it
+	// doubles with every further attempt.
+	refundRetryDelay = time.Minute
+	// maxRefundAttempts is how often a refund is sent before it is marked
+	// failed and its amount handed back to the payment.
+	maxRefundAttempts = 8
+	// refundBatchSize caps how many refunds a single retry run sends.
+	refundBatchSize = 100
+)
+
+// PaymentService takes payments for orders through a payment provider.
+//
+// Paying authorizes the order total. The provider reports the outcome through
+// a webhook; an authorized payment confirms the order and is captured right
+// away, since the bag is set aside for the buyer from then on. If the order
+// was cancelled before the authorization arrived, the hold is released.
+// Cancelling a paid order refunds whatever is left on its payment: the
+// refund is recorded in the cancelling transaction and sent afterwards, and
+// RetryRefunds sends it again for as long as the provider cannot be reached.
+type PaymentService struct {
+	db            *gorm.DB
+	provider      payment.Provider
+	paymentRepo   *repository.PaymentRepository
+	refundRepo    *repository.RefundRepository
+	orderRepo     *repository.OrderRepository
+	orderService  *OrderService
+	walletService *WalletService
+	currency      string
+}
+
+func NewPaymentService(db *gorm.DB, provider payment.Provider, paymentRepo *repository.PaymentRepository, refundRepo *repository.RefundRepository, orderRepo *repository.OrderRepository, orderService *OrderService, walletService *WalletService, currency string) *PaymentService {
+	s := &PaymentService{
+		db:            db,
+		provider:      provider,
+		paymentRepo:   paymentRepo,
+		refundRepo:    refundRepo,
+		orderRepo:     orderRepo,
+		orderService:  orderService,
+		walletService: walletService,
+		currency:      currency,
+	}
+	orderService.AddCancellationHandler(s)
+	orderService.AddCancellationListener(s)
+	return s
+}
+
+// Pay starts a payment for one of the buyer's pending orders.
+func (s *PaymentService) Pay(ctx context.Context, orderID, userID uint, paymentMethod string) (*model.Payment, error) {
+	if _, err := s.orderService.GetForBuyer(orderID, userID); err != nil {
+		return nil, err
+	}
+
+	var record *model.Payment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Locking the order serializes concurrent attempts to pay it.
+		order, err := s.orderRepo.WithTx(tx).GetByIDForUpdate(orderID)
+		if err != nil {
+			return err
+		}
+		if order.Status != model.OrderStatusPending {
+			return ErrOrderNotPayable
+		}
+
+		paymentRepo := s.paymentRepo.WithTx(tx)
+		open, err := paymentRepo.HasOpenPayment(order.ID)
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrPaymentInProgress
+		}
+
+		// The wallet share of the order is not charged again.
+		amount := payment.ToMinorUnits(order.TotalPrice) - payment.ToMinorUnits(order.WalletAmount)
+		if amount <= 0 {
+			return ErrOrderNotPayable
+		}
+
+		record = &model.Payment{
+			OrderID:       order.ID,
+			Provider:      s.provider.Name(),
+			PaymentMethod: paymentMethod,
+			Amount:        payment.FromMinorUnits(amount),
+			Currency:      s.currency,
+			Status:        model.PaymentStatusPending,
+		}
+		return paymentRepo.Create(record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.provider.Authorize(ctx, payment.AuthorizeRequest{
+		Reference:     fmt.Sprintf("payment-%d", record.ID),
+		Amount:        payment.ToMinorUnits(record.Amount),
+		Currency:      record.Currency,
+		PaymentMethod: paymentMethod,
+	})
+	if err != nil {
+		log.Printf("payment %d: authorize: %v", record.ID, err)
+		if _, updateErr := s.paymentRepo.UpdateStatusFrom(record.ID, model.PaymentStatusPending, map[string]interface{}{
+			"status":         model.PaymentStatusFailed,
+			"failure_reason": "provider_error",
+		}); updateErr != nil {
+			return nil, updateErr
+		}
+		return nil, ErrPaymentUnavailable
+	}
+
+	if err := s.paymentRepo.Update(record.ID, map[string]interface{}{"provider_ref": result.ProviderRef}); err != nil {
+		return nil, err
+	}
+	record.ProviderRef = result.ProviderRef
+
+	// Some providers answer synchronously instead of through a webhook.
+	switch result.Status {
+	case payment.StatusSucceeded:
+		if err := s.authorized(ctx, record); err != nil {
+			return nil, err
+		}
+	case payment.StatusFailed:
+		if err := s.failed(record, result.FailureReason); err != nil {
+			return nil, err
+		}
+		return nil, ErrPaymentDeclined
+	}
+
+	return s.paymentRepo.GetByID(record.ID)
+}
+
+// GetForOrder lists the payment attempts of an order for its buyer, the
+// store owner or an admin.
+func (s *PaymentService) GetForOrder(orderID, userID uint) ([]model.Payment, error) {
+	if _, err := s.orderService.GetForParticipant(orderID, userID); err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.GetByOrderID(orderID)
+}
+
+// HandleWebhook verifies and applies a provider callback. Providers deliver
+// at least once, so every event is safe to apply twice.
+func (s *PaymentService) HandleWebhook(ctx context.Context, body []byte, header http.Header) error {
+	event, err := s.provider.VerifyWebhook(body, header)
+	if err != nil {
+		return err
+	}
+
+	record, err := s.paymentRepo.GetByProviderRef(s.provider.Name(), event.ProviderRef)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPaymentNotFound
+		}
+		return err
+	}
+
+	switch event.Type {
+	case payment.EventAuthorized:
+		return s.authorized(ctx, record)
+	case payment.EventAuthorizationFailed:
+		return s.failed(record, event.FailureReason)
+	case payment.EventCaptured:
+		_, err := s.paymentRepo.UpdateStatusFrom(record.ID, model.PaymentStatusAuthorized, map[string]interface{}{
+			"status":      model.PaymentStatusCaptured,
+			"captured_at": event.CreatedAt,
+		})
+		return err
+	}
+	return nil
+}
+
+// authorized records a successful authorization, confirms the order and
+// captures the payment. When the order can no longer be confirmed the hold is
+// released instead.
+func (s *PaymentService) authorized(ctx context.Context, record *model.Payment) error {
+	updated, err := s.paymentRepo.UpdateStatusFrom(record.ID, model.PaymentStatusPending, map[string]interface{}{
+		"status":        model.PaymentStatusAuthorized,
+		"authorized_at": time.Now(),
+	})
+	if err != nil || !updated {
+		return err
+	}
+
+	err = s.orderService.ConfirmPayment(record.OrderID)
+	var transitionErr *OrderTransitionError
+	if errors.As(err, &transitionErr) {
+		_, err := s.refund(ctx, RefundRequest{
+			OrderID: record.OrderID,
+			Actor:   model.OrderActorSystem,
+			Reason:  "order was " + string(transitionErr.From) + " before payment completed",
+		}, true)
+		if errors.Is(err, ErrNothingToRefund) || errors.Is(err, ErrPaymentUnavailable) {
+			// An unreachable provider leaves the refund to RetryRefunds.
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := s.provider.Capture(ctx, record.ProviderRef, payment.ToMinorUnits(record.Amount))
+	if err != nil {
+		log.Printf("payment %d: capture: %v", record.ID, err)
+		return nil
+	}
+	if result.Status == payment.StatusSucceeded {
+		_, err = s.paymentRepo.UpdateStatusFrom(record.ID, model.PaymentStatusAuthorized, map[string]interface{}{
+			"status":      model.PaymentStatusCaptured,
+			"captured_at": time.Now(),
+		})
+	}
+	return err
+}
+
+// RefundRequest describes a refund. A zero Amount refunds everything that is
+// left on the payment. ToWallet pays the refund out as store credit instead
+// of returning it to the original payment method.
+type RefundRequest struct {
+	OrderID  uint
+	Amount   float64
+	Actor    model.OrderActor
+	ActorID  *uint
+	Reason   string
+	ToWallet bool
+}
+
+// Refund gives money back on an order's payment. The amount is reserved on
+// the payment before the provider is called, so concurrent refunds can never
+// add up to more than was paid; a rejected refund releases it again.
+func (s *PaymentService) Refund(ctx context.Context, req RefundRequest) (*model.Refund, error) {
+	return s.refund(ctx, req, false)
+}
+
+// refund reserves and sends a refund. With retry set, a provider that cannot
+// be reached leaves the refund pending for RetryRefunds and
+// ErrPaymentUnavailable is returned.
+func (s *PaymentService) refund(ctx context.Context, req RefundRequest, retry bool) (*model.Refund, error) {
+	var refund *model.Refund
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		refund, err = s.reserveRefund(tx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req.ToWallet {
+		return refund, nil
+	}
+
+	if err := s.sendRefund(ctx, refund, retry); err != nil {
+		return nil, err
+	}
+	return s.refundRepo.GetByID(refund.ID)
+}
+
+// reserveRefund records a refund in tx and takes its amount off the
+// payment. Refunds to the wallet are credited right away; others are left
+// pending, due to be sent.
+func (s *PaymentService) reserveRefund(tx *gorm.DB, req RefundRequest) (*model.Refund, error) {
+	paymentRepo := s.paymentRepo.WithTx(tx)
+
+	record, err := paymentRepo.GetRefundableForUpdate(req.OrderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNothingToRefund
+		}
+		return nil, err
+	}
+
+	remaining := payment.ToMinorUnits(record.Amount) - payment.ToMinorUnits(record.RefundedAmount)
+	amount := payment.ToMinorUnits(req.Amount)
+	if amount == 0 {
+		amount = remaining
+	}
+	if remaining <= 0 {
+		return nil, ErrNothingToRefund
+	}
+	if amount > remaining {
+		return nil, ErrRefundExceedsPayment
+	}
+
+	now := time.Now()
+	refund := &model.Refund{
+		OrderID:          record.OrderID,
+		PaymentID:        record.ID,
+		Amount:           payment.FromMinorUnits(amount),
+		Status:           model.RefundStatusPending,
+		Destination:      model.RefundToOriginalPayment,
+		RequestedByID:    req.ActorID,
+		RequestedByActor: req.Actor,
+		Reason:           req.Reason,
+		NextAttemptAt:    &now,
+	}
+	if req.ToWallet {
+		refund.Destination = model.RefundToWallet
+		refund.Status = model.RefundStatusSucceeded
+		refund.RefundedAt = &now
+		refund.NextAttemptAt = nil
+	}
+	if err := s.refundRepo.WithTx(tx).Create(refund); err != nil {
+		return nil, err
+	}
+
+	if req.ToWallet {
+		order, err := s.orderRepo.WithTx(tx).GetByIDForUpdate(record.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		err = s.walletService.Credit(tx, order.UserID, amount, &model.LedgerTransaction{
+			Type:        model.LedgerTransactionRefund,
+			OrderID:     &order.ID,
+			RefundID:    &refund.ID,
+			Description: req.Reason,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.setRefundedAmount(paymentRepo, record, payment.ToMinorUnits(record.RefundedAmount)+amount); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// sendRefund claims a pending refund and sends it to the provider. A
+// rejected refund is marked failed and released. When the provider cannot
+// be reached and retry is set, the refund stays pending and is due again
+// after a growing delay, until maxRefundAttempts is reached. A refund that
+// is not due or is being sent by someone else is left alone.
+func (s *PaymentService) sendRefund(ctx context.Context, refund *model.Refund, retry bool) error {
+	now := time.Now()
+	claimed, err := s.refundRepo.Claim(refund.ID, now, now.Add(refundLease))
+	if err != nil || !claimed {
+		return err
+	}
+	refund.Attempts++
+
+	record, err := s.paymentRepo.GetByID(refund.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.provider.Refund(ctx, record.ProviderRef, payment.ToMinorUnits(refund.Amount))
+	if err != nil {
+		log.Printf("refund %d: attempt %d: %v", refund.ID, refund.Attempts, err)
+		if retry && refund.Attempts < maxRefundAttempts {
+			next := now.Add(refundRetryDelay << (refund.Attempts - 1))
+			if err := s.refundRepo.Update(refund.ID, map[string]interface{}{"next_attempt_at": next}); err != nil {
+				return err
+			}
+			return ErrPaymentUnavailable
+		}
+		if err := s.releaseRefund(refund, "provider_error"); err != nil {
+			return err
+		}
+		return ErrRefundFailed
+	}
+	if result.Status == payment.StatusFailed {
+		if err := s.releaseRefund(refund, result.FailureReason); err != nil {
+			return err
+		}
+		return ErrRefundFailed
+	}
+
+	return s.refundRepo.Update(refund.ID, map[string]interface{}{
+		"status":          model.RefundStatusSucceeded,
+		"provider_ref":    result.ProviderRef,
+		"refunded_at":     time.Now(),
+		"next_attempt_at": nil,
+	})
+}
+
+// OrderCancelling records a full refund of a cancelled order in the
+// cancelling transaction. It implements CancellationHandler.
+func (s *PaymentService) OrderCancelling(tx *gorm.DB, order *model.Order, actor model.OrderActor, actorID *uint, reason string) error {
+	if reason == "" {
+		reason = "order cancelled"
+	}
+
+	_, err := s.reserveRefund(tx, RefundRequest{
+		OrderID: order.ID,
+		Actor:   actor,
+		ActorID: actorID,
+		Reason:  reason,
+	})
+	if errors.Is(err, ErrNothingToRefund) {
+		return nil
+	}
+	return err
+}
+
+// OrderCancelled sends the refund recorded for a cancelled order. Refunds
+// that cannot be sent now are left to RetryRefunds. It implements
+// CancellationListener.
+func (s *PaymentService) OrderCancelled(order *model.Order, actor model.OrderActor, actorID *uint, reason string) {
+	refunds, err := s.refundRepo.GetByOrderID(order.ID)
+	if err != nil {
+		log.Printf("order %d: automatic refund: %v", order.ID, err)
+		return
+	}
+	for i := range refunds {
+		if refunds[i].Status != model.RefundStatusPending {
+			continue
+		}
+		if err := s.sendRefund(context.Background(), &refunds[i], true); err != nil {
+			log.Printf("order %d: automatic refund: %v", order.ID, err)
+		}
+	}
+}
+
+// RetryRefunds sends pending refunds that are due, such as those whose
+// provider call failed or never happened. It is meant to be run
+// periodically by the scheduler.
+func (s *PaymentService) RetryRefunds(ctx context.Context) error {
+	refunds, err := s.refundRepo.GetDue(time.Now(), refundBatchSize)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for i := range refunds {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.sendRefund(ctx, &refunds[i], true); err != nil {
+			log.Printf("refund %d: retry: %v", refunds[i].ID, err)
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		log.Printf("refunds: sent %d pending refunds", sent)
+	}
+	return nil
+}
+
+// ListRefunds returns refunds newest first, optionally filtered by status.
+func (s *PaymentService) ListRefunds(status model.RefundStatus, limit, offset int) ([]model.Refund, int64, error) {
+	return s.refundRepo.List(status, limit, offset)
+}
+
+// releaseRefund marks a refund as failed and hands its amount back to the
+// payment.
+func (s *PaymentService) releaseRefund(refund *model.Refund, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		paymentRepo := s.paymentRepo.WithTx(tx)
+		record, err := paymentRepo.GetByIDForUpdate(refund.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		err = s.refundRepo.WithTx(tx).Update(refund.ID, map[string]interface{}{
+			"status":         model.RefundStatusFailed,
+			"failure_reason": reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		refunded := payment.ToMinorUnits(record.RefundedAmount) - payment.ToMinorUnits(refund.Amount)
+		return s.setRefundedAmount(paymentRepo, record, refunded)
+	})
+}
+
+// setRefundedAmount stores how much of a payment has been refunded and moves
+// its status to match.
+func (s *PaymentService) setRefundedAmount(paymentRepo *repository.PaymentRepository, record *model.Payment, refunded int64) error {
+	status := model.PaymentStatusPartiallyRefunded
+	switch {
+	case refunded >= payment.ToMinorUnits(record.Amount):
+		status = model.PaymentStatusRefunded
+	case refunded <= 0:
+		status = model.PaymentStatusCaptured
+		if record.CapturedAt == nil {
+			status = model.PaymentStatusAuthorized
+		}
+	}
+
+	return paymentRepo.Update(record.ID, map[string]interface{}{
+		"refunded_amount": payment.FromMinorUnits(refunded),
+		"status":          status,
+	})
+}
+
+func (s *PaymentService) failed(record *model.Payment, reason string) error {
+	_, err := s.paymentRepo.UpdateStatusFrom(record.ID, model.PaymentStatusPending, map[string]interface{}{
+		"status":         model.PaymentStatusFailed,
+		"failure_reason": reason,
+	})
+	return err
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/pkg/pickuptoken"
+	"github.com/skip2/go-qrcode"
+)
+
+// maxRedemptionClockSkew is how far in the future a synced redemption time
+// may lie before it is rejected.
+const maxRedemptionClockSkew = 5 * time.Minute
+
+const qrCodeSize = 320
+
+var (
+	ErrOrderNotCollectable     = errors.New("order can no longer be picked up")
+	ErrOrderNotPaid            = errors.New("order has not been paid yet")
+	ErrPickupTokenSeedRequired = errors.New("PICKUP_TOKEN_SEED must be set outside development")
+)
+
+type PickupTokenService struct {
+	key          ed25519.PrivateKey
+	orderService *OrderService
+}
+
+func NewPickupTokenService(key ed25519.PrivateKey, orderService *OrderService) *PickupTokenService {
+	return &PickupTokenService{
+		key:          key,
+		orderService: orderService,
+	}
+}
+
+// PickupTokenKey builds the signing key from a base64url-encoded 32-byte
+// seed. Without a seed the key is derived from devSecret, which keeps
+// tokens valid across restarts of a development setup. Deployed
+// environments pass no devSecret, so a missing seed is an error rather
+// than a key shared with another secret.
+func PickupTokenKey(seed, devSecret string) (ed25519.PrivateKey, error) {
+	if seed == "" {
+		if devSecret == "" {
+			return nil, ErrPickupTokenSeedRequired
+		}
+		derived := sha256.Sum256([]byte("pickup-token:" + devSecret))
+		return ed25519.NewKeyFromSeed(derived[:]), nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(seed)
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, errors.New("pickup token seed must be 32 bytes of unpadded base64url")
+	}
+	return ed25519.NewKeyFromSeed(raw), nil
+}
+
+func (s *PickupTokenService) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// IssueToken signs a pickup token for one of the buyer's paid orders. Store
+// devices accept tokens offline, so none is issued for an order that is
+// still waiting for payment. The token expires when the order can no
+// longer be collected.
+func (s *PickupTokenService) IssueToken(orderID, userID uint) (*model.PickupTokenResponse, error) {
+	order, err := s.orderService.GetForBuyer(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch order.Status {
+	case model.OrderStatusConfirmed, model.OrderStatusReady:
+	case model.OrderStatusPending:
+		return nil, ErrOrderNotPaid
+	default:
+		return nil, ErrOrderNotCollectable
+	}
+
+	claims := pickuptoken.Claims{
+		OrderID:   order.ID,
+		StoreID:   order.StoreID,
+		ExpiresAt: s.orderService.PickupDeadline(order),
+	}
+	return &model.PickupTokenResponse{
+		Token:     pickuptoken.Sign(s.key, claims),
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+// RenderQRCode encodes a token as a PNG or SVG QR code and returns the image
+// together with its content type.
+func (s *PickupTokenService) RenderQRCode(token, format string) ([]byte, string, error) {
+	qr, err := qrcode.New(token, qrcode.Medium)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case "", "png":
+		png, err := qr.PNG(qrCodeSize)
+		if err != nil {
+			return nil, "", err
+		}
+		return png, "image/png", nil
+	case "svg":
+		return renderSVG(qr.Bitmap()), "image/svg+xml", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported QR code format %q", format)
+	}
+}
+
+// Redeem verifies a scanned token and completes its order.
+func (s *PickupTokenService) Redeem(token string, userID uint) (*model.Order, error) {
+	claims, err := pickuptoken.Verify(s.PublicKey(), token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.orderService.RedeemPickup(claims.OrderID, claims.StoreID, userID, time.Now(), "")
+}
+
+// Sync processes pickups a store device accepted offline. Each redemption is
+// judged at the time it happened and reported on individually; orders that
+// were already completed are treated as synced.
+func (s *PickupTokenService) Sync(redemptions []model.PickupRedemptionInput, userID uint) []model.PickupRedemptionResult {
+	now := time.Now()
+	results := make([]model.PickupRedemptionResult, 0, len(redemptions))
+
+	for _, redemption := range redemptions {
+		result := model.PickupRedemptionResult{Token: redemption.Token}
+
+		claims, err := pickuptoken.Verify(s.PublicKey(), redemption.Token, redemption.RedeemedAt)
+		if err == nil && redemption.RedeemedAt.After(now.Add(maxRedemptionClockSkew)) {
+			err = errors.New("redemption time is in the future")
+		}
+		if err == nil {
+			result.OrderID = claims.OrderID
+			note := fmt.Sprintf("redeemed offline at %s", redemption.RedeemedAt.UTC().Format(time.RFC3339))
+			_, err = s.orderService.RedeemPickup(claims.OrderID, claims.StoreID, userID, redemption.RedeemedAt, note)
+		}
+
+		var transitionErr *OrderTransitionError
+		switch {
+		case err == nil:
+			result.Status = "completed"
+		case errors.As(err, &transitionErr) && transitionErr.From == model.OrderStatusCompleted:
+			result.Status = "already_completed"
+		default:
+			result.Status = "rejected"
+			result.Error = err.Error()
+			if transitionErr != nil {
+				result.Code = transitionErr.Code
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// renderSVG draws a QR bitmap as an SVG with one unit per module.
+func renderSVG(bitmap [][]bool) []byte {
+	size := len(bitmap)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	PromotionErrorNotFound         = "promo_not_found"
+	PromotionErrorInactive         = "promo_inactive"
+	PromotionErrorNotStarted       = "promo_not_started"
+	PromotionErrorExpired          = "promo_expired"
+	PromotionErrorExhausted        = "promo_exhausted"
+	PromotionErrorUserLimitReached = "promo_user_limit_reached"
+	PromotionErrorFirstOrderOnly   = "promo_first_order_only"
+	PromotionErrorNotApplicable    = "promo_not_applicable"
+	PromotionErrorMinOrderNotMet   = "promo_min_order_not_met"
+)
+
+var (
+	ErrPromotionNotFound  = errors.New("promotion not found")
+	ErrPromotionForbidden = errors.New("not authorized to manage this promotion")
+	ErrPromotionCodeTaken = errors.New("promo code is already in use")
+	ErrInvalidPromotion   = errors.New("invalid promotion")
+)
+
+// PromotionError describes why a promo code cannot be used.
+type PromotionError struct {
+	Code string
+}
+
+func (e *PromotionError) Error() string {
+	switch e.Code {
+	case PromotionErrorNotFound:
+		return "promo code does not exist"
+	case PromotionErrorInactive:
+		return "promo code is no longer active"
+	case PromotionErrorNotStarted:
+		return "promo code is not valid yet"
+	case PromotionErrorExpired:
+		return "promo code has expired"
+	case PromotionErrorExhausted:
+		return "promo code has been fully redeemed"
+	case PromotionErrorUserLimitReached:
+		return "you have already used this promo code"
+	case PromotionErrorFirstOrderOnly:
+		return "promo code is only valid on your first order"
+	case PromotionErrorMinOrderNotMet:
+		return "order total is below the promo code minimum"
+	default:
+		return "promo code does not apply to these items"
+	}
+}
+
+// appliedPromotion is a promotion priced against one of the orders being
+// placed.
+type appliedPromotion struct {
+	promotion *model.Promotion
+	order     *model.Order
+	discount  float64
+}
+
+type PromotionService struct {
+	promotionRepo *repository.PromotionRepository
+	orderRepo     *repository.OrderRepository
+	storeRepo     *repository.StoreRepository
+	userRepo      *repository.UserRepository
+}
+
+func NewPromotionService(promotionRepo *repository.PromotionRepository, orderRepo *repository.OrderRepository, storeRepo *repository.StoreRepository, userRepo *repository.UserRepository) *PromotionService {
+	return &PromotionService{
+		promotionRepo: promotionRepo,
+		orderRepo:     orderRepo,
+		storeRepo:     storeRepo,
+		userRepo:      userRepo,
+	}
+}
+
+// Evaluate checks a code against the unsaved orders of a checkout and picks
+// the order it discounts the most. Only items inside the promotion's store
+// and category scope count towards the discount. With lock set the
+// promotion row stays locked until tx ends, so concurrent checkouts cannot
+// redeem past the limits.
+func (s *PromotionService) Evaluate(tx *gorm.DB, userID uint, code string, orders []*model.Order, foodBags map[uint]*model.FoodBag, lock bool) (*appliedPromotion, error) {
+	promotionRepo := s.promotionRepo.WithTx(tx)
+
+	promotion, err := promotionRepo.GetByCode(code, lock)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &PromotionError{Code: PromotionErrorNotFound}
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case !promotion.IsActive:
+		return nil, &PromotionError{Code: PromotionErrorInactive}
+	case promotion.StartsAt != nil && now.Before(*promotion.StartsAt):
+		return nil, &PromotionError{Code: PromotionErrorNotStarted}
+	case promotion.EndsAt != nil && !now.Before(*promotion.EndsAt):
+		return nil, &PromotionError{Code: PromotionErrorExpired}
+	case promotion.MaxRedemptions > 0 && promotion.RedemptionCount >= promotion.MaxRedemptions:
+		return nil, &PromotionError{Code: PromotionErrorExhausted}
+	}
+
+	if promotion.MaxRedemptionsPerUser > 0 {
+		used, err := promotionRepo.CountUserRedemptions(promotion.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(promotion.MaxRedemptionsPerUser) {
+			return nil, &PromotionError{Code: PromotionErrorUserLimitReached}
+		}
+	}
+
+	if promotion.FirstOrderOnly {
+		placed, err := s.orderRepo.WithTx(tx).CountPlacedByUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		if placed > 0 {
+			return nil, &PromotionError{Code: PromotionErrorFirstOrderOnly}
+		}
+	}
+
+	var best *appliedPromotion
+	belowMinimum := false
+	for _, order := range orders {
+		if promotion.StoreID != nil && *promotion.StoreID != order.StoreID {
+			continue
+		}
+
+		eligible := 0.0
+		for _, item := range order.Items {
+			if promotion.Category != "" && !strings.EqualFold(foodBags[item.FoodBagID].Category, promotion.Category) {
+				continue
+			}
+			eligible += item.Subtotal
+		}
+		if eligible == 0 {
+			continue
+		}
+		if order.Subtotal < promotion.MinOrderAmount {
+			belowMinimum = true
+			continue
+		}
+
+		discount := promotionDiscount(promotion, eligible)
+		if discount > 0 && (best == nil || discount > best.discount) {
+			best = &appliedPromotion{promotion: promotion, order: order, discount: discount}
+		}
+	}
+
+	if best == nil {
+		if belowMinimum {
+			return nil, &PromotionError{Code: PromotionErrorMinOrderNotMet}
+		}
+		return nil, &PromotionError{Code: PromotionErrorNotApplicable}
+	}
+	return best, nil
+}
+
+// promotionDiscount is the discount on an eligible amount, rounded to cents
+// and never more than the amount itself.
+func promotionDiscount(promotion *model.Promotion, eligible float64) float64 {
+	discount := promotion.DiscountValue
+	if promotion.DiscountType == model.PromotionDiscountPercentage {
+		discount = eligible * promotion.DiscountValue / 100
+		if promotion.MaxDiscount > 0 {
+			discount = min(discount, promotion.MaxDiscount)
+		}
+	}
+	discount = min(discount, eligible)
+	return payment.FromMinorUnits(payment.ToMinorUnits(discount))
+}
+
+// Redeem records the promotion against its saved order and counts the use.
+func (s *PromotionService) Redeem(tx *gorm.DB, applied *appliedPromotion, userID uint) error {
+	promotionRepo := s.promotionRepo.WithTx(tx)
+
+	err := promotionRepo.CreateRedemption(&model.PromotionRedemption{
+		PromotionID:    applied.promotion.ID,
+		UserID:         userID,
+		OrderID:        applied.order.ID,
+		DiscountAmount: applied.discount,
+		Status:         model.PromotionRedemptionApplied,
+	})
+	if err != nil {
+		return err
+	}
+	return promotionRepo.AdjustRedemptionCount(applied.promotion.ID, 1)
+}
+
+// Release reverses the redemption on a cancelled order, if there is one, so
+// the use counts against neither the global nor the per-user limit.
+func (s *PromotionService) Release(tx *gorm.DB, orderID uint) error {
+	promotionRepo := s.promotionRepo.WithTx(tx)
+
+	redemption, err := promotionRepo.GetAppliedRedemption(orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := promotionRepo.UpdateRedemptionStatus(redemption.ID, model.PromotionRedemptionReversed); err != nil {
+		return err
+	}
+	return promotionRepo.AdjustRedemptionCount(redemption.PromotionID, -1)
+}
+
+// Create adds a promotion. Admins may create any promotion; sellers only
+// ones scoped to a store they own.
+func (s *PromotionService) Create(userID uint, input *model.PromotionInput) (*model.Promotion, error) {
+	if err := validatePromotionInput(input); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(userID, input.StoreID); err != nil {
+		return nil, err
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if err := s.ensureCodeFree(code, 0); err != nil {
+		return nil, err
+	}
+
+	promotion := &model.Promotion{CreatedByID: userID, IsActive: true}
+	applyPromotionInput(promotion, code, input)
+	if err := s.promotionRepo.Create(promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// Update replaces a promotion's settings. The redemption count is kept.
+func (s *PromotionService) Update(id, userID uint, input *model.PromotionInput) (*model.Promotion, error) {
+	if err := validatePromotionInput(input); err != nil {
+		return nil, err
+	}
+
+	promotion, err := s.promotionRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromotionNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorize(userID, promotion.StoreID); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(userID, input.StoreID); err != nil {
+		return nil, err
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if err := s.ensureCodeFree(code, promotion.ID); err != nil {
+		return nil, err
+	}
+
+	applyPromotionInput(promotion, code, input)
+	promotion.Store = nil
+	if err := s.promotionRepo.Update(promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// List returns every promotion to admins and the promotions of their own
+// stores to sellers.
+func (s *PromotionService) List(userID uint, limit, offset int) ([]model.Promotion, int64, error) {
+	user, err := s.userRepo.FindUserById(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if user.UserType == model.UserTypeAdmin {
+		return s.promotionRepo.List(nil, limit, offset)
+	}
+
+	stores, err := s.storeRepo.GetByOwnerID(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	storeIDs := make([]uint, len(stores))
+	for i, store := range stores {
+		storeIDs[i] = store.ID
+	}
+	return s.promotionRepo.List(storeIDs, limit, offset)
+}
+
+// authorize checks that userID may manage a promotion with the given scope.
+func (s *PromotionService) authorize(userID uint, storeID *uint) error {
+	user, err := s.userRepo.FindUserById(userID)
+	if err != nil {
+		return err
+	}
+	if user.UserType == model.UserTypeAdmin {
+		return nil
+	}
+	if storeID == nil {
+		return ErrPromotionForbidden
+	}
+
+	store, err := s.storeRepo.GetByID(*storeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPromotionForbidden
+		}
+		return err
+	}
+	if store.OwnerID != userID {
+		return ErrPromotionForbidden
+	}
+	return nil
+}
+
+func (s *PromotionService) ensureCodeFree(code string, ownID uint) error {
+	existing, err := s.promotionRepo.GetByCode(code, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if existing.ID != ownID {
+		return ErrPromotionCodeTaken
+	}
+	return nil
+}
+
+func validatePromotionInput(input *model.PromotionInput) error {
+	if strings.TrimSpace(input.Code) == "" {
+		return ErrInvalidPromotion
+	}
+	if input.DiscountType == model.PromotionDiscountPercentage && input.DiscountValue > 100 {
+		return ErrInvalidPromotion
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return ErrInvalidPromotion
+	}
+	return nil
+}
+
+func applyPromotionInput(promotion *model.Promotion, code string, input *model.PromotionInput) {
+	promotion.Code = code
+	promotion.Description = input.Description
+	promotion.DiscountType = input.DiscountType
+	promotion.DiscountValue = input.DiscountValue
+	promotion.MaxDiscount = input.MaxDiscount
+	promotion.MinOrderAmount = input.MinOrderAmount
+	promotion.StoreID = input.StoreID
+	promotion.Category = strings.TrimSpace(input.Category)
+	promotion.StartsAt = input.StartsAt
+	promotion.EndsAt = input.EndsAt
+	promotion.MaxRedemptions = input.MaxRedemptions
+	promotion.MaxRedemptionsPerUser = input.MaxRedemptionsPerUser
+	promotion.FirstOrderOnly = input.FirstOrderOnly
+	if input.IsActive != nil {
+		promotion.IsActive = *input.IsActive
+	}
+}
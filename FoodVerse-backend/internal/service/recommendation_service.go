@@ -0,0 +1,317 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+)
+
+const (
+	defaultRecommendationLimit = 10
+	maxRecommendationLimit     = 50
+	// recommendationCandidates is how many of the nearest bags on sale are
+	// scored.
+	recommendationCandidates = 100
+	// recommendationDefaultRadiusKm matches the nearby search default.
+	recommendationDefaultRadiusKm = 5.0
+	// tasteHistory and tasteHistoryOrders bound the orders a taste profile
+	// is built from.
+	tasteHistory       = 180 * 24 * time.Hour
+	tasteHistoryOrders = 100
+	// tasteConfidentOrders is how many orders it takes before the user's
+	// own history fully replaces popularity.
+	tasteConfidentOrders = 5
+	// popularityWindow is how far back orders count towards popularity.
+	popularityWindow = 30 * 24 * time.Hour
+)
+
+// Recommendation reasons, reported for each signal that scored well.
+const (
+	ReasonFavoriteCategory = "favorite_category"
+	ReasonOrderedHere      = "ordered_here_before"
+	ReasonGoodPrice        = "good_price"
+	ReasonUsualPickupTime  = "usual_pickup_time"
+	ReasonPopularNearby    = "popular_nearby"
+	ReasonCloseBy          = "close_by"
+)
+
+// reasonThreshold is the signal score from which a signal counts as a
+// reason for a recommendation.
+const reasonThreshold = 0.5
+
+// RecommendationWeights sets how much each signal counts towards a bag's
+// score. Only their ratios matter; scores are normalized to 0..1.
+type RecommendationWeights struct {
+	Category   float64
+	Store      float64
+	Price      float64
+	TimeOfDay  float64
+	Popularity float64
+	Distance   float64
+}
+
+// TasteProfile sums up a user's order history. Categories and Stores hold
+// each category's and store's share of the bags ordered, and PickupHours
+// the share of pickups starting in each hour of the store's local day.
+// TypicalPrice is the median price paid per bag.
+type TasteProfile struct {
+	Orders       int
+	Categories   map[string]float64
+	Stores       map[uint]float64
+	PickupHours  [24]float64
+	TypicalPrice float64
+}
+
+// BuildTasteProfile works out a taste profile from orders. Bags on orders
+// from before order items existed count at their listed price.
+func BuildTasteProfile(orders []model.Order) TasteProfile {
+	profile := TasteProfile{
+		Orders:     len(orders),
+		Categories: map[string]float64{},
+		Stores:     map[uint]float64{},
+	}
+
+	var prices []float64
+	bags := 0
+	add := func(foodBag *model.FoodBag, unitPrice float64, quantity int) {
+		if foodBag.ID == 0 {
+			return
+		}
+		if quantity < 1 {
+			quantity = 1
+		}
+		bags += quantity
+		if foodBag.Category != "" {
+			profile.Categories[foodBag.Category] += float64(quantity)
+		}
+		profile.Stores[foodBag.StoreID] += float64(quantity)
+		profile.PickupHours[foodBag.PickupTimeStart.In(foodBag.Store.Location()).Hour()] += float64(quantity)
+		for i := 0; i < quantity; i++ {
+			prices = append(prices, unitPrice)
+		}
+	}
+	for i := range orders {
+		order := &orders[i]
+		if len(order.Items) > 0 {
+			for j := range order.Items {
+				add(&order.Items[j].FoodBag, order.Items[j].UnitPrice, order.Items[j].Quantity)
+			}
+		} else {
+			add(&order.FoodBag, order.FoodBag.DiscountedPrice, order.Quantity)
+		}
+	}
+	if bags == 0 {
+		return profile
+	}
+
+	for category := range profile.Categories {
+		profile.Categories[category] /= float64(bags)
+	}
+	for store := range profile.Stores {
+		profile.Stores[store] /= float64(bags)
+	}
+	for hour := range profile.PickupHours {
+		profile.PickupHours[hour] /= float64(bags)
+	}
+	sort.Float64s(prices)
+	profile.TypicalPrice = prices[len(prices)/2]
+	return profile
+}
+
+// RecommendationCandidate is a bag on sale with what scoring needs to know
+// about it besides the user. Popularity and Closeness run from 0 to 1,
+// relative to the other candidates and the search radius.
+type RecommendationCandidate struct {
+	Category   string
+	StoreID    uint
+	Price      float64
+	PickupHour int
+	Popularity float64
+	Closeness  float64
+}
+
+// Score rates how well a candidate suits a profile, from 0 to 1, with the
+// reasons behind it. The user's own signals count in proportion to how much
+// history they have; for users without orders their weight goes to
+// popularity instead.
+func (w RecommendationWeights) Score(profile TasteProfile, c RecommendationCandidate) (float64, []string) {
+	total := w.Category + w.Store + w.Price + w.TimeOfDay + w.Popularity + w.Distance
+	if total <= 0 {
+		return 0, nil
+	}
+
+	confidence := float64(profile.Orders) / tasteConfidentOrders
+	if confidence > 1 {
+		confidence = 1
+	}
+
+	signals := []struct {
+		weight float64
+		score  float64
+		reason string
+	}{
+		{w.Category * confidence, relativeShare(profile.Categories[c.Category], maxShare(profile.Categories)), ReasonFavoriteCategory},
+		{w.Store * confidence, relativeShare(profile.Stores[c.StoreID], maxShare(profile.Stores)), ReasonOrderedHere},
+		{w.Price * confidence, priceScore(profile.TypicalPrice, c.Price), ReasonGoodPrice},
+		{w.TimeOfDay * confidence, pickupHourScore(profile.PickupHours, c.PickupHour), ReasonUsualPickupTime},
+		{w.Popularity + (w.Category+w.Store+w.Price+w.TimeOfDay)*(1-confidence), c.Popularity, ReasonPopularNearby},
+		{w.Distance, c.Closeness, ReasonCloseBy},
+	}
+
+	score := 0.0
+	reasons := []string{}
+	for _, signal := range signals {
+		score += signal.weight * signal.score
+		if signal.weight > 0 && signal.score >= reasonThreshold {
+			reasons = append(reasons, signal.reason)
+		}
+	}
+	return score / total, reasons
+}
+
+// relativeShare scales a share against the largest one, so the user's
+// favorite scores 1.
+func relativeShare(share, largest float64) float64 {
+	if largest <= 0 {
+		return 0
+	}
+	return share / largest
+}
+
+func maxShare[K comparable](shares map[K]float64) float64 {
+	largest := 0.0
+	for _, share := range shares {
+		if share > largest {
+			largest = share
+		}
+	}
+	return largest
+}
+
+// priceScore is 1 for a bag at or below the price the user typically pays
+// and falls off as it gets dearer.
+func priceScore(typical, price float64) float64 {
+	if typical <= 0 {
+		return 0
+	}
+	if price <= typical {
+		return 1
+	}
+	return typical / price
+}
+
+// pickupHourScore is the share of the user's pickups within an hour of
+// hour, against the busiest such span of their day.
+func pickupHourScore(hours [24]float64, hour int) float64 {
+	around := func(h int) float64 {
+		return hours[(h+23)%24] + hours[h] + hours[(h+1)%24]
+	}
+	best := 0.0
+	for h := range hours {
+		if share := around(h); share > best {
+			best = share
+		}
+	}
+	if best <= 0 {
+		return 0
+	}
+	return around(hour) / best
+}
+
+// RecommendationService ranks the bags on sale near a user by how well
+// they suit the user's order history.
+type RecommendationService struct {
+	foodBagRepo *repository.FoodBagRepository
+	orderRepo   *repository.OrderRepository
+	weights     RecommendationWeights
+}
+
+func NewRecommendationService(foodBagRepo *repository.FoodBagRepository, orderRepo *repository.OrderRepository, weights RecommendationWeights) *RecommendationService {
+	return &RecommendationService{
+		foodBagRepo: foodBagRepo,
+		orderRepo:   orderRepo,
+		weights:     weights,
+	}
+}
+
+// Recommend returns the best suited of the nearest bags on sale, best
+// first.
+func (s *RecommendationService) Recommend(userID uint, req model.RecommendationRequest) ([]model.RecommendedFoodBag, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultRecommendationLimit
+	}
+	if limit > maxRecommendationLimit {
+		limit = maxRecommendationLimit
+	}
+	radius := req.Radius
+	if radius <= 0 {
+		radius = recommendationDefaultRadiusKm
+	}
+
+	now := time.Now()
+	orders, err := s.orderRepo.GetRecentPlacedByUser(userID, now.Add(-tasteHistory), tasteHistoryOrders)
+	if err != nil {
+		return nil, err
+	}
+	profile := BuildTasteProfile(orders)
+
+	nearby, err := s.foodBagRepo.SearchNearby(model.FoodBagSearchRequest{
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		Radius:      radius,
+		PageRequest: model.PageRequest{Limit: recommendationCandidates, Sort: "distance"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	storeIDs := []uint{}
+	for _, foodBag := range nearby.Data {
+		storeIDs = append(storeIDs, foodBag.Store.ID)
+	}
+	orderCounts, err := s.orderRepo.CountRecentByStore(storeIDs, now.Add(-popularityWindow))
+	if err != nil {
+		return nil, err
+	}
+	mostOrders := int64(0)
+	for _, count := range orderCounts {
+		if count > mostOrders {
+			mostOrders = count
+		}
+	}
+
+	recommendations := make([]model.RecommendedFoodBag, 0, len(nearby.Data))
+	for _, foodBag := range nearby.Data {
+		candidate := RecommendationCandidate{
+			Category:   foodBag.Category,
+			StoreID:    foodBag.Store.ID,
+			Price:      foodBag.CurrentPrice,
+			PickupHour: foodBag.PickupWindow.StartLocal.Hour(),
+			Closeness:  1 - foodBag.Store.Distance/radius,
+		}
+		if candidate.Closeness < 0 {
+			candidate.Closeness = 0
+		}
+		if mostOrders > 0 {
+			candidate.Popularity = float64(orderCounts[foodBag.Store.ID]) / float64(mostOrders)
+		}
+
+		score, reasons := s.weights.Score(profile, candidate)
+		recommendations = append(recommendations, model.RecommendedFoodBag{
+			FoodBagResponse: foodBag,
+			Score:           score,
+			Reasons:         reasons,
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Score > recommendations[j].Score
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// TestRecommendationScore checks how the user's own signals hand their
+// weight to popularity when there is little or no order history.
+func TestRecommendationScore(t *testing.T) {
+	even := RecommendationWeights{Category: 1, Store: 1, Price: 1, TimeOfDay: 1, Popularity: 1, Distance: 1}
+	shortHistory := TasteProfile{Orders: 2, Categories: map[string]float64{"bakery": 1}}
+
+	tests := []struct {
+		name        string
+		weights     RecommendationWeights
+		profile     TasteProfile
+		candidate   RecommendationCandidate
+		want        float64
+		wantReasons []string
+	}{
+		{
+			name:        "new user goes by popularity",
+			weights:     even,
+			candidate:   RecommendationCandidate{Category: "bakery", Popularity: 0.8, Closeness: 0.2},
+			want:        (5*0.8 + 0.2) / 6,
+			wantReasons: []string{ReasonPopularNearby},
+		},
+		{
+			name:        "short history shares weight with popularity",
+			weights:     RecommendationWeights{Category: 1},
+			profile:     shortHistory,
+			candidate:   RecommendationCandidate{Category: "bakery", Popularity: 1},
+			want:        0.4 + 0.6,
+			wantReasons: []string{ReasonFavoriteCategory, ReasonPopularNearby},
+		},
+		{
+			name:        "short history, unpopular bag",
+			weights:     RecommendationWeights{Category: 1},
+			profile:     shortHistory,
+			candidate:   RecommendationCandidate{Category: "bakery"},
+			want:        0.4,
+			wantReasons: []string{ReasonFavoriteCategory},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reasons := tt.weights.Score(tt.profile, tt.candidate)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score = %g, want %g", got, tt.want)
+			}
+			if !slices.Equal(reasons, tt.wantReasons) {
+				t.Errorf("reasons = %v, want %v", reasons, tt.wantReasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,560 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+const localDateLayout = "2006-01-02"
+
+var (
+	ErrTemplateNotFound      = errors.New("food bag template not found")
+	ErrScheduleNotFound      = errors.New("food bag schedule not found")
+	ErrScheduleForbidden     = errors.New("not authorized to manage this store's schedules")
+	ErrInvalidSchedule       = errors.New("invalid schedule")
+	ErrScheduledBagHasOrders = errors.New("the food bag for this date already has orders")
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ScheduleService manages food bag templates and the recurring schedules
+// that list them. Generate turns schedules into food bags a few days ahead,
+// working in each store's timezone so a bag set for 18:00 stays at 18:00
+// local time across daylight saving changes.
+type ScheduleService struct {
+	db           *gorm.DB
+	templateRepo *repository.FoodBagTemplateRepository
+	scheduleRepo *repository.FoodBagScheduleRepository
+	foodBagRepo  *repository.FoodBagRepository
+	storeRepo    *repository.StoreRepository
+	// lookahead is how many local days, today included, bags are created
+	// for in advance.
+	lookahead int
+
+	listingListeners []ListingListener
+}
+
+// ListingListener is told about every food bag that goes on sale, newly
+// listed or back on sale, once the change has been committed.
+type ListingListener interface {
+	FoodBagListed(foodBag *model.FoodBag)
+}
+
+func NewScheduleService(db *gorm.DB, templateRepo *repository.FoodBagTemplateRepository, scheduleRepo *repository.FoodBagScheduleRepository, foodBagRepo *repository.FoodBagRepository, storeRepo *repository.StoreRepository, lookahead int) *ScheduleService {
+	return &ScheduleService{
+		db:           db,
+		templateRepo: templateRepo,
+		scheduleRepo: scheduleRepo,
+		foodBagRepo:  foodBagRepo,
+		storeRepo:    storeRepo,
+		lookahead:    lookahead,
+	}
+}
+
+// AddListingListener registers a listener for bags going on sale.
+func (s *ScheduleService) AddListingListener(listener ListingListener) {
+	s.listingListeners = append(s.listingListeners, listener)
+}
+
+func (s *ScheduleService) notifyListed(foodBag *model.FoodBag) {
+	for _, listener := range s.listingListeners {
+		listener.FoodBagListed(foodBag)
+	}
+}
+
+// Generate creates the food bags every running schedule has coming up. It is
+// safe to run repeatedly: each schedule gets at most one bag per date, and
+// a bag that was deleted or skipped is not created again. It is meant to run
+// as a scheduled job.
+func (s *ScheduleService) Generate(ctx context.Context) error {
+	schedules, err := s.scheduleRepo.GetActive()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	created := 0
+	var firstErr error
+	for i := range schedules {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// One broken schedule should not hold up the others.
+		n, err := s.generateSchedule(&schedules[i], now)
+		created += n
+		if err != nil {
+			log.Printf("schedules: schedule %d: %v", schedules[i].ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if created > 0 {
+		log.Printf("schedules: created %d food bags", created)
+	}
+	return firstErr
+}
+
+func (s *ScheduleService) generateSchedule(schedule *model.FoodBagSchedule, now time.Time) (int, error) {
+	// Closed or deleted stores list nothing.
+	if schedule.Store.ID == 0 || !schedule.Store.IsActive {
+		return 0, nil
+	}
+
+	days, err := parseWeekdays(schedule.Weekdays)
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := map[string]bool{}
+	for _, skip := range schedule.Skips {
+		skipped[skip.Date] = true
+	}
+
+	loc := schedule.Store.Location()
+	today := now.In(loc)
+	created := 0
+	lastDate := ""
+	for offset := 0; offset < s.lookahead; offset++ {
+		day := time.Date(today.Year(), today.Month(), today.Day()+offset, 0, 0, 0, 0, loc)
+		date := day.Format(localDateLayout)
+		lastDate = date
+
+		if !days[day.Weekday()] || skipped[date] {
+			continue
+		}
+		if schedule.StartsOn != "" && date < schedule.StartsOn || schedule.EndsOn != "" && date > schedule.EndsOn {
+			continue
+		}
+
+		start, end, err := pickupWindow(day, schedule.PickupStart, schedule.PickupEnd)
+		if err != nil {
+			return created, err
+		}
+		// Past windows and days the store is closed for are left out.
+		if !end.After(now) || !schedule.Store.CoversWindow(start, end) {
+			continue
+		}
+
+		template := schedule.Template
+		scheduleID := schedule.ID
+		foodBag := &model.FoodBag{
+			StoreID:         schedule.StoreID,
+			Title:           template.Title,
+			Description:     template.Description,
+			OriginalPrice:   template.OriginalPrice,
+			DiscountedPrice: template.DiscountedPrice,
+			QuantityTotal:   template.QuantityTotal,
+			PickupTimeStart: start,
+			PickupTimeEnd:   end,
+			ImageURL:        template.ImageURL,
+			Category:        template.Category,
+			IsActive:        true,
+			PricingStrategy: template.PricingStrategy,
+			PriceFloor:      template.PriceFloor,
+			PriceSteps:      template.PriceSteps,
+			ScheduleID:      &scheduleID,
+			ScheduledDate:   date,
+		}
+		ok, err := s.foodBagRepo.CreateScheduled(foodBag)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+			s.notifyListed(foodBag)
+		}
+	}
+
+	if lastDate != "" && lastDate != schedule.GeneratedThrough {
+		if err := s.scheduleRepo.UpdateFields(schedule.ID, map[string]interface{}{"generated_through": lastDate}); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// pickupWindow places "HH:MM" pickup times on a local day. An end at or
+// before the start falls on the next day.
+func pickupWindow(day time.Time, startClock, endClock string) (time.Time, time.Time, error) {
+	startAt, err := time.Parse("15:04", startClock)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: pickup_start must be HH:MM", ErrInvalidSchedule)
+	}
+	endAt, err := time.Parse("15:04", endClock)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: pickup_end must be HH:MM", ErrInvalidSchedule)
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), startAt.Hour(), startAt.Minute(), 0, 0, day.Location())
+	endDay := day.Day()
+	if !endAt.After(startAt) {
+		endDay++
+	}
+	end := time.Date(day.Year(), day.Month(), endDay, endAt.Hour(), endAt.Minute(), 0, 0, day.Location())
+	return start, end, nil
+}
+
+// parseWeekdays reads a list such as "mon,wed,fri".
+func parseWeekdays(weekdays string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, name := range strings.Split(weekdays, ",") {
+		day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%w: weekdays must be a comma-separated list of mon, tue, wed, thu, fri, sat, sun", ErrInvalidSchedule)
+		}
+		days[day] = true
+	}
+	return days, nil
+}
+
+// CreateTemplate adds a food bag template to one of the seller's stores.
+func (s *ScheduleService) CreateTemplate(userID uint, input *model.FoodBagTemplateInput) (*model.FoodBagTemplate, error) {
+	if err := validateTemplateInput(input); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeStore(input.StoreID, userID); err != nil {
+		return nil, err
+	}
+
+	template := &model.FoodBagTemplate{StoreID: input.StoreID}
+	applyTemplateInput(template, input)
+	if err := s.templateRepo.Create(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// ListTemplates returns the templates of every store the seller owns.
+func (s *ScheduleService) ListTemplates(userID uint) ([]model.FoodBagTemplate, error) {
+	storeIDs, err := s.ownedStoreIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.templateRepo.GetByStoreIDs(storeIDs)
+}
+
+// UpdateTemplate changes a template. Bags that were already created keep
+// their old details; only future bags pick up the change.
+func (s *ScheduleService) UpdateTemplate(id, userID uint, input *model.FoodBagTemplateInput) (*model.FoodBagTemplate, error) {
+	if err := validateTemplateInput(input); err != nil {
+		return nil, err
+	}
+
+	template, err := s.getTemplate(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if input.StoreID != template.StoreID {
+		return nil, fmt.Errorf("%w: a template cannot move to another store", ErrInvalidSchedule)
+	}
+
+	applyTemplateInput(template, input)
+	if err := s.templateRepo.Update(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// DeleteTemplate removes a template together with its schedules. Bags that
+// were already created stay listed.
+func (s *ScheduleService) DeleteTemplate(id, userID uint) error {
+	if _, err := s.getTemplate(id, userID); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.scheduleRepo.WithTx(tx).DeleteByTemplateID(id); err != nil {
+			return err
+		}
+		return s.templateRepo.WithTx(tx).Delete(id)
+	})
+}
+
+// CreateSchedule starts listing a template on a recurring basis. The first
+// bags appear the next time the generator runs.
+func (s *ScheduleService) CreateSchedule(userID uint, input *model.FoodBagScheduleInput) (*model.FoodBagSchedule, error) {
+	template, err := s.getTemplate(input.TemplateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateScheduleInput(input); err != nil {
+		return nil, err
+	}
+
+	schedule := &model.FoodBagSchedule{TemplateID: template.ID, StoreID: template.StoreID}
+	applyScheduleInput(schedule, input)
+	if err := s.scheduleRepo.Create(schedule); err != nil {
+		return nil, err
+	}
+	return s.scheduleRepo.GetByID(schedule.ID)
+}
+
+// ListSchedules returns the schedules of every store the seller owns.
+func (s *ScheduleService) ListSchedules(userID uint) ([]model.FoodBagSchedule, error) {
+	storeIDs, err := s.ownedStoreIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.scheduleRepo.GetByStoreIDs(storeIDs)
+}
+
+// UpdateSchedule changes a schedule's recurrence. Bags that were already
+// created are left as they are.
+func (s *ScheduleService) UpdateSchedule(id, userID uint, input *model.FoodBagScheduleInput) (*model.FoodBagSchedule, error) {
+	schedule, err := s.getSchedule(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	template, err := s.getTemplate(input.TemplateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if template.StoreID != schedule.StoreID {
+		return nil, fmt.Errorf("%w: the template belongs to another store", ErrInvalidSchedule)
+	}
+	if err := validateScheduleInput(input); err != nil {
+		return nil, err
+	}
+
+	schedule.TemplateID = template.ID
+	applyScheduleInput(schedule, input)
+	if err := s.scheduleRepo.Update(schedule); err != nil {
+		return nil, err
+	}
+	return s.scheduleRepo.GetByID(schedule.ID)
+}
+
+func (s *ScheduleService) DeleteSchedule(id, userID uint) error {
+	if _, err := s.getSchedule(id, userID); err != nil {
+		return err
+	}
+	return s.scheduleRepo.Delete(id)
+}
+
+// SetPaused pauses or resumes a schedule. A paused schedule creates no new
+// bags; bags it already created stay listed.
+func (s *ScheduleService) SetPaused(id, userID uint, paused bool) (*model.FoodBagSchedule, error) {
+	if _, err := s.getSchedule(id, userID); err != nil {
+		return nil, err
+	}
+	if err := s.scheduleRepo.UpdateFields(id, map[string]interface{}{"is_paused": paused}); err != nil {
+		return nil, err
+	}
+	return s.scheduleRepo.GetByID(id)
+}
+
+// SkipDate keeps a schedule from listing a bag on one local date. If the bag
+// was already created it is taken off sale, unless someone has ordered it.
+func (s *ScheduleService) SkipDate(id, userID uint, date string) (*model.FoodBagSchedule, error) {
+	if _, err := s.getSchedule(id, userID); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse(localDateLayout, date); err != nil {
+		return nil, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidSchedule)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		foodBagRepo := s.foodBagRepo.WithTx(tx)
+
+		foodBag, err := foodBagRepo.GetBySchedule(id, date)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+		case err != nil:
+			return err
+		default:
+			locked, err := foodBagRepo.GetByIDForUpdate(foodBag.ID)
+			if err != nil {
+				return err
+			}
+			if locked.QuantityLeft < locked.QuantityTotal {
+				return ErrScheduledBagHasOrders
+			}
+			locked.IsActive = false
+			if _, err := foodBagRepo.Update(locked); err != nil {
+				return err
+			}
+		}
+
+		return s.scheduleRepo.WithTx(tx).AddSkip(id, date)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.scheduleRepo.GetByID(id)
+}
+
+// UnskipDate lists a skipped date again. A bag that was taken off sale when
+// the date was skipped goes back on sale if it can still be picked up.
+func (s *ScheduleService) UnskipDate(id, userID uint, date string) (*model.FoodBagSchedule, error) {
+	if _, err := s.getSchedule(id, userID); err != nil {
+		return nil, err
+	}
+
+	var relisted *model.FoodBag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		removed, err := s.scheduleRepo.WithTx(tx).RemoveSkip(id, date)
+		if err != nil || !removed {
+			return err
+		}
+
+		foodBagRepo := s.foodBagRepo.WithTx(tx)
+		foodBag, err := foodBagRepo.GetBySchedule(id, date)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if foodBag.IsActive || !foodBag.PickupTimeEnd.After(time.Now()) {
+			return nil
+		}
+		foodBag.IsActive = true
+		if _, err := foodBagRepo.Update(foodBag); err != nil {
+			return err
+		}
+		relisted = foodBag
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if relisted != nil {
+		s.notifyListed(relisted)
+	}
+	return s.scheduleRepo.GetByID(id)
+}
+
+func (s *ScheduleService) getTemplate(id, userID uint) (*model.FoodBagTemplate, error) {
+	template, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorizeStore(template.StoreID, userID); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *ScheduleService) getSchedule(id, userID uint) (*model.FoodBagSchedule, error) {
+	schedule, err := s.scheduleRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorizeStore(schedule.StoreID, userID); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *ScheduleService) authorizeStore(storeID, userID uint) error {
+	store, err := s.storeRepo.GetByID(storeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrStoreNotFound
+		}
+		return err
+	}
+	if store.OwnerID != userID {
+		return ErrScheduleForbidden
+	}
+	return nil
+}
+
+func (s *ScheduleService) ownedStoreIDs(userID uint) ([]uint, error) {
+	stores, err := s.storeRepo.GetByOwnerID(userID)
+	if err != nil {
+		return nil, err
+	}
+	storeIDs := make([]uint, len(stores))
+	for i, store := range stores {
+		storeIDs[i] = store.ID
+	}
+	return storeIDs, nil
+}
+
+func validateTemplateInput(input *model.FoodBagTemplateInput) error {
+	if input.PricingStrategy == "" {
+		input.PricingStrategy = model.PricingFixed
+	}
+	if input.PricingStrategy != model.PricingFixed && (input.PriceFloor <= 0 || input.PriceFloor >= input.DiscountedPrice) {
+		return fmt.Errorf("%w: price_floor must be above zero and below discounted_price", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+func validateScheduleInput(input *model.FoodBagScheduleInput) error {
+	if _, err := parseWeekdays(input.Weekdays); err != nil {
+		return err
+	}
+	start, end, err := pickupWindow(time.Now(), input.PickupStart, input.PickupEnd)
+	if err != nil {
+		return err
+	}
+	if end.Sub(start) > model.MaxPickupWindow {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, model.ErrPickupWindowLength)
+	}
+	for _, date := range []string{input.StartsOn, input.EndsOn} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(localDateLayout, date); err != nil {
+			return fmt.Errorf("%w: starts_on and ends_on must be YYYY-MM-DD", ErrInvalidSchedule)
+		}
+	}
+	if input.StartsOn != "" && input.EndsOn != "" && input.EndsOn < input.StartsOn {
+		return fmt.Errorf("%w: ends_on is before starts_on", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+func applyTemplateInput(template *model.FoodBagTemplate, input *model.FoodBagTemplateInput) {
+	template.Title = input.Title
+	template.Description = input.Description
+	template.OriginalPrice = input.OriginalPrice
+	template.DiscountedPrice = input.DiscountedPrice
+	template.QuantityTotal = input.QuantityTotal
+	template.ImageURL = input.ImageURL
+	template.Category = input.Category
+	template.PricingStrategy = input.PricingStrategy
+	template.PriceFloor = input.PriceFloor
+	template.PriceSteps = input.PriceSteps
+}
+
+// applyScheduleInput copies input onto a schedule, normalizing weekdays to
+// lower case without spaces.
+func applyScheduleInput(schedule *model.FoodBagSchedule, input *model.FoodBagScheduleInput) {
+	names := strings.Split(input.Weekdays, ",")
+	for i, name := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	schedule.Weekdays = strings.Join(names, ",")
+	schedule.PickupStart = input.PickupStart
+	schedule.PickupEnd = input.PickupEnd
+	schedule.StartsOn = input.StartsOn
+	schedule.EndsOn = input.EndsOn
+}
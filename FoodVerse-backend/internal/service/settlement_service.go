@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payout"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrStoreForbidden     = errors.New("not authorized to view this store's payouts")
+	ErrPayoutNotFound     = errors.New("payout not found")
+	ErrPayoutNotRetryable = errors.New("only failed payouts can be retried")
+)
+
+// payoutPeriodAnchor is a Monday, so weekly periods run Monday to Monday UTC.
+var payoutPeriodAnchor = time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+
+// SettlementConfig holds the commercial terms stores are paid out on.
+type SettlementConfig struct {
+	// CommissionRate is the platform's share, between 0 and 1, of what a
+	// store sells after store-funded discounts.
+	CommissionRate float64
+	Period         time.Duration
+	Currency       string
+}
+
+// SettlementService works out what stores are owed and pays them.
+//
+// A completed order counts towards the period it was picked up in, less the
+// platform commission and any discount from the store's own promo codes.
+// Refunds on completed orders are taken off again, commission included. When
+// a period closes every store with something to settle gets one payout, and
+// the payout provider is asked to send the net amount. Orders or refunds
+// that turn up after their period was settled, e.g. pickups synced late,
+// roll into the next payout.
+type SettlementService struct {
+	db         *gorm.DB
+	payoutRepo *repository.PayoutRepository
+	storeRepo  *repository.StoreRepository
+	userRepo   *repository.UserRepository
+	provider   payout.Provider
+	config     SettlementConfig
+}
+
+func NewSettlementService(db *gorm.DB, payoutRepo *repository.PayoutRepository, storeRepo *repository.StoreRepository, userRepo *repository.UserRepository, provider payout.Provider, config SettlementConfig) *SettlementService {
+	return &SettlementService{
+		db:         db,
+		payoutRepo: payoutRepo,
+		storeRepo:  storeRepo,
+		userRepo:   userRepo,
+		provider:   provider,
+		config:     config,
+	}
+}
+
+// PeriodStart returns the start of the payout period containing t.
+func (s *SettlementService) PeriodStart(t time.Time) time.Time {
+	periods := t.UTC().Sub(payoutPeriodAnchor) / s.config.Period
+	return payoutPeriodAnchor.Add(periods * s.config.Period)
+}
+
+// Settle creates payouts for the period that last closed and sends them. It
+// is safe to run repeatedly; stores that were already settled for the
+// period are skipped. It is meant to run as a scheduled job.
+func (s *SettlementService) Settle(ctx context.Context) error {
+	periodEnd := s.PeriodStart(time.Now())
+	periodStart := periodEnd.Add(-s.config.Period)
+
+	orders, err := s.payoutRepo.UnsettledOrders(0, periodEnd)
+	if err != nil {
+		return err
+	}
+	refunds, err := s.payoutRepo.UnsettledRefunds(0, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	ordersByStore := map[uint][]repository.SettledOrder{}
+	refundsByStore := map[uint][]repository.SettledRefund{}
+	seen := map[uint]bool{}
+	var storeIDs []uint
+	for _, order := range orders {
+		ordersByStore[order.StoreID] = append(ordersByStore[order.StoreID], order)
+		if !seen[order.StoreID] {
+			seen[order.StoreID] = true
+			storeIDs = append(storeIDs, order.StoreID)
+		}
+	}
+	for _, refund := range refunds {
+		refundsByStore[refund.StoreID] = append(refundsByStore[refund.StoreID], refund)
+		if !seen[refund.StoreID] {
+			seen[refund.StoreID] = true
+			storeIDs = append(storeIDs, refund.StoreID)
+		}
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+
+	var firstErr error
+	for _, storeID := range storeIDs {
+		created, err := s.settleStore(storeID, periodStart, periodEnd, ordersByStore[storeID], refundsByStore[storeID])
+		if err == nil && created != nil && created.Status == model.PayoutStatusPending {
+			err = s.transfer(ctx, created)
+		}
+		if err != nil {
+			log.Printf("settlement: store %d: %v", storeID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// settleStore records one store's payout for a period. It returns nil when
+// the store was already settled for that period.
+func (s *SettlementService) settleStore(storeID uint, periodStart, periodEnd time.Time, orders []repository.SettledOrder, refunds []repository.SettledRefund) (*model.Payout, error) {
+	var created *model.Payout
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		payoutRepo := s.payoutRepo.WithTx(tx)
+
+		// Serializes settlement runs for the store.
+		if err := s.storeRepo.WithTx(tx).LockByID(storeID); err != nil {
+			return err
+		}
+
+		exists, err := payoutRepo.ExistsForPeriod(storeID, periodStart)
+		if err != nil || exists {
+			return err
+		}
+
+		carried, err := payoutRepo.GetCarriedForward(storeID, true)
+		if err != nil {
+			return err
+		}
+
+		totals := s.settlementTotals(orders, refunds, carried)
+		record := &model.Payout{
+			StoreID:        storeID,
+			PeriodStart:    periodStart,
+			PeriodEnd:      periodEnd,
+			CommissionRate: s.config.CommissionRate,
+			Gross:          totals.Gross,
+			Discounts:      totals.Discounts,
+			Commission:     totals.Commission,
+			Refunds:        totals.Refunds,
+			CarriedOver:    totals.CarriedOver,
+			Net:            totals.Net,
+			Currency:       s.config.Currency,
+			Status:         model.PayoutStatusPending,
+			Provider:       s.provider.Name(),
+			Lines:          totals.Lines,
+		}
+		switch {
+		case totals.Net < 0:
+			record.Status = model.PayoutStatusCarriedForward
+		case totals.Net == 0:
+			now := time.Now()
+			record.Status = model.PayoutStatusPaid
+			record.PaidAt = &now
+		}
+
+		if err := payoutRepo.Create(record); err != nil {
+			return err
+		}
+
+		if len(carried) > 0 {
+			ids := make([]uint, len(carried))
+			for i, previous := range carried {
+				ids[i] = previous.ID
+			}
+			if err := payoutRepo.MarkCarriedInto(ids, record.ID); err != nil {
+				return err
+			}
+		}
+
+		created = record
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// settlementTotals prices orders and refunds into payout lines and adds them
+// up. The arithmetic is done in cents.
+func (s *SettlementService) settlementTotals(orders []repository.SettledOrder, refunds []repository.SettledRefund, carried []model.Payout) *model.StoreEarnings {
+	var gross, discounts, commission, refunded, carriedOver, net int64
+	lines := make([]model.PayoutLine, 0, len(orders)+len(refunds))
+
+	for _, order := range orders {
+		orderGross := payment.ToMinorUnits(order.Subtotal)
+		if orderGross == 0 {
+			// Orders from before promo codes only have a total.
+			orderGross = payment.ToMinorUnits(order.TotalPrice)
+		}
+		var discount int64
+		if order.StoreFunded {
+			discount = payment.ToMinorUnits(order.DiscountAmount)
+		}
+		fee := s.commission(orderGross - discount)
+		lineNet := orderGross - discount - fee
+
+		lines = append(lines, model.PayoutLine{
+			Type:       model.PayoutLineOrder,
+			OrderID:    order.OrderID,
+			OccurredAt: order.PickedUpAt,
+			Gross:      payment.FromMinorUnits(orderGross),
+			Discount:   payment.FromMinorUnits(discount),
+			Commission: payment.FromMinorUnits(fee),
+			Net:        payment.FromMinorUnits(lineNet),
+		})
+		gross += orderGross
+		discounts += discount
+		commission += fee
+		net += lineNet
+	}
+
+	for _, refund := range refunds {
+		amount := payment.ToMinorUnits(refund.Amount)
+		fee := s.commission(amount)
+
+		lines = append(lines, model.PayoutLine{
+			Type:       model.PayoutLineRefund,
+			OrderID:    refund.OrderID,
+			RefundID:   refund.RefundID,
+			OccurredAt: refund.RefundedAt,
+			Gross:      payment.FromMinorUnits(-amount),
+			Commission: payment.FromMinorUnits(-fee),
+			Net:        payment.FromMinorUnits(fee - amount),
+		})
+		refunded += amount
+		commission -= fee
+		net -= amount - fee
+	}
+
+	for _, previous := range carried {
+		carriedOver += payment.ToMinorUnits(previous.Net)
+	}
+	net += carriedOver
+
+	return &model.StoreEarnings{
+		CommissionRate: s.config.CommissionRate,
+		Gross:          payment.FromMinorUnits(gross),
+		Discounts:      payment.FromMinorUnits(discounts),
+		Commission:     payment.FromMinorUnits(commission),
+		Refunds:        payment.FromMinorUnits(refunded),
+		CarriedOver:    payment.FromMinorUnits(carriedOver),
+		Net:            payment.FromMinorUnits(net),
+		Currency:       s.config.Currency,
+		Lines:          lines,
+	}
+}
+
+func (s *SettlementService) commission(cents int64) int64 {
+	return payment.ToMinorUnits(payment.FromMinorUnits(cents) * s.config.CommissionRate)
+}
+
+// transfer asks the payout provider to send a pending payout. The payout ID
+// is the provider's idempotency key, so a retried transfer pays once.
+func (s *SettlementService) transfer(ctx context.Context, record *model.Payout) error {
+	result, err := s.provider.Transfer(ctx, payout.TransferRequest{
+		Reference: fmt.Sprintf("payout_%d", record.ID),
+		StoreID:   record.StoreID,
+		Amount:    payment.ToMinorUnits(record.Net),
+		Currency:  record.Currency,
+	})
+
+	updates := map[string]interface{}{}
+	switch {
+	case err != nil:
+		updates["status"] = model.PayoutStatusFailed
+		updates["failure_reason"] = err.Error()
+	case !result.Succeeded:
+		updates["status"] = model.PayoutStatusFailed
+		updates["failure_reason"] = result.FailureReason
+	default:
+		now := time.Now()
+		updates["status"] = model.PayoutStatusPaid
+		updates["provider_ref"] = result.ProviderRef
+		updates["paid_at"] = now
+		updates["failure_reason"] = ""
+	}
+
+	if _, updateErr := s.payoutRepo.UpdateStatusFrom(record.ID, model.PayoutStatusPending, updates); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// Retry sends a failed payout again.
+func (s *SettlementService) Retry(ctx context.Context, payoutID uint) (*model.Payout, error) {
+	updated, err := s.payoutRepo.UpdateStatusFrom(payoutID, model.PayoutStatusFailed, map[string]interface{}{
+		"status": model.PayoutStatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		if _, err := s.getPayout(payoutID); err != nil {
+			return nil, err
+		}
+		return nil, ErrPayoutNotRetryable
+	}
+
+	record, err := s.getPayout(payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transfer(ctx, record); err != nil {
+		log.Printf("settlement: retrying payout %d: %v", payoutID, err)
+	}
+	return s.getPayout(payoutID)
+}
+
+// Earnings previews what a store would be paid if its open period closed
+// now.
+func (s *SettlementService) Earnings(storeID, userID uint) (*model.StoreEarnings, error) {
+	if err := s.authorize(storeID, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	orders, err := s.payoutRepo.UnsettledOrders(storeID, now)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.payoutRepo.UnsettledRefunds(storeID, now)
+	if err != nil {
+		return nil, err
+	}
+	carried, err := s.payoutRepo.GetCarriedForward(storeID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	earnings := s.settlementTotals(orders, refunds, carried)
+	earnings.StoreID = storeID
+	earnings.NextPayoutAt = s.PeriodStart(now).Add(s.config.Period)
+	return earnings, nil
+}
+
+// StorePayouts lists a store's payouts, newest first.
+func (s *SettlementService) StorePayouts(storeID, userID uint, limit, offset int) ([]model.Payout, int64, error) {
+	if err := s.authorize(storeID, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.payoutRepo.GetByStoreID(storeID, limit, offset)
+}
+
+// Statement returns one of a store's payouts with every order and refund on
+// it.
+func (s *SettlementService) Statement(storeID, payoutID, userID uint) (*model.Payout, error) {
+	if err := s.authorize(storeID, userID); err != nil {
+		return nil, err
+	}
+
+	record, err := s.getPayout(payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if record.StoreID != storeID {
+		return nil, ErrPayoutNotFound
+	}
+	return record, nil
+}
+
+// List returns payouts across stores for admins.
+func (s *SettlementService) List(filter repository.PayoutFilter, limit, offset int) ([]model.Payout, int64, error) {
+	return s.payoutRepo.List(filter, limit, offset)
+}
+
+// ExportCSV writes one row per payout matching filter.
+func (s *SettlementService) ExportCSV(w io.Writer, filter repository.PayoutFilter) error {
+	payouts, _, err := s.payoutRepo.List(filter, -1, -1)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{
+		"payout_id", "store_id", "store_name", "period_start", "period_end", "status",
+		"currency", "commission_rate", "gross", "discounts", "commission", "refunds",
+		"carried_over", "net", "provider", "provider_ref", "paid_at", "failure_reason",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range payouts {
+		paidAt := ""
+		if record.PaidAt != nil {
+			paidAt = record.PaidAt.UTC().Format(time.RFC3339)
+		}
+		row := []string{
+			strconv.FormatUint(uint64(record.ID), 10),
+			strconv.FormatUint(uint64(record.StoreID), 10),
+			record.Store.Name,
+			record.PeriodStart.UTC().Format(time.RFC3339),
+			record.PeriodEnd.UTC().Format(time.RFC3339),
+			string(record.Status),
+			record.Currency,
+			strconv.FormatFloat(record.CommissionRate, 'f', -1, 64),
+			formatAmount(record.Gross),
+			formatAmount(record.Discounts),
+			formatAmount(record.Commission),
+			formatAmount(record.Refunds),
+			formatAmount(record.CarriedOver),
+			formatAmount(record.Net),
+			record.Provider,
+			record.ProviderRef,
+			paidAt,
+			record.FailureReason,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func (s *SettlementService) getPayout(payoutID uint) (*model.Payout, error) {
+	record, err := s.payoutRepo.GetByID(payoutID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPayoutNotFound
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// authorize lets store owners see their own store and admins see any.
+func (s *SettlementService) authorize(storeID, userID uint) error {
+	store, err := s.storeRepo.GetByID(storeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrStoreNotFound
+		}
+		return err
+	}
+	if store.OwnerID == userID {
+		return nil
+	}
+
+	user, err := s.userRepo.FindUserById(userID)
+	if err != nil {
+		return err
+	}
+	if user.UserType != model.UserTypeAdmin {
+		return ErrStoreForbidden
+	}
+	return nil
+}
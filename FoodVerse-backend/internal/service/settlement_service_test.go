@@ -0,0 +1,107 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+)
+
+func TestSettlementTotals(t *testing.T) {
+	s := &SettlementService{config: SettlementConfig{CommissionRate: 0.15, Currency: "EUR"}}
+	order := func(id uint, subtotal float64) repository.SettledOrder {
+		return repository.SettledOrder{OrderID: id, Subtotal: subtotal, TotalPrice: subtotal}
+	}
+
+	// Amounts are in cents.
+	type totals struct {
+		gross, discounts, commission, refunds, carriedOver, net int64
+	}
+	tests := []struct {
+		name    string
+		orders  []repository.SettledOrder
+		refunds []repository.SettledRefund
+		carried []model.Payout
+		want    totals
+	}{
+		{
+			name:   "commission",
+			orders: []repository.SettledOrder{order(1, 10)},
+			want:   totals{gross: 1000, commission: 150, net: 850},
+		},
+		{
+			name: "store-funded discount is taken off before commission",
+			orders: []repository.SettledOrder{
+				{OrderID: 1, Subtotal: 10, TotalPrice: 8, DiscountAmount: 2, StoreFunded: true},
+			},
+			want: totals{gross: 1000, discounts: 200, commission: 120, net: 680},
+		},
+		{
+			name: "platform-funded discount is not the store's",
+			orders: []repository.SettledOrder{
+				{OrderID: 1, Subtotal: 10, TotalPrice: 8, DiscountAmount: 2},
+			},
+			want: totals{gross: 1000, commission: 150, net: 850},
+		},
+		{
+			name:   "order from before promo codes",
+			orders: []repository.SettledOrder{{OrderID: 1, TotalPrice: 6}},
+			want:   totals{gross: 600, commission: 90, net: 510},
+		},
+		{
+			name:    "refund gives the commission back",
+			orders:  []repository.SettledOrder{order(1, 10)},
+			refunds: []repository.SettledRefund{{RefundID: 1, OrderID: 1, Amount: 4}},
+			want:    totals{gross: 1000, commission: 90, refunds: 400, net: 510},
+		},
+		{
+			name:    "refunds outweigh earnings",
+			refunds: []repository.SettledRefund{{RefundID: 1, OrderID: 1, Amount: 10}},
+			want:    totals{commission: -150, refunds: 1000, net: -850},
+		},
+		{
+			name:    "carried-forward negative payouts",
+			orders:  []repository.SettledOrder{order(1, 10)},
+			carried: []model.Payout{{Net: -3.20}, {Net: -1}},
+			want:    totals{gross: 1000, commission: 150, carriedOver: -420, net: 430},
+		},
+		{
+			name:   "commission is rounded per line",
+			orders: []repository.SettledOrder{order(1, 0.10), order(2, 0.10), order(3, 0.10)},
+			want:   totals{gross: 30, commission: 6, net: 24},
+		},
+		{
+			name:   "half a cent rounds up",
+			orders: []repository.SettledOrder{order(1, 3.33)},
+			want:   totals{gross: 333, commission: 50, net: 283},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			earnings := s.settlementTotals(tt.orders, tt.refunds, tt.carried)
+			got := totals{
+				gross:       payment.ToMinorUnits(earnings.Gross),
+				discounts:   payment.ToMinorUnits(earnings.Discounts),
+				commission:  payment.ToMinorUnits(earnings.Commission),
+				refunds:     payment.ToMinorUnits(earnings.Refunds),
+				carriedOver: payment.ToMinorUnits(earnings.CarriedOver),
+				net:         payment.ToMinorUnits(earnings.Net),
+			}
+			if got != tt.want {
+				t.Errorf("totals = %+v, want %+v", got, tt.want)
+			}
+
+			if len(earnings.Lines) != len(tt.orders)+len(tt.refunds) {
+				t.Fatalf("%d lines, want one per order and refund", len(earnings.Lines))
+			}
+			var linesNet int64
+			for _, line := range earnings.Lines {
+				linesNet += payment.ToMinorUnits(line.Net)
+			}
+			if linesNet+got.carriedOver != got.net {
+				t.Errorf("lines add up to %d plus %d carried over, want net %d", linesNet, got.carriedOver, got.net)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/payment"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInsufficientWalletBalance = errors.New("wallet balance is too low")
+	ErrInvalidWalletAmount       = errors.New("wallet amount must be positive")
+)
+
+// WalletService keeps every user's wallet on a double-entry ledger. Wallet
+// money only moves together with a matching entry on a platform account, and
+// every transaction records the order, refund, promotion or admin adjustment
+// behind it. Amounts are in cents.
+//
+// Methods that take a *gorm.DB run inside the caller's transaction, so wallet
+// changes commit or roll back together with the order they belong to.
+type WalletService struct {
+	db         *gorm.DB
+	ledgerRepo *repository.LedgerRepository
+	currency   string
+}
+
+func NewWalletService(db *gorm.DB, ledgerRepo *repository.LedgerRepository, currency string) *WalletService {
+	return &WalletService{
+		db:         db,
+		ledgerRepo: ledgerRepo,
+		currency:   currency,
+	}
+}
+
+// ledgerLine is one side of a posting.
+type ledgerLine struct {
+	accountID uint
+	amount    int64
+}
+
+// Balance returns the user's wallet balance.
+func (s *WalletService) Balance(userID uint) (*model.WalletResponse, error) {
+	account, err := s.walletAccount(s.db, userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.WalletResponse{
+		Balance:   payment.FromMinorUnits(account.Balance),
+		Held:      payment.FromMinorUnits(account.HeldAmount),
+		Available: payment.FromMinorUnits(account.Balance - account.HeldAmount),
+		Currency:  account.Currency,
+	}, nil
+}
+
+// Transactions returns the entries on the user's wallet, newest first.
+func (s *WalletService) Transactions(userID uint, limit, offset int) ([]model.LedgerEntry, int64, error) {
+	account, err := s.walletAccount(s.db, userID, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.ledgerRepo.GetEntriesByAccount(account.ID, limit, offset)
+}
+
+// Hold sets amount aside on the user's wallet for an order.
+func (s *WalletService) Hold(tx *gorm.DB, userID, orderID uint, amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidWalletAmount
+	}
+
+	account, err := s.walletAccount(tx, userID, true)
+	if err != nil {
+		return err
+	}
+
+	ledgerRepo := s.ledgerRepo.WithTx(tx)
+	applied, err := ledgerRepo.ApplyDelta(account.ID, 0, amount)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrInsufficientWalletBalance
+	}
+
+	return ledgerRepo.CreateHold(&model.WalletHold{
+		AccountID: account.ID,
+		OrderID:   orderID,
+		Amount:    amount,
+		Status:    model.WalletHoldActive,
+	})
+}
+
+// CaptureHold pays an order with the money held for it. It does nothing when
+// the order has no active hold.
+func (s *WalletService) CaptureHold(tx *gorm.DB, orderID uint) error {
+	ledgerRepo := s.ledgerRepo.WithTx(tx)
+	hold, err := ledgerRepo.GetHoldForUpdate(orderID, model.WalletHoldActive)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := ledgerRepo.ApplyDelta(hold.AccountID, 0, -hold.Amount); err != nil {
+		return err
+	}
+
+	sales, err := s.platformAccount(tx, model.LedgerAccountSales)
+	if err != nil {
+		return err
+	}
+
+	err = s.post(tx, &model.LedgerTransaction{
+		Type:        model.LedgerTransactionOrderPayment,
+		OrderID:     &orderID,
+		Description: fmt.Sprintf("Payment for order #%d", orderID),
+	}, ledgerLine{hold.AccountID, -hold.Amount}, ledgerLine{sales.ID, hold.Amount})
+	if err != nil {
+		return err
+	}
+
+	return ledgerRepo.UpdateHoldStatus(hold.ID, model.WalletHoldCaptured)
+}
+
+// ReleaseOrder undoes the wallet side of a cancelled order: an active hold is
+// released and money already taken is paid back.
+func (s *WalletService) ReleaseOrder(tx *gorm.DB, orderID uint) error {
+	ledgerRepo := s.ledgerRepo.WithTx(tx)
+
+	hold, err := ledgerRepo.GetHoldForUpdate(orderID, model.WalletHoldActive)
+	if err == nil {
+		if _, err := ledgerRepo.ApplyDelta(hold.AccountID, 0, -hold.Amount); err != nil {
+			return err
+		}
+		return ledgerRepo.UpdateHoldStatus(hold.ID, model.WalletHoldReleased)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	hold, err = ledgerRepo.GetHoldForUpdate(orderID, model.WalletHoldCaptured)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sales, err := s.platformAccount(tx, model.LedgerAccountSales)
+	if err != nil {
+		return err
+	}
+
+	err = s.post(tx, &model.LedgerTransaction{
+		Type:        model.LedgerTransactionOrderRefund,
+		OrderID:     &orderID,
+		Description: fmt.Sprintf("Refund for cancelled order #%d", orderID),
+	}, ledgerLine{sales.ID, -hold.Amount}, ledgerLine{hold.AccountID, hold.Amount})
+	if err != nil {
+		return err
+	}
+
+	return ledgerRepo.UpdateHoldStatus(hold.ID, model.WalletHoldRefunded)
+}
+
+// Credit adds amount to the user's wallet, taking it from the platform
+// account that matches the transaction type.
+func (s *WalletService) Credit(tx *gorm.DB, userID uint, amount int64, transaction *model.LedgerTransaction) error {
+	if amount <= 0 {
+		return ErrInvalidWalletAmount
+	}
+
+	wallet, err := s.walletAccount(tx, userID, false)
+	if err != nil {
+		return err
+	}
+
+	source, err := s.platformAccount(tx, s.sourceAccount(transaction.Type))
+	if err != nil {
+		return err
+	}
+
+	return s.post(tx, transaction, ledgerLine{source.ID, -amount}, ledgerLine{wallet.ID, amount})
+}
+
+// Adjust credits or debits a wallet on behalf of an admin. A debit can never
+// take the wallet below what is held for orders.
+func (s *WalletService) Adjust(adminID, userID uint, amount int64, reason string) (*model.LedgerTransaction, error) {
+	if amount == 0 {
+		return nil, ErrInvalidWalletAmount
+	}
+
+	transaction := &model.LedgerTransaction{
+		Type:        model.LedgerTransactionAdjustment,
+		AdminID:     &adminID,
+		Description: reason,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		wallet, err := s.walletAccount(tx, userID, false)
+		if err != nil {
+			return err
+		}
+
+		adjustments, err := s.platformAccount(tx, model.LedgerAccountAdjustments)
+		if err != nil {
+			return err
+		}
+
+		return s.post(tx, transaction, ledgerLine{adjustments.ID, -amount}, ledgerLine{wallet.ID, amount})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// Reconcile checks that every transaction balances, that every cached
+// balance equals the sum of its entries, that held amounts match the active
+// holds and that no wallet is negative.
+func (s *WalletService) Reconcile() (*model.ReconciliationReport, error) {
+	accounts, err := s.ledgerRepo.GetAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	entryTotals, err := s.ledgerRepo.EntryTotals()
+	if err != nil {
+		return nil, err
+	}
+
+	holdTotals, err := s.ledgerRepo.ActiveHoldTotals()
+	if err != nil {
+		return nil, err
+	}
+
+	unbalanced, err := s.ledgerRepo.UnbalancedTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ReconciliationReport{
+		GeneratedAt:            time.Now(),
+		UnbalancedTransactions: unbalanced,
+		Accounts:               make([]model.ReconciliationAccount, 0, len(accounts)),
+	}
+	for _, account := range accounts {
+		line := model.ReconciliationAccount{
+			AccountID:     account.ID,
+			Code:          account.Code,
+			Balance:       account.Balance,
+			EntryTotal:    entryTotals[account.ID],
+			HeldAmount:    account.HeldAmount,
+			ActiveHolds:   holdTotals[account.ID],
+			NegativeFound: !account.AllowNegative && account.Balance < 0,
+		}
+		line.BalanceMatch = line.Balance == line.EntryTotal
+		line.HoldsMatch = line.HeldAmount == line.ActiveHolds
+		if !line.BalanceMatch || !line.HoldsMatch || line.NegativeFound {
+			report.Mismatches++
+		}
+
+		report.LedgerTotal += line.EntryTotal
+		report.Accounts = append(report.Accounts, line)
+	}
+	report.Balanced = report.LedgerTotal == 0 && len(unbalanced) == 0 && report.Mismatches == 0
+
+	return report, nil
+}
+
+// post records a transaction and applies its lines to the account balances.
+// Accounts are updated in ID order so concurrent postings cannot deadlock.
+func (s *WalletService) post(tx *gorm.DB, transaction *model.LedgerTransaction, lines ...ledgerLine) error {
+	var sum int64
+	for _, line := range lines {
+		sum += line.amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("ledger transaction does not balance: off by %d", sum)
+	}
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i].accountID < lines[j].accountID })
+
+	ledgerRepo := s.ledgerRepo.WithTx(tx)
+	transaction.Entries = make([]model.LedgerEntry, 0, len(lines))
+	for _, line := range lines {
+		applied, err := ledgerRepo.ApplyDelta(line.accountID, line.amount, 0)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			return ErrInsufficientWalletBalance
+		}
+		transaction.Entries = append(transaction.Entries, model.LedgerEntry{
+			AccountID: line.accountID,
+			Amount:    line.amount,
+		})
+	}
+
+	return ledgerRepo.CreateTransaction(transaction)
+}
+
+// walletAccount returns the user's wallet, opening it on first use.
+func (s *WalletService) walletAccount(tx *gorm.DB, userID uint, forUpdate bool) (*model.LedgerAccount, error) {
+	code := fmt.Sprintf("wallet:%d", userID)
+	ledgerRepo := s.ledgerRepo.WithTx(tx)
+
+	err := ledgerRepo.EnsureAccount(&model.LedgerAccount{
+		Code:     code,
+		Type:     model.LedgerAccountWallet,
+		UserID:   &userID,
+		Currency: s.currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if forUpdate {
+		return ledgerRepo.GetAccountByCodeForUpdate(code)
+	}
+	return ledgerRepo.GetAccountByCode(code)
+}
+
+// platformAccount returns one of the platform's own accounts, creating it on
+// first use.
+func (s *WalletService) platformAccount(tx *gorm.DB, code string) (*model.LedgerAccount, error) {
+	ledgerRepo := s.ledgerRepo.WithTx(tx)
+
+	err := ledgerRepo.EnsureAccount(&model.LedgerAccount{
+		Code:          code,
+		Type:          model.LedgerAccountPlatform,
+		Currency:      s.currency,
+		AllowNegative: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ledgerRepo.GetAccountByCode(code)
+}
+
+// sourceAccount is the platform account that funds a wallet credit.
+func (s *WalletService) sourceAccount(transactionType model.LedgerTransactionType) string {
+	switch transactionType {
+	case model.LedgerTransactionPromotion:
+		return model.LedgerAccountPromotions
+	case model.LedgerTransactionAdjustment:
+		return model.LedgerAccountAdjustments
+	default:
+		return model.LedgerAccountSales
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/internal/repository"
+	"github.com/FoodVerse/FoodVerse-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+// newTestWallet returns a wallet service and a user whose wallet holds
+// balance cents, credited from the promotions account.
+func newTestWallet(t *testing.T, balance int64) (*WalletService, *gorm.DB, uint) {
+	t.Helper()
+	db := testdb.Open(t)
+	user := model.User{Name: "Buyer", Email: "buyer@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := NewWalletService(db, repository.NewLedgerRepository(db), "EUR")
+	if err := s.Credit(db, user.ID, balance, &model.LedgerTransaction{Type: model.LedgerTransactionPromotion}); err != nil {
+		t.Fatal(err)
+	}
+	return s, db, user.ID
+}
+
+// checkAccount compares an account's cached balance and held amount.
+func checkAccount(t *testing.T, s *WalletService, code string, balance, held int64) {
+	t.Helper()
+	account, err := s.ledgerRepo.GetAccountByCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != balance || account.HeldAmount != held {
+		t.Errorf("%s: balance %d, held %d; want %d, %d", code, account.Balance, account.HeldAmount, balance, held)
+	}
+}
+
+func checkReconciled(t *testing.T, s *WalletService) {
+	t.Helper()
+	report, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Balanced {
+		t.Errorf("ledger does not reconcile: %+v", report)
+	}
+}
+
+func TestWalletPostRejectsUnbalancedEntries(t *testing.T) {
+	s, db, userID := newTestWallet(t, 1000)
+	wallet, err := s.walletAccount(db, userID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sales, err := s.platformAccount(db, model.LedgerAccountSales)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.post(db, &model.LedgerTransaction{Type: model.LedgerTransactionAdjustment},
+		ledgerLine{wallet.ID, -100}, ledgerLine{sales.ID, 90})
+	if err == nil {
+		t.Fatal("unbalanced posting was accepted")
+	}
+
+	var transactions int64
+	if err := db.Model(&model.LedgerTransaction{}).Count(&transactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if transactions != 1 {
+		t.Errorf("%d ledger transactions, want only the opening credit", transactions)
+	}
+	checkAccount(t, s, wallet.Code, 1000, 0)
+	checkReconciled(t, s)
+}
+
+func TestLedgerApplyDeltaGuard(t *testing.T) {
+	s, db, userID := newTestWallet(t, 500)
+	wallet, err := s.walletAccount(db, userID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	promotions, err := s.platformAccount(db, model.LedgerAccountPromotions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledgerRepo := s.ledgerRepo
+
+	steps := []struct {
+		name                  string
+		accountID             uint
+		balance, held         int64
+		want                  bool
+		wantBalance, wantHeld int64
+	}{
+		{"overdraw", wallet.ID, -600, 0, false, 500, 0},
+		{"hold more than the balance", wallet.ID, 0, 600, false, 500, 0},
+		{"hold", wallet.ID, 0, 400, true, 500, 400},
+		{"spend held money", wallet.ID, -200, 0, false, 500, 400},
+		{"spend the rest", wallet.ID, -100, 0, true, 400, 400},
+		{"negative hold", wallet.ID, 0, -500, false, 400, 400},
+		{"platform account may go negative", promotions.ID, -10000, 0, true, 0, 0},
+	}
+	for _, step := range steps {
+		applied, err := ledgerRepo.ApplyDelta(step.accountID, step.balance, step.held)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if applied != step.want {
+			t.Errorf("%s: applied = %v, want %v", step.name, applied, step.want)
+		}
+		if step.accountID == wallet.ID {
+			checkAccount(t, s, wallet.Code, step.wantBalance, step.wantHeld)
+		}
+	}
+}
+
+func TestWalletHoldCapture(t *testing.T) {
+	s, db, userID := newTestWallet(t, 1000)
+	const orderID = 1
+
+	if err := s.Hold(db, userID, orderID, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Hold(db, userID, orderID+1, 800); !errors.Is(err, ErrInsufficientWalletBalance) {
+		t.Errorf("hold beyond the available balance: err = %v, want ErrInsufficientWalletBalance", err)
+	}
+	wallet, err := s.walletAccount(db, userID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAccount(t, s, wallet.Code, 1000, 300)
+
+	if err := s.CaptureHold(db, orderID); err != nil {
+		t.Fatal(err)
+	}
+	checkAccount(t, s, wallet.Code, 700, 0)
+	checkAccount(t, s, model.LedgerAccountSales, 300, 0)
+	checkReconciled(t, s)
+
+	// Cancelling after capture pays the money back.
+	if err := s.ReleaseOrder(db, orderID); err != nil {
+		t.Fatal(err)
+	}
+	checkAccount(t, s, wallet.Code, 1000, 0)
+	checkAccount(t, s, model.LedgerAccountSales, 0, 0)
+	checkReconciled(t, s)
+}
+
+func TestWalletHoldRelease(t *testing.T) {
+	s, db, userID := newTestWallet(t, 1000)
+	const orderID = 1
+
+	if err := s.Hold(db, userID, orderID, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReleaseOrder(db, orderID); err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := s.walletAccount(db, userID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAccount(t, s, wallet.Code, 1000, 0)
+
+	// A released hold can no longer be captured.
+	if err := s.CaptureHold(db, orderID); err != nil {
+		t.Fatal(err)
+	}
+	checkAccount(t, s, wallet.Code, 1000, 0)
+	checkReconciled(t, s)
+}
+
+func TestWalletReconcileReportsDrift(t *testing.T) {
+	s, db, userID := newTestWallet(t, 1000)
+	if err := s.Hold(db, userID, 1, 300); err != nil {
+		t.Fatal(err)
+	}
+	checkReconciled(t, s)
+
+	wallet, err := s.walletAccount(db, userID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A cached balance and held amount changed outside the ledger.
+	err = db.Model(&model.LedgerAccount{}).Where("id = ?", wallet.ID).
+		Updates(map[string]interface{}{"balance": gorm.Expr("balance + 5"), "held_amount": 0}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Balanced || report.Mismatches != 1 {
+		t.Errorf("Balanced = %v, Mismatches = %d; want false, 1", report.Balanced, report.Mismatches)
+	}
+	for _, account := range report.Accounts {
+		if account.AccountID != wallet.ID {
+			continue
+		}
+		if account.BalanceMatch || account.HoldsMatch {
+			t.Errorf("wallet line = %+v, want balance and holds mismatched", account)
+		}
+		if account.Balance-account.EntryTotal != 5 {
+			t.Errorf("wallet drift = %d, want 5", account.Balance-account.EntryTotal)
+		}
+	}
+}
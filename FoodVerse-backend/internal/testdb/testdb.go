@@ -0,0 +1,78 @@
+// Package testdb opens a migrated Postgres database for tests that need
+// the real query planner, locks and constraints.
+package testdb
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/FoodVerse/FoodVerse-backend/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DSNEnv names the environment variable holding the connection string of
+// a disposable database, for example
+// "host=localhost user=postgres password=postgres dbname=foodverse_test".
+const DSNEnv = "FOODVERSE_TEST_DSN"
+
+var schemas atomic.Int64
+
+// Open connects to the database in FOODVERSE_TEST_DSN and migrates a fresh
+// schema that is dropped when the test ends. Tests are skipped when the
+// variable is unset.
+func Open(tb testing.TB) *gorm.DB {
+	tb.Helper()
+
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		tb.Skipf("%s is not set", DSNEnv)
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		tb.Fatalf("connect to test database: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d_%d", time.Now().UnixNano(), schemas.Add(1))
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		tb.Fatalf("create schema: %v", err)
+	}
+
+	// Extensions live in public, so it stays on the search path.
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema+",public")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		tb.Fatalf("connect to test schema: %v", err)
+	}
+	tb.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := migrations.Migrate(db); err != nil {
+		tb.Fatalf("migrate test schema: %v", err)
+	}
+	return db
+}
+
+// withSearchPath adds a search_path run-time parameter to a DSN in either
+// keyword/value or URL form.
+func withSearchPath(dsn, searchPath string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		query := u.Query()
+		query.Set("search_path", searchPath)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(dsn) + " search_path=" + searchPath
+}
@@ -1,17 +1,125 @@
 package migrations
 
 import (
+	"fmt"
+	"log"
+	"strings"
+
 	"github.com/FoodVerse/FoodVerse-backend/internal/model"
+	"github.com/FoodVerse/FoodVerse-backend/pkg/database"
 	"gorm.io/gorm"
 )
 
-func Migrate(db *gorm.DB) {
+// Migrate brings the schema up to date. It fails when a table or index the
+// application relies on could not be created; optional extensions that are
+// not available are only logged.
+func Migrate(db *gorm.DB) error {
 	// Auto-migrate all models
-	db.AutoMigrate(
+	err := db.AutoMigrate(
 		&model.User{},
 		&model.Store{},
+		&model.StoreOpeningHours{},
+		&model.StoreHoursException{},
 		&model.FoodBag{},
+		&model.FoodBagTemplate{},
+		&model.FoodBagSchedule{},
+		&model.FoodBagScheduleSkip{},
 		&model.Order{},
+		&model.OrderEvent{},
+		&model.OrderItem{},
+		&model.CartItem{},
+		&model.IdempotencyKey{},
+		&model.Payment{},
+		&model.Refund{},
+		&model.LedgerAccount{},
+		&model.LedgerTransaction{},
+		&model.LedgerEntry{},
+		&model.WalletHold{},
+		&model.Promotion{},
+		&model.PromotionRedemption{},
+		&model.Payout{},
+		&model.PayoutLine{},
 		&model.SellerRequest{},
+		&model.SearchSuggestion{},
+		&model.SavedSearch{},
+		&model.Notification{},
 	)
+	if err != nil {
+		return fmt.Errorf("auto-migrate: %w", err)
+	}
+
+	// Nearby search prefilters on a bounding box of coordinates, or uses a
+	// GiST index on the stores' location when PostGIS is installed.
+	if err := exec(db, `CREATE INDEX IF NOT EXISTS idx_stores_lat_lon ON stores (latitude, longitude)`); err != nil {
+		return err
+	}
+	if database.HasExtension(db, "postgis") {
+		err := exec(db, `CREATE INDEX IF NOT EXISTS idx_stores_geography
+			ON stores USING GIST (geography(ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)))`)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Text search matches weighted tsvectors kept up to date by Postgres,
+	// plus trigram similarity on names and titles for typos when pg_trgm
+	// can be installed.
+	err = exec(db,
+		`ALTER TABLE stores ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(category, '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+		) STORED`,
+		`ALTER TABLE food_bags ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(category, '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+		) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_stores_search_vector ON stores USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_food_bags_search_vector ON food_bags USING GIN (search_vector)`,
+	)
+	if err != nil {
+		return err
+	}
+	// Installing an extension takes privileges the database user may not
+	// have; search then works without typo tolerance.
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error; err != nil {
+		log.Printf("migrations: pg_trgm is not available, search will not tolerate typos: %v", err)
+	}
+	if database.HasExtension(db, "pg_trgm") {
+		err := exec(db,
+			`CREATE INDEX IF NOT EXISTS idx_stores_name_trgm ON stores USING GIN (name gin_trgm_ops)`,
+			`CREATE INDEX IF NOT EXISTS idx_food_bags_title_trgm ON food_bags USING GIN (title gin_trgm_ops)`,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Suggestions match what has been typed so far as a prefix.
+	err = exec(db,
+		`CREATE INDEX IF NOT EXISTS idx_search_suggestions_term ON search_suggestions (term text_pattern_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_search_suggestions_category_term ON search_suggestions (category_term text_pattern_ops)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Pickup codes only have to be unique among a store's open orders.
+	return exec(db, `CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_store_open_pickup_code
+		ON orders (store_id, pickup_code)
+		WHERE status IN ('pending', 'confirmed', 'ready') AND deleted_at IS NULL`)
+}
+
+// exec runs schema statements in order and stops at the first that fails,
+// naming it in the error.
+func exec(db *gorm.DB, statements ...string) error {
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("migration %q: %w", strings.Join(strings.Fields(statement), " "), err)
+		}
+	}
+	return nil
 }
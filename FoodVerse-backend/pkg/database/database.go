@@ -21,3 +21,11 @@ func NewConnection(cfg *config.Config) (*gorm.DB, error) {
 
 	return db, nil
 }
+
+// HasExtension reports whether a Postgres extension, such as postgis or
+// pg_trgm, is installed in the connected database.
+func HasExtension(db *gorm.DB, name string) bool {
+	var installed bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = ?)", name).Scan(&installed).Error
+	return err == nil && installed
+}
@@ -0,0 +1,106 @@
+// Package pickuptoken issues and verifies the signed tokens encoded in order
+// pickup QR codes.
+//
+// A token is "v1.<payload>.<signature>", both parts unpadded base64url. The
+// payload is the order ID, store ID and expiry as three big-endian 64-bit
+// integers, and the signature is Ed25519 over the "v1.<payload>" prefix.
+// Anyone holding the published public key can check a token without talking
+// to the API, which is what Verifier does on a store's device.
+package pickuptoken
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+const version = "v1"
+
+const payloadSize = 24
+
+var (
+	ErrMalformed        = errors.New("malformed pickup token")
+	ErrInvalidSignature = errors.New("invalid pickup token signature")
+	ErrExpired          = errors.New("pickup token has expired")
+	ErrWrongStore       = errors.New("pickup token belongs to another store")
+	ErrAlreadyRedeemed  = errors.New("pickup token has already been redeemed")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Claims is what a pickup token asserts.
+type Claims struct {
+	OrderID   uint      `json:"order_id"`
+	StoreID   uint      `json:"store_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Sign encodes claims into a token signed with key.
+func Sign(key ed25519.PrivateKey, claims Claims) string {
+	payload := make([]byte, payloadSize)
+	binary.BigEndian.PutUint64(payload[0:8], uint64(claims.OrderID))
+	binary.BigEndian.PutUint64(payload[8:16], uint64(claims.StoreID))
+	binary.BigEndian.PutUint64(payload[16:24], uint64(claims.ExpiresAt.Unix()))
+
+	signed := version + "." + encoding.EncodeToString(payload)
+	signature := ed25519.Sign(key, []byte(signed))
+	return signed + "." + encoding.EncodeToString(signature)
+}
+
+// Parse checks a token's signature and returns its claims without looking at
+// the expiry.
+func Parse(key ed25519.PublicKey, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != version {
+		return Claims{}, ErrMalformed
+	}
+
+	payload, err := encoding.DecodeString(parts[1])
+	if err != nil || len(payload) != payloadSize {
+		return Claims{}, ErrMalformed
+	}
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return Claims{}, ErrMalformed
+	}
+
+	if !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	return Claims{
+		OrderID:   uint(binary.BigEndian.Uint64(payload[0:8])),
+		StoreID:   uint(binary.BigEndian.Uint64(payload[8:16])),
+		ExpiresAt: time.Unix(int64(binary.BigEndian.Uint64(payload[16:24])), 0).UTC(),
+	}, nil
+}
+
+// Verify checks a token's signature and that it had not expired at the given
+// time.
+func Verify(key ed25519.PublicKey, token string, at time.Time) (Claims, error) {
+	claims, err := Parse(key, token)
+	if err != nil {
+		return Claims{}, err
+	}
+	if at.After(claims.ExpiresAt) {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+// EncodePublicKey formats a public key the way the API publishes it.
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return encoding.EncodeToString(key)
+}
+
+// ParsePublicKey reads a public key produced by EncodePublicKey.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := encoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid pickup token public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
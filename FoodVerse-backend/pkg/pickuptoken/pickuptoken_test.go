@@ -0,0 +1,127 @@
+package pickuptoken
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func TestSignParseRoundTrip(t *testing.T) {
+	key := testKey(1)
+	claims := Claims{OrderID: 42, StoreID: 7, ExpiresAt: time.Date(2026, 10, 17, 18, 30, 0, 0, time.UTC)}
+
+	token := Sign(key, claims)
+	got, err := Parse(key.Public().(ed25519.PublicKey), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != claims {
+		t.Errorf("Parse = %+v, want %+v", got, claims)
+	}
+
+	published, err := ParsePublicKey(EncodePublicKey(key.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(published, token); err != nil {
+		t.Errorf("Parse with the published key: %v", err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	key := testKey(1)
+	public := key.Public().(ed25519.PublicKey)
+	token := Sign(key, Claims{OrderID: 42, StoreID: 7, ExpiresAt: time.Now().Add(time.Hour)})
+	parts := strings.Split(token, ".")
+
+	// Another order ID under the original signature.
+	payload, _ := encoding.DecodeString(parts[1])
+	binary.BigEndian.PutUint64(payload[0:8], 43)
+	tamperedPayload := parts[0] + "." + encoding.EncodeToString(payload) + "." + parts[2]
+
+	signature, _ := encoding.DecodeString(parts[2])
+	signature[0] ^= 1
+	tamperedSignature := parts[0] + "." + parts[1] + "." + encoding.EncodeToString(signature)
+
+	tests := []struct {
+		name  string
+		key   ed25519.PublicKey
+		token string
+		want  error
+	}{
+		{"tampered payload", public, tamperedPayload, ErrInvalidSignature},
+		{"tampered signature", public, tamperedSignature, ErrInvalidSignature},
+		{"wrong public key", testKey(2).Public().(ed25519.PublicKey), token, ErrInvalidSignature},
+		{"placeholder parts", public, "v1.payload.sig", ErrMalformed},
+		{"missing signature", public, parts[0] + "." + parts[1], ErrMalformed},
+		{"extra part", public, token + ".x", ErrMalformed},
+		{"short payload", public, parts[0] + "." + encoding.EncodeToString(payload[:16]) + "." + parts[2], ErrMalformed},
+		{"unknown version", public, "v2." + parts[1] + "." + parts[2], ErrMalformed},
+		{"empty", public, "", ErrMalformed},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.key, tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	key := testKey(1)
+	expiresAt := time.Date(2026, 10, 17, 18, 30, 0, 0, time.UTC)
+	token := Sign(key, Claims{OrderID: 42, StoreID: 7, ExpiresAt: expiresAt})
+	public := key.Public().(ed25519.PublicKey)
+
+	if _, err := Verify(public, token, expiresAt); err != nil {
+		t.Errorf("Verify at expiry: %v", err)
+	}
+	claims, err := Verify(public, token, expiresAt.Add(time.Second))
+	if !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify after expiry: err = %v, want ErrExpired", err)
+	}
+	if claims.OrderID != 42 {
+		t.Errorf("expired token claims order %d, want 42", claims.OrderID)
+	}
+}
+
+func TestVerifierRedeem(t *testing.T) {
+	key := testKey(1)
+	now := time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)
+	sign := func(orderID, storeID uint) string {
+		return Sign(key, Claims{OrderID: orderID, StoreID: storeID, ExpiresAt: now.Add(time.Hour)})
+	}
+	verifier := NewVerifier(key.Public().(ed25519.PublicKey), 7)
+
+	if _, err := verifier.Redeem(sign(1, 8), now); !errors.Is(err, ErrWrongStore) {
+		t.Errorf("token for another store: err = %v, want ErrWrongStore", err)
+	}
+	if _, err := verifier.Redeem(sign(1, 7), now); err != nil {
+		t.Fatalf("token for this store: %v", err)
+	}
+	if _, err := verifier.Redeem(sign(1, 7), now); !errors.Is(err, ErrAlreadyRedeemed) {
+		t.Errorf("second scan: err = %v, want ErrAlreadyRedeemed", err)
+	}
+	if _, err := verifier.Redeem(sign(2, 7), now); err != nil {
+		t.Fatalf("second order: %v", err)
+	}
+
+	pending := verifier.Pending()
+	if len(pending) != 2 || pending[0].OrderID != 1 || pending[1].OrderID != 2 {
+		t.Fatalf("Pending = %+v, want orders 1 and 2", pending)
+	}
+	verifier.Acknowledge(1)
+	if pending := verifier.Pending(); len(pending) != 1 || pending[0].OrderID != 2 {
+		t.Errorf("Pending after acknowledging order 1 = %+v, want order 2", pending)
+	}
+	if _, err := verifier.Redeem(sign(1, 7), now); !errors.Is(err, ErrAlreadyRedeemed) {
+		t.Errorf("scan after acknowledging: err = %v, want ErrAlreadyRedeemed", err)
+	}
+}
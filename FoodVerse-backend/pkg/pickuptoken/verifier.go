@@ -0,0 +1,89 @@
+package pickuptoken
+
+import (
+	"crypto/ed25519"
+	"sync"
+	"time"
+)
+
+// Redemption is a pickup accepted offline that still has to be reported to
+// the API.
+type Redemption struct {
+	Token      string    `json:"token"`
+	OrderID    uint      `json:"order_id"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
+
+// Verifier checks pickup tokens on a store's device without network access
+// and queues the accepted ones until they can be synced.
+type Verifier struct {
+	key     ed25519.PublicKey
+	storeID uint
+
+	mu       sync.Mutex
+	redeemed map[uint]bool
+	pending  []Redemption
+}
+
+// NewVerifier returns a Verifier for storeID using the API's published
+// public key.
+func NewVerifier(key ed25519.PublicKey, storeID uint) *Verifier {
+	return &Verifier{
+		key:      key,
+		storeID:  storeID,
+		redeemed: map[uint]bool{},
+	}
+}
+
+// Redeem accepts a scanned token if it is validly signed, meant for this
+// store, unexpired and not redeemed on this device before.
+func (v *Verifier) Redeem(token string, now time.Time) (Claims, error) {
+	claims, err := Verify(v.key, token, now)
+	if err != nil {
+		return claims, err
+	}
+	if claims.StoreID != v.storeID {
+		return claims, ErrWrongStore
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.redeemed[claims.OrderID] {
+		return claims, ErrAlreadyRedeemed
+	}
+	v.redeemed[claims.OrderID] = true
+	v.pending = append(v.pending, Redemption{Token: token, OrderID: claims.OrderID, RedeemedAt: now})
+	return claims, nil
+}
+
+// Pending returns the redemptions that have not been acknowledged yet, in
+// the order they were accepted.
+func (v *Verifier) Pending() []Redemption {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	pending := make([]Redemption, len(v.pending))
+	copy(pending, v.pending)
+	return pending
+}
+
+// Acknowledge drops redemptions the API has processed from the pending queue.
+// The orders stay marked as redeemed on this device.
+func (v *Verifier) Acknowledge(orderIDs ...uint) {
+	done := make(map[uint]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		done[id] = true
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	remaining := v.pending[:0]
+	for _, r := range v.pending {
+		if !done[r.OrderID] {
+			remaining = append(remaining, r)
+		}
+	}
+	v.pending = remaining
+}
@@ -29,6 +29,7 @@ export interface OrderStatusUpdate {
 }
 
 export interface PickupVerification {
+  store_id: number
   pickup_code: string
 }
 